@@ -0,0 +1,155 @@
+// Command flavors cleans up the flavors of existing beans.
+//
+// Propose merges of misspelled and inconsistent flavors, review the file and
+// remove the merges you don't want, then apply it:
+//
+//	go run ./cmd/flavors propose > merges.json
+//	go run ./cmd/flavors apply -merges merges.json -user you@cafebean.org -dry-run
+//	go run ./cmd/flavors apply -merges merges.json -user you@cafebean.org
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/pubsub"
+	"github.com/gorilla/mux"
+	bq "github.com/mager/cafebean-api/bigquery"
+	"github.com/mager/cafebean-api/common"
+	"github.com/mager/cafebean-api/database"
+	"github.com/mager/cafebean-api/events"
+	"github.com/mager/cafebean-api/handler"
+	"github.com/mager/cafebean-api/logger"
+	"github.com/mager/cafebean-api/postgres"
+	"github.com/mager/cafebean-api/router"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: flavors propose")
+	fmt.Fprintln(os.Stderr, "       flavors apply -merges merges.json -user email [-dry-run]")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var run func(h *handler.Handler) error
+
+	switch os.Args[1] {
+	case "propose":
+		run = propose
+	case "apply":
+		var (
+			fs     = flag.NewFlagSet("apply", flag.ExitOnError)
+			file   = fs.String("merges", "", "JSON file with the approved merges")
+			user   = fs.String("user", "", "email recorded as the author in the changelog")
+			dryRun = fs.Bool("dry-run", false, "print the changes without writing them")
+		)
+		fs.Parse(os.Args[2:])
+		if *file == "" || *user == "" {
+			usage()
+		}
+		run = func(h *handler.Handler) error {
+			return apply(h, *file, *user, *dryRun)
+		}
+	default:
+		usage()
+	}
+
+	app := fx.New(
+		fx.Provide(
+			bq.Options,
+			database.Options,
+			postgres.Options,
+			events.Options,
+			router.Options,
+			logger.Options,
+		),
+		fx.Invoke(func(
+			lifecycle fx.Lifecycle,
+			bq *bigquery.Client,
+			database *firestore.Client,
+			postgres *sql.DB,
+			events *pubsub.Client,
+			logger *zap.SugaredLogger,
+			router *mux.Router,
+		) error {
+			bq, cfg, database, discord, events, logger, postgres, router := common.Register(
+				lifecycle,
+				bq,
+				database,
+				postgres,
+				events,
+				logger,
+				router,
+			)
+			defer database.Close()
+
+			h := handler.New(bq, cfg, database, discord, events, logger, postgres, router)
+			err := run(h)
+
+			// The changelog entries are written in the background
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.SideEffectTimeout)
+			defer cancel()
+			if drainErr := h.Drain(ctx); drainErr != nil && err == nil {
+				err = drainErr
+			}
+			return err
+		}),
+		fx.NopLogger,
+	)
+	if err := app.Err(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// propose prints the proposed merges as JSON
+func propose(h *handler.Handler) error {
+	merges, err := h.ProposeFlavorMerges(context.Background())
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(merges)
+}
+
+// apply reads the approved merges and applies them to every bean
+func apply(h *handler.Handler, file string, user string, dryRun bool) error {
+	var merges []handler.FlavorMerge
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, &merges); err != nil {
+		return err
+	}
+
+	approved := make(map[string]string)
+	for _, m := range merges {
+		approved[m.From] = m.To
+	}
+
+	result, err := h.ApplyFlavorMerges(context.Background(), approved, user, dryRun)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
@@ -0,0 +1,73 @@
+// Command geohash indexes the locations of roasters written before the
+// geohash index existed, so they show up in GET /roasters/near and bbox
+// queries:
+//
+//	go run ./cmd/geohash
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/pubsub"
+	"github.com/gorilla/mux"
+	bq "github.com/mager/cafebean-api/bigquery"
+	"github.com/mager/cafebean-api/common"
+	"github.com/mager/cafebean-api/database"
+	"github.com/mager/cafebean-api/events"
+	"github.com/mager/cafebean-api/handler"
+	"github.com/mager/cafebean-api/logger"
+	"github.com/mager/cafebean-api/postgres"
+	"github.com/mager/cafebean-api/router"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+func main() {
+	app := fx.New(
+		fx.Provide(
+			bq.Options,
+			database.Options,
+			postgres.Options,
+			events.Options,
+			router.Options,
+			logger.Options,
+		),
+		fx.Invoke(func(
+			lifecycle fx.Lifecycle,
+			bq *bigquery.Client,
+			database *firestore.Client,
+			postgres *sql.DB,
+			events *pubsub.Client,
+			logger *zap.SugaredLogger,
+			router *mux.Router,
+		) error {
+			bq, cfg, database, discord, events, logger, postgres, router := common.Register(
+				lifecycle,
+				bq,
+				database,
+				postgres,
+				events,
+				logger,
+				router,
+			)
+			defer database.Close()
+
+			h := handler.New(bq, cfg, database, discord, events, logger, postgres, router)
+			updated, err := h.BackfillGeohashes(context.Background())
+			if err != nil {
+				return err
+			}
+			fmt.Printf("indexed %d roasters\n", updated)
+			return nil
+		}),
+		fx.NopLogger,
+	)
+	if err := app.Err(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,12 +1,57 @@
 package config
 
+import "time"
+
 type Config struct {
 	DiscordAuthToken         string
 	DiscordBeansWebhookID    string
 	DiscordBeansWebhookToken string
 
+	EventsTopic string `default:"events"`
+
 	PostgresHostname string
 	PostgresPassword string
 
-	ReviewsEnabled bool
+	ReviewsEnabled        bool
+	ReviewReportThreshold int `default:"3"`
+
+	ModerationEnabled        bool
+	ModerationTrustThreshold int `default:"3"`
+	Moderators               []string
+
+	// ReputationTrustThreshold is the reputation that skips moderation, 0
+	// turns it off
+	ReputationTrustThreshold int64 `default:"100"`
+
+	// ExchangeRates is the value of one unit of each currency in BaseCurrency
+	BaseCurrency  string             `default:"USD"`
+	ExchangeRates map[string]float64 `default:"USD:1,EUR:1.18,GBP:1.31,CAD:0.76,AUD:0.72,NZD:0.68,CHF:1.09,SEK:0.12,JPY:0.0095"`
+
+	AvailabilityCheckEnabled  bool
+	AvailabilityCheckInterval time.Duration `default:"24h"`
+	AvailabilityCheckDelay    time.Duration `default:"2s"`
+
+	// RequestTimeout is the deadline of a request, RouteTimeouts overrides it
+	// for the paths starting with a prefix, like /import:5m
+	RequestTimeout time.Duration            `default:"15s"`
+	RouteTimeouts  map[string]time.Duration `default:"/stats:1m,/analytics:1m,/import:5m,/export:2m,/roasters/{slug}/imports:5m"`
+
+	// SideEffectTimeout bounds the BigQuery, Discord and Pub/Sub writes that
+	// finish after the response, including the wait for a slot.
+	// MaxSideEffects is how many run at once, MaxQueuedSideEffects how many
+	// can wait before new ones are dropped.
+	SideEffectTimeout    time.Duration `default:"30s"`
+	MaxSideEffects       int           `default:"16"`
+	MaxQueuedSideEffects int           `default:"512"`
+
+	// StatsRefreshInterval is how often GET /stats reads the new changelog
+	// entries
+	StatsRefreshInterval time.Duration `default:"1m"`
+
+	// AnalyticsChangelogFile is a changelog exported from BigQuery as JSONL to
+	// answer analytics queries from instead of BigQuery
+	AnalyticsChangelogFile string
+
+	// CitiesFile is the GeoNames style cities file used to geocode roasters
+	CitiesFile string `default:"data/cities.tsv"`
 }
@@ -14,7 +14,7 @@ require (
 	github.com/lib/pq v1.3.0
 	github.com/niemeyer/pretty v0.0.0-20200227124842-a10e7caefd8e // indirect
 	github.com/pkg/errors v0.9.1 // indirect
-	github.com/stretchr/testify v1.6.1 // indirect
+	github.com/stretchr/testify v1.6.1
 	go.uber.org/fx v1.11.0
 	go.uber.org/multierr v1.6.0 // indirect
 	go.uber.org/zap v1.14.1
@@ -23,5 +23,6 @@ require (
 	golang.org/x/tools v0.0.0-20210102185154-773b96fafca2 // indirect
 	google.golang.org/api v0.36.0
 	google.golang.org/genproto v0.0.0-20201214200347-8c77b98c765d
+	google.golang.org/grpc v1.33.2
 	gopkg.in/check.v1 v1.0.0-20200227125254-8fa46927fb4f // indirect
 )
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/gorilla/mux"
+)
+
+// maxBatches is how many of the latest batches are kept on a bean
+const maxBatches = 10
+
+// AddBatchReq is the request body for the POST /beans/{slug}/batches endpoint
+type AddBatchReq struct {
+	Agtron    int64  `json:"agtron"`
+	BestBy    string `json:"best_by"`
+	RoastedAt string `json:"roasted_at"`
+}
+
+// addBatch records a roast of a bean. Only the owner of the bean's roaster
+// or a moderator can add batches.
+func (h *Handler) addBatch(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		vars      = mux.Vars(r)
+		slug      = vars["slug"]
+		err       error
+		req       AddBatchReq
+		resp      = &BeanResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.writeError(w, r, bodyError(err))
+		return
+	}
+
+	batch, err := parseBatch(req)
+	if err != nil {
+		h.writeError(w, r, validationError(err.Error()))
+		return
+	}
+
+	beanDoc, err := h.getBeanDocBySlug(ctx, slug)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	bean := docToBean(beanDoc)
+
+	roasterDoc, err := h.getRoasterDocBySlug(ctx, bean.Roaster.Slug)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if !h.isRoasterOwner(docToRoasterDB(roasterDoc), userEmail) {
+		h.writeError(w, r, forbiddenError("only the roaster can add batches"))
+		return
+	}
+
+	// Keep the latest batches first
+	batches := append(bean.Batches, batch)
+	sort.Slice(batches, func(i, j int) bool {
+		return batches[i].RoastedAt.After(batches[j].RoastedAt)
+	})
+	if len(batches) > maxBatches {
+		batches = batches[:maxBatches]
+	}
+
+	_, err = beanDoc.Ref.Update(ctx, []firestore.Update{
+		{Path: "batches", Value: batches},
+	})
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	h.logger.Infow(
+		"Batch added",
+		"id", beanDoc.Ref.ID,
+		"roasted_at", batch.RoastedAt,
+		"updated_by", userEmail,
+	)
+
+	bean.Batches = batches
+	resp.Bean = bean
+
+	w.WriteHeader(http.StatusCreated)
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// parseBatch validates a batch request
+func parseBatch(req AddBatchReq) (Batch, error) {
+	var batch Batch
+
+	roastedAt, err := time.Parse("2006-01-02", req.RoastedAt)
+	if err != nil {
+		return batch, errors.New("roasted_at must be a date like 2006-01-02")
+	}
+	if roastedAt.After(time.Now()) {
+		return batch, errors.New("roasted_at can't be in the future")
+	}
+	batch.RoastedAt = roastedAt
+
+	if req.BestBy != "" {
+		bestBy, err := time.Parse("2006-01-02", req.BestBy)
+		if err != nil {
+			return batch, errors.New("best_by must be a date like 2006-01-02")
+		}
+		if !bestBy.After(roastedAt) {
+			return batch, errors.New("best_by must be after roasted_at")
+		}
+		batch.BestBy = bestBy
+	}
+
+	if !validAgtron(req.Agtron) {
+		return batch, errors.New("agtron must be between 0 and 100")
+	}
+	batch.Agtron = req.Agtron
+
+	return batch, nil
+}
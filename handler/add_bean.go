@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 )
@@ -13,12 +12,13 @@ type AddBeanReq struct {
 
 // AddBeanResp is the response from the POST /beans endpoint
 type AddBeanResp struct {
-	ID string `json:"id"`
+	ID           string `json:"id"`
+	SubmissionID string `json:"submission_id,omitempty"`
 }
 
 func (h *Handler) addBean(w http.ResponseWriter, r *http.Request) {
 	var (
-		ctx       = context.TODO()
+		ctx       = r.Context()
 		err       error
 		req       BeanReq
 		resp      = &AddBeanResp{}
@@ -27,47 +27,56 @@ func (h *Handler) addBean(w http.ResponseWriter, r *http.Request) {
 
 	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.writeError(w, r, bodyError(err))
+		return
+	}
+
+	if err = prepareBean(&req.Bean); err != nil {
+		h.writeError(w, r, validationError(err.Error()))
 		return
 	}
 
 	// Make sure roaster exists
-	iter := h.database.Collection("roasters").Where("name", "==", req.Roaster.Name).Documents(ctx)
-	for {
-		doc, err := iter.Next()
-		if doc == nil {
-			http.Error(w, "invalid roaster", http.StatusBadRequest)
-			return
-		}
+	exists, err := h.roasterNameExists(ctx, req.Roaster.Name)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if !exists {
+		h.writeError(w, r, validationError("invalid roaster"))
+		return
+	}
 
+	// New contributors go through the moderation queue
+	if h.requiresModeration(ctx, userEmail) {
+		bean := req.Bean
+		resp.SubmissionID, err = h.submit(ctx, Submission{
+			Kind:        "bean",
+			Action:      "add",
+			Slug:        req.Slug,
+			Bean:        &bean,
+			SubmittedBy: userEmail,
+		})
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.writeError(w, r, err)
 			return
 		}
 
-		break
+		w.WriteHeader(http.StatusAccepted)
+
+		json.NewEncoder(w).Encode(resp)
+		return
 	}
 
 	// Add the bean
-	doc, _, err := h.database.Collection("beans").Add(ctx, req)
+	doc, err := h.createBean(ctx, req, userEmail)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.writeError(w, r, err)
 		return
 	}
-	h.logger.Infow(
-		"Bean added",
-		"id", doc.ID,
-		"updated_by", userEmail,
-	)
 
 	resp.ID = doc.ID
 
-	// Publish an entry in BigQuery
-	h.recordBeanChange(ctx, req, userEmail)
-
-	// Send a webhook event to Discord
-	h.postBeanToDiscord(req, userEmail, "add")
-
 	w.WriteHeader(http.StatusAccepted)
 
 	json.NewEncoder(w).Encode(resp)
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/gorilla/mux"
+)
+
+// AddCollectionReq is the request body for creating or updating a collection
+type AddCollectionReq struct {
+	Name       string `json:"name"`
+	Visibility string `json:"visibility"`
+}
+
+// AddCollectionBeanReq is the request body for saving a bean to a collection
+type AddCollectionBeanReq struct {
+	Bean string `json:"bean"`
+}
+
+// addProfileCollection creates a custom collection
+func (h *Handler) addProfileCollection(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		err       error
+		req       AddCollectionReq
+		resp      = &CollectionResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.writeError(w, r, bodyError(err))
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	slug := slugify(req.Name)
+	if slug == "" {
+		h.writeError(w, r, validationError("invalid name"))
+		return
+	}
+	if req.Visibility == "" {
+		req.Visibility = visibilityPrivate
+	}
+	if !validVisibility(req.Visibility) {
+		h.writeError(w, r, validationError("invalid visibility"))
+		return
+	}
+
+	u, err := h.getUserByEmail(ctx, userEmail)
+	if err != nil {
+		h.writeError(w, r, validationError("invalid user"))
+		return
+	}
+
+	// Make sure the collection doesn't already exist
+	_, err = h.getCollection(ctx, u, slug)
+	if err == nil {
+		h.writeError(w, r, conflictError("collection already exists"))
+		return
+	}
+	if err != errCollectionNotFound {
+		h.writeError(w, r, err)
+		return
+	}
+
+	c := newCollection(u, req.Name, slug, req.Visibility)
+	if err = h.saveCollection(ctx, &c); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	h.logger.Infow(
+		"Collection added",
+		"slug", slug,
+		"updated_by", userEmail,
+	)
+
+	resp.Collection = c
+
+	w.WriteHeader(http.StatusAccepted)
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// addCollectionBean saves a bean to one of the user's collections
+func (h *Handler) addCollectionBean(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		vars      = mux.Vars(r)
+		slug      = vars["collection"]
+		err       error
+		req       AddCollectionBeanReq
+		resp      = &CollectionResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.writeError(w, r, bodyError(err))
+		return
+	}
+
+	u, err := h.getUserByEmail(ctx, userEmail)
+	if err != nil {
+		h.writeError(w, r, validationError("invalid user"))
+		return
+	}
+
+	if _, err = h.getBeanDocBySlug(ctx, req.Bean); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	c, err := h.getCollection(ctx, u, slug)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	// Saving a bean twice is a no-op
+	var added bool
+	c.Beans, added = withBean(c.Beans, req.Bean)
+	if added {
+		if err = h.updateCollectionBeans(ctx, c, firestore.ArrayUnion(req.Bean)); err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+		h.logger.Infow(
+			"Bean saved to collection",
+			"collection", slug,
+			"bean", req.Bean,
+			"updated_by", userEmail,
+		)
+	}
+
+	resp.Collection = c
+
+	json.NewEncoder(w).Encode(resp)
+}
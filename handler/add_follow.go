@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// followUser follows another user
+func (h *Handler) followUser(w http.ResponseWriter, r *http.Request) {
+	h.addFollow(w, r, followUser, mux.Vars(r)["username"])
+}
+
+// followRoaster follows a roaster
+func (h *Handler) followRoaster(w http.ResponseWriter, r *http.Request) {
+	h.addFollow(w, r, followRoaster, mux.Vars(r)["slug"])
+}
+
+func (h *Handler) addFollow(w http.ResponseWriter, r *http.Request, followType string, target string) {
+	var (
+		ctx       = r.Context()
+		resp      = &FollowResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	u, err := h.getUserByEmail(ctx, userEmail)
+	if err != nil {
+		h.writeError(w, r, validationError("invalid user"))
+		return
+	}
+
+	// Make sure the target exists
+	switch followType {
+	case followUser:
+		if target == u.Username {
+			h.writeError(w, r, validationError("cannot follow yourself"))
+			return
+		}
+		_, err = h.getUserByUsername(ctx, target)
+	case followRoaster:
+		_, err = h.getRoasterDocBySlug(ctx, target)
+	}
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	_, err = h.database.Collection("follows").Doc(followID(userEmail, followType, target)).Set(ctx, Follow{
+		Follower:      u.Username,
+		FollowerEmail: userEmail,
+		Type:          followType,
+		Target:        target,
+		CreatedAt:     time.Now(),
+	})
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	h.logger.Infow(
+		"Followed",
+		"follower", u.Username,
+		"type", followType,
+		"target", target,
+	)
+
+	resp.Following = true
+
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// addOffering adds an offering to a bean
+func (h *Handler) addOffering(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		vars      = mux.Vars(r)
+		slug      = vars["slug"]
+		err       error
+		req       Offering
+		resp      = &OfferingResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.writeError(w, r, bodyError(err))
+		return
+	}
+
+	req.CheckedAt = time.Time{}
+	if err = normalizeOffering(&req, h.exchangeRates()); err != nil {
+		h.writeError(w, r, validationError(err.Error()))
+		return
+	}
+
+	doc, err := h.getBeanDocBySlug(ctx, slug)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	bean := docToBean(doc)
+
+	allowed, err := h.canEditBean(ctx, bean, userEmail)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if !allowed {
+		h.writeError(w, r, forbiddenError("roaster is verified, suggest an edit instead"))
+		return
+	}
+
+	req.ID, err = newOfferingID()
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	// New contributors go through the moderation queue
+	if h.requiresModeration(ctx, userEmail) {
+		resp.SubmissionID, err = h.submitOffering(ctx, slug, offeringAdd, req, userEmail)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	err = h.updateOffering(ctx, slug, offeringAdd, req, userEmail)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.Offering = &req
+
+	w.WriteHeader(http.StatusAccepted)
+
+	json.NewEncoder(w).Encode(resp)
+}
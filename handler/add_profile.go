@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 	"time"
@@ -17,7 +16,7 @@ type AddProfileResp struct {
 // TODO: Add better security
 func (h *Handler) addProfile(w http.ResponseWriter, r *http.Request) {
 	var (
-		ctx       = context.TODO()
+		ctx       = r.Context()
 		err       error
 		req       UserDB
 		resp      = &AddProfileResp{}
@@ -26,13 +25,13 @@ func (h *Handler) addProfile(w http.ResponseWriter, r *http.Request) {
 
 	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		http.Error(w, "error handling request", http.StatusBadRequest)
+		h.writeError(w, r, validationError("error handling request"))
 		return
 	}
 
 	// Validate user
 	if req.Email != userEmail {
-		http.Error(w, "only the user can create their profile", http.StatusBadRequest)
+		h.writeError(w, r, forbiddenError("only the user can create their profile"))
 		return
 	}
 
@@ -42,7 +41,7 @@ func (h *Handler) addProfile(w http.ResponseWriter, r *http.Request) {
 		doc, err := iter.Next()
 
 		if doc != nil && err != nil {
-			http.Error(w, "user already exists", http.StatusBadRequest)
+			h.writeError(w, r, conflictError("user already exists"))
 			return
 		}
 
@@ -55,7 +54,7 @@ func (h *Handler) addProfile(w http.ResponseWriter, r *http.Request) {
 		}
 		created, _, err := h.database.Collection("users").Add(ctx, newUser)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.writeError(w, r, err)
 			return
 		}
 		h.logger.Infow(
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// AddReviewReq is the request body for the POST /reviews endpoint
+type AddReviewReq struct {
+	Bean   string  `firestore:"bean" json:"bean"`
+	Rating float64 `firestore:"rating" json:"rating"`
+	Review string  `firestore:"review" json:"review"`
+}
+
+// AddReviewResp is the response from the POST /reviews endpoint
+type AddReviewResp struct {
+	Review       *Review `json:"review,omitempty"`
+	SubmissionID string  `json:"submission_id,omitempty"`
+}
+
+// addReview adds a review of a bean by the user
+func (h *Handler) addReview(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		err       error
+		req       AddReviewReq
+		resp      = &AddReviewResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	if !h.cfg.ReviewsEnabled {
+		h.writeError(w, r, notFoundError("reviews are disabled"))
+		return
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.writeError(w, r, bodyError(err))
+		return
+	}
+
+	if err = normalizeReview(&req); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	// New contributors go through the moderation queue
+	if h.requiresModeration(ctx, userEmail) {
+		if _, err = h.getBeanDocBySlug(ctx, req.Bean); err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		review := req
+		resp.SubmissionID, err = h.submit(ctx, Submission{
+			Kind:        "review",
+			Action:      "add",
+			Slug:        req.Bean,
+			Review:      &review,
+			SubmittedBy: userEmail,
+		})
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	review, err := h.createReview(ctx, req, userEmail)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	resp.Review = &review
+
+	w.WriteHeader(http.StatusAccepted)
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// errAlreadyReviewed is returned when a user reviews a bean a second time
+var errAlreadyReviewed = conflictError("you already reviewed this bean")
+
+// isUniqueViolation checks if a Postgres write failed on a unique constraint
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
+
+// createReview adds a review of a bean by a user and publishes the event
+func (h *Handler) createReview(ctx context.Context, req AddReviewReq, userEmail string) (Review, error) {
+	// Resolve the reviewer and the bean
+	u, err := h.getUserByEmail(ctx, userEmail)
+	if err != nil {
+		return Review{}, validationError("invalid user")
+	}
+	userID, err := h.getPostgresUserID(ctx, u.Username)
+	if err != nil {
+		return Review{}, validationError("invalid user")
+	}
+	beanDoc, err := h.getBeanDocBySlug(ctx, req.Bean)
+	if err != nil {
+		return Review{}, err
+	}
+	bean := docToBean(beanDoc)
+
+	// One review per user and bean
+	var existing int
+	err = h.postgres.QueryRowContext(
+		ctx,
+		`SELECT review_id FROM reviews WHERE user_id = $1 AND bean_ref = $2;`,
+		userID, beanDoc.Ref.ID,
+	).Scan(&existing)
+	if err == nil {
+		return Review{}, errAlreadyReviewed
+	}
+	if err != sql.ErrNoRows {
+		h.logger.Error(err)
+		return Review{}, err
+	}
+
+	var (
+		reviewID  int
+		updatedAt time.Time
+	)
+	err = h.postgres.QueryRowContext(ctx, `
+		INSERT INTO reviews (user_id, bean_ref, rating, review, updated_at)
+		VALUES ($1, $2, $3, $4, now())
+		RETURNING review_id, updated_at;
+	`, userID, beanDoc.Ref.ID, req.Rating, req.Review).Scan(&reviewID, &updatedAt)
+	if isUniqueViolation(err) {
+		// Another request added the review since the check above
+		return Review{}, errAlreadyReviewed
+	}
+	if err != nil {
+		h.logger.Error(err)
+		return Review{}, err
+	}
+	h.logger.Infow(
+		"Review added",
+		"review_id", reviewID,
+		"bean", bean.Slug,
+		"updated_by", userEmail,
+	)
+
+	e := beanEvent(eventReviewAdded, bean)
+	e.Username = u.Username
+	e.ReviewID = reviewID
+	e.Rating = req.Rating
+	e.Review = req.Review
+	h.publishEvent(ctx, e, userEmail)
+
+	return Review{
+		ID:        reviewID,
+		Rating:    req.Rating,
+		Review:    req.Review,
+		User:      u.Username,
+		UpdatedAt: updatedAt,
+		Bean:      bean.Slug,
+	}, nil
+}
@@ -1,19 +1,20 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
+	"time"
 )
 
 // AddRoasterResp is the response from the POST /roasters/{slug} endpoint
 type AddRoasterResp struct {
-	ID string `json:"id"`
+	ID           string `json:"id"`
+	SubmissionID string `json:"submission_id,omitempty"`
 }
 
 func (h *Handler) addRoaster(w http.ResponseWriter, r *http.Request) {
 	var (
-		ctx       = context.TODO()
+		ctx       = r.Context()
 		err       error
 		req       RoasterReq
 		resp      = &AddRoasterResp{}
@@ -22,46 +23,57 @@ func (h *Handler) addRoaster(w http.ResponseWriter, r *http.Request) {
 
 	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.writeError(w, r, bodyError(err))
 		return
 	}
 
+	if err = prepareRoaster(&req.Roaster, time.Now()); err != nil {
+		h.writeError(w, r, validationError(err.Error()))
+		return
+	}
+
+	h.geocodeRoaster(&req.Roaster)
+	h.geocodeCafes(&req.Roaster)
+
 	// Make sure the roaster doesn't already exist
-	roasterIter := h.database.Collection("roasters").Where("slug", "==", req.Slug).Documents(ctx)
-	for {
-		doc, err := roasterIter.Next()
+	_, err = h.getRoasterDocBySlug(ctx, req.Slug)
+	if err == nil {
+		h.writeError(w, r, conflictError("roaster already exists"))
+		return
+	}
+	if err != errRoasterNotFound {
+		h.writeError(w, r, err)
+		return
+	}
 
-		if doc != nil {
-			http.Error(w, "roaster already exists", http.StatusBadRequest)
-			return
-		}
-		if err != nil && err.Error() != "no more items in iterator" {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+	// New contributors go through the moderation queue
+	if h.requiresModeration(ctx, userEmail) {
+		roaster := req.Roaster
+		resp.SubmissionID, err = h.submit(ctx, Submission{
+			Kind:        "roaster",
+			Action:      "add",
+			Slug:        req.Slug,
+			Roaster:     &roaster,
+			SubmittedBy: userEmail,
+		})
+		if err != nil {
+			h.writeError(w, r, err)
 			return
 		}
 
-		break
+		w.WriteHeader(http.StatusAccepted)
+
+		json.NewEncoder(w).Encode(resp)
+		return
 	}
 
 	// Add the roaster
-	doc, _, err := h.database.Collection("roasters").Add(ctx, req)
+	doc, err := h.createRoaster(ctx, req, userEmail)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.writeError(w, r, err)
 		return
 	}
 
-	h.logger.Infow(
-		"Roaster added",
-		"id", doc.ID,
-		"updated_by", userEmail,
-	)
-
-	// Publish an entry in BigQuery
-	h.recordRoasterChange(ctx, req, userEmail)
-
-	// Send a webhook event to Discord
-	h.postRoasterToDiscord(req, userEmail, "add")
-
 	// Send updated roaster response
 	w.WriteHeader(http.StatusAccepted)
 
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// AddSuggestionReq is the request body for suggesting an edit
+type AddSuggestionReq struct {
+	Patch map[string]interface{} `json:"patch"`
+}
+
+// SuggestionResp is the response from the suggestion endpoints
+type SuggestionResp struct {
+	Suggestion Suggestion `json:"suggestion"`
+}
+
+// addBeanSuggestion proposes an edit to a bean
+func (h *Handler) addBeanSuggestion(w http.ResponseWriter, r *http.Request) {
+	h.addSuggestion(w, r, "bean")
+}
+
+// addRoasterSuggestion proposes an edit to a roaster
+func (h *Handler) addRoasterSuggestion(w http.ResponseWriter, r *http.Request) {
+	h.addSuggestion(w, r, "roaster")
+}
+
+func (h *Handler) addSuggestion(w http.ResponseWriter, r *http.Request, kind string) {
+	var (
+		ctx       = r.Context()
+		vars      = mux.Vars(r)
+		slug      = vars["slug"]
+		err       error
+		req       AddSuggestionReq
+		resp      = &SuggestionResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.writeError(w, r, bodyError(err))
+		return
+	}
+
+	if userEmail == "" {
+		h.writeError(w, r, validationError("invalid user"))
+		return
+	}
+
+	allowed := beanSuggestionFields
+	if kind == "roaster" {
+		allowed = roasterSuggestionFields
+	}
+	if err = validatePatch(req.Patch, allowed); err != nil {
+		h.writeError(w, r, validationError(err.Error()))
+		return
+	}
+
+	s := Suggestion{
+		Kind:        kind,
+		Slug:        slug,
+		RoasterSlug: slug,
+		Patch:       req.Patch,
+		Status:      suggestionOpen,
+		SuggestedBy: userEmail,
+		SuggestedAt: time.Now(),
+	}
+
+	// Make sure the patch applies cleanly
+	_, current, proposed, err := h.suggestionTarget(ctx, s)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if b, ok := current.(Bean); ok {
+		s.RoasterSlug = b.Roaster.Slug
+	}
+
+	s.Changes = diffFields(current, proposed)
+	if len(s.Changes) == 0 {
+		h.writeError(w, r, validationError("patch doesn't change anything"))
+		return
+	}
+
+	doc, _, err := h.database.Collection("suggestions").Add(ctx, s)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	s.ID = doc.ID
+	h.logger.Infow(
+		"Suggestion added",
+		"id", doc.ID,
+		"kind", kind,
+		"slug", slug,
+		"suggested_by", userEmail,
+	)
+
+	resp.Suggestion = s
+
+	w.WriteHeader(http.StatusAccepted)
+
+	json.NewEncoder(w).Encode(resp)
+}
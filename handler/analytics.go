@@ -0,0 +1,452 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/firestore"
+	"go.uber.org/zap"
+)
+
+// Analytics query limits
+const (
+	defaultAnalyticsDays = 30
+	maxAnalyticsDays     = 365
+
+	defaultContributors = 20
+	maxContributors     = 100
+
+	// maxInQuery is the most values a firestore "in" filter takes
+	maxInQuery = 10
+)
+
+// AnalyticsStore answers questions about the edits in the changelog
+type AnalyticsStore interface {
+	// Contributors ranks the users by the edits they made in the window
+	Contributors(ctx context.Context, w AnalyticsWindow, limit int) ([]ContributorCount, error)
+	// Activity counts the edits per day in the window. A kind and slug narrow
+	// it down to a bean or roaster.
+	Activity(ctx context.Context, w AnalyticsWindow, kind, slug string) ([]DayActivity, error)
+	// Entity sums up the edits of a bean or roaster in the window
+	Entity(ctx context.Context, w AnalyticsWindow, kind, slug string) (EntityEdits, error)
+	// Contributions counts what a user added and edited over all time
+	Contributions(ctx context.Context, email string) (Contributions, error)
+}
+
+// AnalyticsWindow is the time range of an analytics query, From inclusive
+// and To exclusive
+type AnalyticsWindow struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// Contains checks if a time is in the window
+func (w AnalyticsWindow) Contains(t time.Time) bool {
+	return !t.Before(w.From) && t.Before(w.To)
+}
+
+// ContributorCount is the number of edits of a user. The email is only used
+// to look up the username and isn't returned.
+type ContributorCount struct {
+	Email        string `json:"-"`
+	Username     string `json:"username,omitempty"`
+	Edits        int64  `json:"edits"`
+	BeanEdits    int64  `json:"bean_edits"`
+	RoasterEdits int64  `json:"roaster_edits"`
+}
+
+// DayActivity is the number of edits on a day
+type DayActivity struct {
+	Date     string `json:"date"`
+	Beans    int64  `json:"beans"`
+	Roasters int64  `json:"roasters"`
+}
+
+// EntityEdits sums up the edits of a bean or roaster
+type EntityEdits struct {
+	Kind          string    `json:"kind"`
+	Slug          string    `json:"slug"`
+	Edits         int64     `json:"edits"`
+	Contributors  int64     `json:"contributors"`
+	FirstEditedAt time.Time `json:"first_edited_at"`
+	LastEditedAt  time.Time `json:"last_edited_at"`
+	// EditsPerWeek is the average over the window
+	EditsPerWeek float64 `json:"edits_per_week"`
+}
+
+// Contributions are what a user added and fixed. The first entry of a slug
+// in the changelog counts as adding it, the later ones as edits.
+type Contributions struct {
+	BeansAdded    int64 `json:"beans_added"`
+	RoastersAdded int64 `json:"roasters_added"`
+	BeanEdits     int64 `json:"bean_edits"`
+	RoasterEdits  int64 `json:"roaster_edits"`
+	// FlavorEdits are the bean edits that changed the flavors
+	FlavorEdits int64 `json:"flavor_edits"`
+	// Reviews and HelpfulVotes come from the reviews rather than the changelog
+	Reviews      int64 `json:"reviews"`
+	HelpfulVotes int64 `json:"helpful_votes"`
+}
+
+// parseAnalyticsWindow reads a window from the from and to dates or the
+// number of days up to now
+func parseAnalyticsWindow(q url.Values, now time.Time) (AnalyticsWindow, error) {
+	var (
+		today = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		w     = AnalyticsWindow{To: today.AddDate(0, 0, 1)}
+		days  = defaultAnalyticsDays
+	)
+
+	if v := q.Get("to"); v != "" {
+		to, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return w, fmt.Errorf("to must be a date like 2006-01-02")
+		}
+		w.To = to.AddDate(0, 0, 1)
+	}
+
+	if v := q.Get("days"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d < 1 || d > maxAnalyticsDays {
+			return w, fmt.Errorf("days must be between 1 and %d", maxAnalyticsDays)
+		}
+		days = d
+	}
+	w.From = w.To.AddDate(0, 0, -days)
+
+	if v := q.Get("from"); v != "" {
+		from, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return w, fmt.Errorf("from must be a date like 2006-01-02")
+		}
+		w.From = from
+	}
+
+	switch {
+	case !w.From.Before(w.To):
+		return w, fmt.Errorf("from must be before to")
+	case w.To.Sub(w.From) > maxAnalyticsDays*24*time.Hour:
+		return w, fmt.Errorf("the window can't be longer than %d days", maxAnalyticsDays)
+	}
+
+	return w, nil
+}
+
+// parseEntityKind reads the kind of entity, beans by default
+func parseEntityKind(kind string) (string, error) {
+	switch kind {
+	case "", "bean":
+		return "bean", nil
+	case "roaster":
+		return "roaster", nil
+	}
+	return "", fmt.Errorf("kind must be bean or roaster")
+}
+
+// editsPerWeek averages edits over a window
+func editsPerWeek(edits int64, w AnalyticsWindow) float64 {
+	weeks := w.To.Sub(w.From).Hours() / (24 * 7)
+	if weeks <= 0 {
+		return 0
+	}
+	return float64(edits) / weeks
+}
+
+// isContributor checks if a changelog entry was written by a person rather
+// than a job like the availability checker, which record a name instead of an
+// email
+func isContributor(updatedBy string) bool {
+	return strings.Contains(updatedBy, "@")
+}
+
+// changelogEdit is an entry of the bean or roaster changelog
+type changelogEdit struct {
+	Kind      string
+	Slug      string
+	Flavors   string
+	UpdatedBy string
+	UpdatedAt time.Time
+}
+
+// MemoryAnalytics answers analytics queries from changelog entries held in
+// memory, for local development without BigQuery
+type MemoryAnalytics struct {
+	edits []changelogEdit
+}
+
+// NewMemoryAnalytics loads a changelog exported from BigQuery as JSONL. Bean
+// and roaster entries can be mixed in the same file.
+func NewMemoryAnalytics(path string) (*MemoryAnalytics, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		m       = &MemoryAnalytics{}
+		scanner = bufio.NewScanner(f)
+		line    int
+	)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		edit, err := parseChangelogLine(scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		m.edits = append(m.edits, edit)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// parseChangelogLine reads a changelog entry like
+// {"Bean": {"Slug": "..."}, "UpdatedBy": "...", "UpdatedAt": "..."}
+func parseChangelogLine(b []byte) (changelogEdit, error) {
+	var (
+		edit  changelogEdit
+		entry struct {
+			Bean *struct {
+				Slug    string
+				Flavors string
+			}
+			Roaster *struct {
+				Slug string
+			}
+			UpdatedBy string
+			UpdatedAt string
+		}
+	)
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return edit, err
+	}
+
+	switch {
+	case entry.Bean != nil:
+		edit.Kind, edit.Slug, edit.Flavors = "bean", entry.Bean.Slug, entry.Bean.Flavors
+	case entry.Roaster != nil:
+		edit.Kind, edit.Slug = "roaster", entry.Roaster.Slug
+	default:
+		return edit, fmt.Errorf("entry has no Bean or Roaster")
+	}
+	edit.UpdatedBy = entry.UpdatedBy
+
+	// BigQuery exports timestamps as "2006-01-02 15:04:05 UTC"
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999 MST", "2006-01-02 15:04:05 MST"} {
+		if t, err := time.Parse(layout, entry.UpdatedAt); err == nil {
+			edit.UpdatedAt = t.UTC()
+			return edit, nil
+		}
+	}
+	return edit, fmt.Errorf("invalid UpdatedAt %q", entry.UpdatedAt)
+}
+
+// Contributors ranks the users by the edits they made in the window
+func (m *MemoryAnalytics) Contributors(ctx context.Context, w AnalyticsWindow, limit int) ([]ContributorCount, error) {
+	byUser := make(map[string]*ContributorCount)
+	for _, e := range m.edits {
+		if !isContributor(e.UpdatedBy) || !w.Contains(e.UpdatedAt) {
+			continue
+		}
+		c, ok := byUser[e.UpdatedBy]
+		if !ok {
+			c = &ContributorCount{Email: e.UpdatedBy}
+			byUser[e.UpdatedBy] = c
+		}
+		c.Edits++
+		if e.Kind == "bean" {
+			c.BeanEdits++
+		} else {
+			c.RoasterEdits++
+		}
+	}
+
+	var contributors = []ContributorCount{}
+	for _, c := range byUser {
+		contributors = append(contributors, *c)
+	}
+	sort.Slice(contributors, func(i, j int) bool {
+		if contributors[i].Edits != contributors[j].Edits {
+			return contributors[i].Edits > contributors[j].Edits
+		}
+		return contributors[i].Email < contributors[j].Email
+	})
+	if len(contributors) > limit {
+		contributors = contributors[:limit]
+	}
+
+	return contributors, nil
+}
+
+// Activity counts the edits per day in the window
+func (m *MemoryAnalytics) Activity(ctx context.Context, w AnalyticsWindow, kind, slug string) ([]DayActivity, error) {
+	byDay := make(map[string]*DayActivity)
+	for _, e := range m.edits {
+		if !w.Contains(e.UpdatedAt) || kind != "" && e.Kind != kind || slug != "" && e.Slug != slug {
+			continue
+		}
+		date := e.UpdatedAt.Format("2006-01-02")
+		d, ok := byDay[date]
+		if !ok {
+			d = &DayActivity{Date: date}
+			byDay[date] = d
+		}
+		if e.Kind == "bean" {
+			d.Beans++
+		} else {
+			d.Roasters++
+		}
+	}
+
+	var days = []DayActivity{}
+	for _, d := range byDay {
+		days = append(days, *d)
+	}
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Date < days[j].Date
+	})
+
+	return days, nil
+}
+
+// Entity sums up the edits of a bean or roaster in the window
+func (m *MemoryAnalytics) Entity(ctx context.Context, w AnalyticsWindow, kind, slug string) (EntityEdits, error) {
+	var (
+		entity = EntityEdits{Kind: kind, Slug: slug}
+		users  = make(map[string]bool)
+	)
+	for _, e := range m.edits {
+		if e.Kind != kind || e.Slug != slug || !w.Contains(e.UpdatedAt) {
+			continue
+		}
+		entity.Edits++
+		users[e.UpdatedBy] = true
+		if entity.FirstEditedAt.IsZero() || e.UpdatedAt.Before(entity.FirstEditedAt) {
+			entity.FirstEditedAt = e.UpdatedAt
+		}
+		if e.UpdatedAt.After(entity.LastEditedAt) {
+			entity.LastEditedAt = e.UpdatedAt
+		}
+	}
+	entity.Contributors = int64(len(users))
+	entity.EditsPerWeek = editsPerWeek(entity.Edits, w)
+
+	return entity, nil
+}
+
+// Contributions counts what a user added and edited over all time
+func (m *MemoryAnalytics) Contributions(ctx context.Context, email string) (Contributions, error) {
+	var c Contributions
+
+	edits := make([]changelogEdit, len(m.edits))
+	copy(edits, m.edits)
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].UpdatedAt.Before(edits[j].UpdatedAt)
+	})
+
+	var (
+		seen    = make(map[string]bool)
+		flavors = make(map[string]string)
+	)
+	for _, e := range edits {
+		key := e.Kind + "/" + e.Slug
+		added := !seen[key]
+		seen[key] = true
+
+		previous := flavors[key]
+		flavors[key] = e.Flavors
+
+		if e.UpdatedBy != email {
+			continue
+		}
+		switch {
+		case e.Kind == "bean" && added:
+			c.BeansAdded++
+		case e.Kind == "bean":
+			c.BeanEdits++
+			if e.Flavors != previous {
+				c.FlavorEdits++
+			}
+		case added:
+			c.RoastersAdded++
+		default:
+			c.RoasterEdits++
+		}
+	}
+
+	return c, nil
+}
+
+// newAnalyticsStore queries BigQuery, or a local changelog file when one is
+// configured
+func newAnalyticsStore(path string, bq *bigquery.Client, logger *zap.SugaredLogger) AnalyticsStore {
+	if path == "" {
+		return &BigQueryAnalytics{client: bq}
+	}
+
+	m, err := NewMemoryAnalytics(path)
+	if err != nil {
+		if logger != nil {
+			logger.Warnw(
+				"Couldn't load the analytics changelog, querying BigQuery",
+				"path", path,
+				"error", err,
+			)
+		}
+		return &BigQueryAnalytics{client: bq}
+	}
+	return m
+}
+
+// setContributorUsernames looks up the usernames of contributors
+func (h *Handler) setContributorUsernames(ctx context.Context, contributors []ContributorCount) {
+	var (
+		emails    []string
+		usernames = make(map[string]string)
+	)
+	for _, c := range contributors {
+		emails = append(emails, c.Email)
+	}
+
+	for start := 0; start < len(emails); start += maxInQuery {
+		end := start + maxInQuery
+		if end > len(emails) {
+			end = len(emails)
+		}
+		docs, err := h.database.Collection("users").Where("email", "in", emails[start:end]).Documents(ctx).GetAll()
+		if err != nil {
+			h.logger.Error(err)
+			return
+		}
+		for _, doc := range docs {
+			u := docToUserDB(doc)
+			usernames[u.Email] = u.Username
+		}
+	}
+
+	for i := range contributors {
+		contributors[i].Username = usernames[contributors[i].Email]
+	}
+}
+
+func docToUserDB(doc *firestore.DocumentSnapshot) UserDB {
+	var u UserDB
+	doc.DataTo(&u)
+	return u
+}
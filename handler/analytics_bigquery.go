@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// changelogEdits selects the edits of both changelogs
+const changelogEdits = `
+	WITH edits AS (
+		SELECT 'bean' AS Kind, Bean.Slug AS Slug, UpdatedBy, CAST(UpdatedAt AS TIMESTAMP) AS UpdatedAt
+		FROM ` + "`cafebean.bean.changelog`" + `
+		UNION ALL
+		SELECT 'roaster' AS Kind, Roaster.Slug AS Slug, UpdatedBy, CAST(UpdatedAt AS TIMESTAMP) AS UpdatedAt
+		FROM ` + "`cafebean.roaster.changelog`" + `
+	)
+`
+
+// BigQueryAnalytics answers analytics queries from the changelogs in BigQuery
+type BigQueryAnalytics struct {
+	client *bigquery.Client
+}
+
+// query runs a parameterized query over the edits in a window
+func (b *BigQueryAnalytics) query(sql string, w AnalyticsWindow, params ...bigquery.QueryParameter) *bigquery.Query {
+	q := b.client.Query(changelogEdits + sql)
+	q.Parameters = append([]bigquery.QueryParameter{
+		{Name: "from", Value: w.From},
+		{Name: "to", Value: w.To},
+	}, params...)
+	return q
+}
+
+// Contributors ranks the users by the edits they made in the window
+func (b *BigQueryAnalytics) Contributors(ctx context.Context, w AnalyticsWindow, limit int) ([]ContributorCount, error) {
+	q := b.query(`
+		SELECT
+			UpdatedBy AS Email,
+			COUNT(*) AS Edits,
+			COUNTIF(Kind = 'bean') AS BeanEdits,
+			COUNTIF(Kind = 'roaster') AS RoasterEdits
+		FROM edits
+		WHERE UpdatedAt >= @from AND UpdatedAt < @to AND STRPOS(UpdatedBy, '@') > 0
+		GROUP BY Email
+		ORDER BY Edits DESC, Email
+		LIMIT @limit
+	`, w, bigquery.QueryParameter{Name: "limit", Value: limit})
+
+	var contributors = []ContributorCount{}
+	err := readRows(ctx, q, func(it *bigquery.RowIterator) error {
+		var c ContributorCount
+		if err := it.Next(&c); err != nil {
+			return err
+		}
+		contributors = append(contributors, c)
+		return nil
+	})
+	return contributors, err
+}
+
+// Activity counts the edits per day in the window
+func (b *BigQueryAnalytics) Activity(ctx context.Context, w AnalyticsWindow, kind, slug string) ([]DayActivity, error) {
+	q := b.query(`
+		SELECT
+			FORMAT_DATE('%Y-%m-%d', DATE(UpdatedAt)) AS Date,
+			COUNTIF(Kind = 'bean') AS Beans,
+			COUNTIF(Kind = 'roaster') AS Roasters
+		FROM edits
+		WHERE UpdatedAt >= @from AND UpdatedAt < @to
+			AND (@kind = '' OR Kind = @kind)
+			AND (@slug = '' OR Slug = @slug)
+		GROUP BY Date
+		ORDER BY Date
+	`, w,
+		bigquery.QueryParameter{Name: "kind", Value: kind},
+		bigquery.QueryParameter{Name: "slug", Value: slug},
+	)
+
+	var days = []DayActivity{}
+	err := readRows(ctx, q, func(it *bigquery.RowIterator) error {
+		var d DayActivity
+		if err := it.Next(&d); err != nil {
+			return err
+		}
+		days = append(days, d)
+		return nil
+	})
+	return days, err
+}
+
+// Entity sums up the edits of a bean or roaster in the window
+func (b *BigQueryAnalytics) Entity(ctx context.Context, w AnalyticsWindow, kind, slug string) (EntityEdits, error) {
+	q := b.query(`
+		SELECT
+			COUNT(*) AS Edits,
+			COUNT(DISTINCT UpdatedBy) AS Contributors,
+			MIN(UpdatedAt) AS FirstEditedAt,
+			MAX(UpdatedAt) AS LastEditedAt
+		FROM edits
+		WHERE UpdatedAt >= @from AND UpdatedAt < @to AND Kind = @kind AND Slug = @slug
+	`, w,
+		bigquery.QueryParameter{Name: "kind", Value: kind},
+		bigquery.QueryParameter{Name: "slug", Value: slug},
+	)
+
+	var (
+		entity = EntityEdits{Kind: kind, Slug: slug}
+		row    struct {
+			Edits         int64
+			Contributors  int64
+			FirstEditedAt bigquery.NullTimestamp
+			LastEditedAt  bigquery.NullTimestamp
+		}
+	)
+	err := readRows(ctx, q, func(it *bigquery.RowIterator) error {
+		return it.Next(&row)
+	})
+	if err != nil {
+		return entity, err
+	}
+
+	entity.Edits = row.Edits
+	entity.Contributors = row.Contributors
+	entity.FirstEditedAt = nullTime(row.FirstEditedAt)
+	entity.LastEditedAt = nullTime(row.LastEditedAt)
+	entity.EditsPerWeek = editsPerWeek(entity.Edits, w)
+
+	return entity, nil
+}
+
+// Contributions counts what a user added and edited over all time
+func (b *BigQueryAnalytics) Contributions(ctx context.Context, email string) (Contributions, error) {
+	q := b.client.Query(`
+		WITH beans AS (
+			SELECT
+				UpdatedBy,
+				IFNULL(Bean.Flavors, '') AS Flavors,
+				ROW_NUMBER() OVER (PARTITION BY Bean.Slug ORDER BY CAST(UpdatedAt AS TIMESTAMP)) AS N,
+				IFNULL(LAG(Bean.Flavors) OVER (PARTITION BY Bean.Slug ORDER BY CAST(UpdatedAt AS TIMESTAMP)), '') AS PreviousFlavors
+			FROM ` + "`cafebean.bean.changelog`" + `
+		),
+		roasters AS (
+			SELECT
+				UpdatedBy,
+				ROW_NUMBER() OVER (PARTITION BY Roaster.Slug ORDER BY CAST(UpdatedAt AS TIMESTAMP)) AS N
+			FROM ` + "`cafebean.roaster.changelog`" + `
+		)
+		SELECT
+			(SELECT COUNTIF(N = 1) FROM beans WHERE UpdatedBy = @email) AS BeansAdded,
+			(SELECT COUNTIF(N = 1) FROM roasters WHERE UpdatedBy = @email) AS RoastersAdded,
+			(SELECT COUNTIF(N > 1) FROM beans WHERE UpdatedBy = @email) AS BeanEdits,
+			(SELECT COUNTIF(N > 1) FROM roasters WHERE UpdatedBy = @email) AS RoasterEdits,
+			(SELECT COUNTIF(N > 1 AND Flavors != PreviousFlavors) FROM beans WHERE UpdatedBy = @email) AS FlavorEdits
+	`)
+	q.Parameters = []bigquery.QueryParameter{{Name: "email", Value: email}}
+
+	var c Contributions
+	err := readRows(ctx, q, func(it *bigquery.RowIterator) error {
+		return it.Next(&c)
+	})
+	return c, err
+}
+
+func nullTime(t bigquery.NullTimestamp) time.Time {
+	if !t.Valid {
+		return time.Time{}
+	}
+	return t.Timestamp
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testChangelog = `{"Bean": {"Slug": "jumpstart", "Name": "Jumpstart"}, "UpdatedBy": "ana@cafebean.org", "UpdatedAt": "2021-06-01T10:00:00Z"}
+{"Bean": {"Slug": "jumpstart"}, "UpdatedBy": "ben@cafebean.org", "UpdatedAt": "2021-06-01 18:30:00 UTC"}
+{"Roaster": {"Slug": "ipsento"}, "UpdatedBy": "ana@cafebean.org", "UpdatedAt": "2021-06-03T09:00:00-05:00"}
+
+{"Bean": {"Slug": "jumpstart"}, "UpdatedBy": "availability-checker", "UpdatedAt": "2021-06-03T12:00:00Z"}
+{"Bean": {"Slug": "jumpstart"}, "UpdatedBy": "ana@cafebean.org", "UpdatedAt": "2021-04-01T12:00:00Z"}
+`
+
+func Test_parseAnalyticsWindow(t *testing.T) {
+	now := time.Date(2021, 6, 10, 15, 0, 0, 0, time.UTC)
+
+	w, err := parseAnalyticsWindow(url.Values{}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2021, 6, 11, 0, 0, 0, 0, time.UTC), w.To)
+	assert.Equal(t, time.Date(2021, 5, 12, 0, 0, 0, 0, time.UTC), w.From)
+
+	w, err = parseAnalyticsWindow(url.Values{"from": {"2021-06-01"}, "to": {"2021-06-03"}}, now)
+	assert.NoError(t, err)
+	assert.True(t, w.Contains(time.Date(2021, 6, 3, 23, 0, 0, 0, time.UTC)))
+	assert.False(t, w.Contains(time.Date(2021, 6, 4, 0, 0, 0, 0, time.UTC)))
+
+	_, err = parseAnalyticsWindow(url.Values{"days": {"0"}}, now)
+	assert.Error(t, err)
+	_, err = parseAnalyticsWindow(url.Values{"from": {"2021-06-05"}, "to": {"2021-06-01"}}, now)
+	assert.Error(t, err)
+	_, err = parseAnalyticsWindow(url.Values{"from": {"2019-01-01"}}, now)
+	assert.Error(t, err)
+}
+
+func Test_MemoryAnalytics(t *testing.T) {
+	f, err := ioutil.TempFile("", "changelog*.jsonl")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(testChangelog)
+	f.Close()
+
+	m, err := NewMemoryAnalytics(f.Name())
+	assert.NoError(t, err)
+
+	var (
+		ctx = context.Background()
+		w   = AnalyticsWindow{
+			From: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2021, 6, 8, 0, 0, 0, 0, time.UTC),
+		}
+	)
+
+	contributors, err := m.Contributors(ctx, w, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []ContributorCount{
+		{Email: "ana@cafebean.org", Edits: 2, BeanEdits: 1, RoasterEdits: 1},
+		{Email: "ben@cafebean.org", Edits: 1, BeanEdits: 1},
+	}, contributors)
+
+	days, err := m.Activity(ctx, w, "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []DayActivity{
+		{Date: "2021-06-01", Beans: 2},
+		{Date: "2021-06-03", Beans: 1, Roasters: 1},
+	}, days)
+
+	days, err = m.Activity(ctx, w, "roaster", "ipsento")
+	assert.NoError(t, err)
+	assert.Equal(t, []DayActivity{{Date: "2021-06-03", Roasters: 1}}, days)
+
+	entity, err := m.Entity(ctx, w, "bean", "jumpstart")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), entity.Edits)
+	assert.Equal(t, int64(3), entity.Contributors)
+	assert.Equal(t, time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC), entity.FirstEditedAt)
+	assert.Equal(t, time.Date(2021, 6, 3, 12, 0, 0, 0, time.UTC), entity.LastEditedAt)
+	assert.Equal(t, 3.0, entity.EditsPerWeek)
+
+	contributions, err := m.Contributions(ctx, "ana@cafebean.org")
+	assert.NoError(t, err)
+	assert.Equal(t, Contributions{BeansAdded: 1, RoastersAdded: 1, BeanEdits: 1}, contributions)
+
+	_, err = parseChangelogLine([]byte(`{"UpdatedBy": "ana@cafebean.org", "UpdatedAt": "2021-06-01T10:00:00Z"}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,344 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// Availability of a bean on the roaster's site
+const (
+	availabilityInStock = "in_stock"
+	availabilitySoldOut = "sold_out"
+	availabilityGone    = "gone"
+)
+
+// availabilityCheckerUser is recorded as the author of availability changes
+const availabilityCheckerUser = "availability-checker"
+
+// maxProductPageSize is how much of a product page is read
+const maxProductPageSize = 2 * 1024 * 1024
+
+// soldOutMarkers are phrases shops use on sold out product pages
+var soldOutMarkers = [][]byte{
+	[]byte("sold out"),
+	[]byte("out of stock"),
+	[]byte("currently unavailable"),
+	[]byte("no longer available"),
+}
+
+var jsonLDPattern = regexp.MustCompile(`(?is)<script[^>]+type=["']application/ld\+json["'][^>]*>(.*?)</script>`)
+
+// AvailabilityResult is what a product page says about a bean
+type AvailabilityResult struct {
+	Availability string
+	Price        float64
+	Currency     string
+}
+
+// checkAvailability fetches a product page and works out if the bean is
+// still sold. Missing pages and redirects away from the product count as
+// gone, otherwise schema.org Product offers are preferred over sold out
+// markers in the page.
+func checkAvailability(ctx context.Context, fetcher Fetcher, productURL string) (AvailabilityResult, error) {
+	var result AvailabilityResult
+
+	req, err := http.NewRequestWithContext(ctx, "GET", productURL, nil)
+	if err != nil {
+		return result, err
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return result, fmt.Errorf("unsupported product URL %s", productURL)
+	}
+	req.Header.Set("User-Agent", "cafebean-availability-checker (+https://cafebean.org)")
+
+	resp, err := fetcher.Do(req)
+	if err != nil {
+		return result, fmt.Errorf("could not fetch %s: %v", productURL, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		result.Availability = availabilityGone
+		return result, nil
+	case resp.StatusCode != http.StatusOK:
+		return result, fmt.Errorf("%s returned %d", productURL, resp.StatusCode)
+	}
+
+	if resp.Request != nil && redirectedAway(req.URL, resp.Request.URL) {
+		result.Availability = availabilityGone
+		return result, nil
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxProductPageSize))
+	if err != nil {
+		return result, err
+	}
+
+	if offer, ok := productOffer(body); ok {
+		return offer, nil
+	}
+
+	result.Availability = availabilityInStock
+	lower := bytes.ToLower(body)
+	for _, marker := range soldOutMarkers {
+		if bytes.Contains(lower, marker) {
+			result.Availability = availabilitySoldOut
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// redirectedAway checks if a product URL was redirected to a page that isn't
+// the product anymore, usually the home page or a collection
+func redirectedAway(from, to *url.URL) bool {
+	if from.Host == to.Host && from.Path == to.Path {
+		return false
+	}
+	slug := path.Base(strings.TrimSuffix(from.Path, "/"))
+	if slug == "/" || slug == "." {
+		return false
+	}
+	return !strings.Contains(to.Path, slug)
+}
+
+// productOffer reads the first schema.org Product offer from the JSON-LD of a page
+func productOffer(body []byte) (AvailabilityResult, bool) {
+	for _, m := range jsonLDPattern.FindAllSubmatch(body, -1) {
+		var data interface{}
+		if err := json.Unmarshal(bytes.TrimSpace(m[1]), &data); err != nil {
+			continue
+		}
+		if offer, ok := findProductOffer(data); ok {
+			return offer, true
+		}
+	}
+	return AvailabilityResult{}, false
+}
+
+func findProductOffer(data interface{}) (AvailabilityResult, bool) {
+	switch v := data.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if offer, ok := findProductOffer(item); ok {
+				return offer, true
+			}
+		}
+	case map[string]interface{}:
+		if graph, ok := v["@graph"]; ok {
+			return findProductOffer(graph)
+		}
+		if !hasSchemaType(v["@type"], "Product") {
+			return AvailabilityResult{}, false
+		}
+		return parseOffers(v["offers"])
+	}
+	return AvailabilityResult{}, false
+}
+
+func hasSchemaType(t interface{}, name string) bool {
+	switch v := t.(type) {
+	case string:
+		return v == name
+	case []interface{}:
+		for _, item := range v {
+			if hasSchemaType(item, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseOffers reads an Offer, AggregateOffer or list of offers. The bean is
+// in stock if any of the offers are.
+func parseOffers(data interface{}) (AvailabilityResult, bool) {
+	var result AvailabilityResult
+
+	switch v := data.(type) {
+	case []interface{}:
+		found := false
+		for _, item := range v {
+			offer, ok := parseOffers(item)
+			if !ok {
+				continue
+			}
+			if !found || (offer.Availability == availabilityInStock && result.Availability != availabilityInStock) {
+				result = offer
+			}
+			found = true
+		}
+		return result, found
+	case map[string]interface{}:
+		if nested, ok := v["offers"]; ok {
+			return parseOffers(nested)
+		}
+
+		availability, _ := v["availability"].(string)
+		switch path.Base(availability) {
+		case "InStock", "LimitedAvailability", "OnlineOnly", "PreOrder", "PreSale":
+			result.Availability = availabilityInStock
+		case "OutOfStock", "SoldOut", "Discontinued", "InStoreOnly":
+			result.Availability = availabilitySoldOut
+		default:
+			return result, false
+		}
+
+		price := v["price"]
+		if price == nil {
+			price = v["lowPrice"]
+		}
+		switch p := price.(type) {
+		case float64:
+			result.Price = p
+		case string:
+			result.Price, _ = strconv.ParseFloat(p, 64)
+		}
+		result.Currency, _ = v["priceCurrency"].(string)
+		result.Currency = strings.ToUpper(result.Currency)
+
+		return result, true
+	}
+
+	return result, false
+}
+
+// applyAvailability updates a bean with a check result and reports if
+// anything changed
+func applyAvailability(b *Bean, result AvailabilityResult, now time.Time) bool {
+	changed := b.Availability != result.Availability
+	b.Availability = result.Availability
+	b.CheckedAt = now
+
+	inStock := result.Availability == availabilityInStock
+	for i := range b.Offerings {
+		o := &b.Offerings[i]
+		if o.InStock != inStock {
+			o.InStock = inStock
+			changed = true
+		}
+		o.CheckedAt = now
+	}
+
+	// The page only has one price, so only a single offering can be updated
+	if inStock && result.Price > 0 && len(b.Offerings) == 1 {
+		o := &b.Offerings[0]
+		if result.Currency == "" || result.Currency == o.Currency {
+			if o.Price != result.Price {
+				o.Price = result.Price
+				changed = true
+			}
+		}
+	}
+
+	return changed
+}
+
+// checkBeanAvailability checks a single bean and saves the result. The check
+// can take a while, so the bean is read again in a transaction and only its
+// availability is updated.
+func (h *Handler) checkBeanAvailability(ctx context.Context, doc *firestore.DocumentSnapshot) error {
+	checked := docToBean(doc)
+
+	result, err := checkAvailability(ctx, h.fetcher, checked.URL)
+	if err != nil {
+		return err
+	}
+
+	var (
+		bean    Bean
+		changed bool
+		stale   bool
+	)
+	err = h.database.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		current, err := tx.Get(doc.Ref)
+		if err != nil {
+			return err
+		}
+		bean = docToBean(current)
+
+		// The product page moved while it was being checked
+		stale = bean.URL != checked.URL
+		if stale {
+			return nil
+		}
+
+		changed = applyAvailability(&bean, result, time.Now())
+		return tx.Update(doc.Ref, []firestore.Update{
+			{Path: "availability", Value: bean.Availability},
+			{Path: "checked_at", Value: bean.CheckedAt},
+			{Path: "offerings", Value: bean.Offerings},
+		})
+	})
+	if err != nil || stale {
+		return err
+	}
+
+	if changed {
+		h.logger.Infow(
+			"Bean availability changed",
+			"id", doc.Ref.ID,
+			"slug", bean.Slug,
+			"availability", bean.Availability,
+		)
+		h.recordBeanChange(BeanReq{bean}, availabilityCheckerUser)
+	}
+
+	return nil
+}
+
+// CheckAvailability checks every bean with a URL, waiting between requests
+// so roasters' sites aren't hammered
+func (h *Handler) CheckAvailability(ctx context.Context) {
+	docs, err := h.database.Collection("beans").Where("url", ">", "").Documents(ctx).GetAll()
+	if err != nil {
+		h.logger.Errorw("Failed to list beans for availability check", "error", err)
+		return
+	}
+
+	for _, doc := range docs {
+		if err := h.checkBeanAvailability(ctx, doc); err != nil {
+			h.logger.Warnw("Availability check failed", "id", doc.Ref.ID, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(h.cfg.AvailabilityCheckDelay):
+		}
+	}
+
+	h.logger.Infow("Availability check finished", "beans", len(docs))
+}
+
+// RunAvailabilityChecker checks availability on an interval until the
+// context is cancelled
+func (h *Handler) RunAvailabilityChecker(ctx context.Context) {
+	ticker := time.NewTicker(h.cfg.AvailabilityCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		h.CheckAvailability(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
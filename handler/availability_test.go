@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_checkAvailability(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/products/gone", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/products/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/collections/all", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/products/renamed", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/products/renamed-ethiopia", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/products/renamed-ethiopia", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<button>Add to cart</button>`)
+	})
+	mux.HandleFunc("/collections/all", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<h1>All coffee</h1>`)
+	})
+	mux.HandleFunc("/products/schema", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head>
+<script type="application/ld+json">{"@type": "Organization", "name": "Roaster"}</script>
+<script type="application/ld+json">
+{"@context": "https://schema.org", "@type": "Product", "name": "Ethiopia",
+ "offers": [
+  {"@type": "Offer", "price": "18.00", "priceCurrency": "usd", "availability": "https://schema.org/OutOfStock"},
+  {"@type": "Offer", "price": "16.50", "priceCurrency": "usd", "availability": "https://schema.org/InStock"}
+ ]}
+</script></head><body>Related: Kenya - Sold out</body></html>`)
+	})
+	mux.HandleFunc("/products/schema-sold-out", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<script type="application/ld+json">
+{"@graph": [{"@type": "Product", "offers": {"@type": "Offer", "price": 18, "availability": "http://schema.org/SoldOut"}}]}
+</script>`)
+	})
+	mux.HandleFunc("/products/marker", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<button disabled>Sold Out</button>`)
+	})
+	mux.HandleFunc("/products/error", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		path    string
+		want    AvailabilityResult
+		wantErr bool
+	}{
+		{path: "/products/gone", want: AvailabilityResult{Availability: availabilityGone}},
+		{path: "/products/moved", want: AvailabilityResult{Availability: availabilityGone}},
+		{path: "/products/renamed", want: AvailabilityResult{Availability: availabilityInStock}},
+		{path: "/products/schema", want: AvailabilityResult{Availability: availabilityInStock, Price: 16.5, Currency: "USD"}},
+		{path: "/products/schema-sold-out", want: AvailabilityResult{Availability: availabilitySoldOut, Price: 18}},
+		{path: "/products/marker", want: AvailabilityResult{Availability: availabilitySoldOut}},
+		{path: "/products/error", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := checkAvailability(context.Background(), srv.Client(), srv.URL+tt.path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_applyAvailability(t *testing.T) {
+	now := time.Date(2020, 11, 20, 0, 0, 0, 0, time.UTC)
+	b := Bean{
+		Availability: availabilityInStock,
+		Offerings:    []Offering{{Currency: "USD", Price: 18, InStock: true}},
+	}
+
+	assert.False(t, applyAvailability(&b, AvailabilityResult{Availability: availabilityInStock, Price: 18, Currency: "USD"}, now))
+	assert.Equal(t, now, b.CheckedAt)
+
+	assert.True(t, applyAvailability(&b, AvailabilityResult{Availability: availabilityInStock, Price: 20, Currency: "USD"}, now))
+	assert.Equal(t, 20.0, b.Offerings[0].Price)
+
+	assert.True(t, applyAvailability(&b, AvailabilityResult{Availability: availabilitySoldOut}, now))
+	assert.False(t, b.Offerings[0].InStock)
+	assert.Equal(t, availabilitySoldOut, b.Availability)
+}
@@ -8,29 +8,46 @@ import (
 
 	"cloud.google.com/go/firestore"
 	"github.com/bwmarrin/discordgo"
+	"google.golang.org/api/iterator"
 )
 
 // RoasterMap represents the roaster
 type RoasterMap struct {
-	Name string `firestore:"name" json:"name"`
-	Slug string `firestore:"slug" json:"slug"`
+	Name     string `firestore:"name" json:"name"`
+	Slug     string `firestore:"slug" json:"slug"`
+	Verified bool   `firestore:"-" json:"verified" bigquery:"-"`
 }
 
 // Bean represents a coffee bean
 type Bean struct {
-	Countries   []string   `firestore:"countries" json:"countries"`
-	Description string     `firestore:"description" json:"description"`
-	DirectSun   bool       `firestore:"direct_sun" json:"direct_sun"`
-	FairTrade   bool       `firestore:"fair_trade" json:"fair_trade"`
-	Flavors     []string   `firestore:"flavors" json:"flavors"`
-	Name        string     `firestore:"name" json:"name"`
-	Organic     bool       `firestore:"organic" json:"organic"`
-	Photo       string     `firestore:"photo" json:"photo"`
-	Roaster     RoasterMap `firestore:"roaster" json:"roaster"`
-	Shade       string     `firestore:"shade" json:"shade"`
-	Slug        string     `firestore:"slug" json:"slug"`
-	URL         string     `firestore:"url" json:"url"`
-	Year        int64      `firestore:"year" json:"year"`
+	Agtron       int64      `firestore:"agtron" json:"agtron"`
+	Altitude     *Altitude  `firestore:"altitude" json:"altitude"`
+	Availability string     `firestore:"availability" json:"availability"`
+	Batches      []Batch    `firestore:"batches" json:"batches"`
+	CheckedAt    time.Time  `firestore:"checked_at" json:"checked_at"`
+	Countries    []string   `firestore:"countries" json:"countries"`
+	Description  string     `firestore:"description" json:"description"`
+	DirectSun    bool       `firestore:"direct_sun" json:"direct_sun"`
+	FairTrade    bool       `firestore:"fair_trade" json:"fair_trade"`
+	Farm         string     `firestore:"farm" json:"farm"`
+	Flavors      []string   `firestore:"flavors" json:"flavors"`
+	Name         string     `firestore:"name" json:"name"`
+	Offerings    []Offering `firestore:"offerings" json:"offerings"`
+	Organic      bool       `firestore:"organic" json:"organic"`
+	Photo        string     `firestore:"photo" json:"photo"`
+	Process      string     `firestore:"process" json:"process"`
+	Producer     string     `firestore:"producer" json:"producer"`
+	Region       string     `firestore:"region" json:"region"`
+	RoastLevel   int64      `firestore:"roast_level" json:"roast_level"`
+	Roaster      RoasterMap `firestore:"roaster" json:"roaster"`
+	Shade        string     `firestore:"shade" json:"shade"`
+	Slug         string     `firestore:"slug" json:"slug"`
+	URL          string     `firestore:"url" json:"url"`
+	Varietals    []string   `firestore:"varietals" json:"varietals"`
+	Year         int64      `firestore:"year" json:"year"`
+
+	// PricePer100g is the cheapest in-stock offering, set when listing beans
+	PricePer100g *Price `firestore:"-" json:"price_per_100g,omitempty"`
 }
 
 type BeanSimple struct {
@@ -46,16 +63,30 @@ type BeanDB struct {
 
 // BeanBQ represents a coffee bean
 type BeanBQ struct {
-	Countries   string
-	Description string
-	Flavors     string
-	Name        string
-	Photo       string
-	Roaster     RoasterMap
-	Shade       string
-	Slug        string
-	URL         string
-	Year        int64
+	Agtron       int64
+	AltitudeMin  int64
+	AltitudeMax  int64
+	Availability string
+	Countries    string
+	Description  string
+	DirectSun    bool
+	FairTrade    bool
+	Farm         string
+	Flavors      string
+	Name         string
+	Offerings    []OfferingBQ
+	Organic      bool
+	Photo        string
+	Process      string
+	Producer     string
+	Region       string
+	RoastLevel   int64
+	Roaster      RoasterMap
+	Shade        string
+	Slug         string
+	URL          string
+	Varietals    string
+	Year         int64
 }
 
 type BeanBQItem struct {
@@ -90,36 +121,217 @@ func docToBean(doc *firestore.DocumentSnapshot) Bean {
 	if b.Countries == nil {
 		b.Countries = []string{}
 	}
+	if b.Varietals == nil {
+		b.Varietals = []string{}
+	}
+	if b.Batches == nil {
+		b.Batches = []Batch{}
+	}
+	if b.Offerings == nil {
+		b.Offerings = []Offering{}
+	}
+	// Beans added before roast levels only have a shade
+	if b.RoastLevel == 0 && b.Shade != "" {
+		b.RoastLevel, _ = parseRoastLevel(b.Shade)
+	}
 	return b
 }
 
-// recordBeanChange posts a changelog event to BigQuery
-func (h *Handler) recordBeanChange(ctx context.Context, req BeanReq, userEmail string) {
+// prepareBean normalizes the flavors, origin and roast of a new bean before
+// it's written
+func prepareBean(b *Bean) error {
+	return prepareBeanUpdate(b, Bean{})
+}
+
+// prepareBeanUpdate normalizes an edit of a stored bean. Only values that
+// differ from the stored bean have to be in the controlled vocabularies.
+func prepareBeanUpdate(b *Bean, stored Bean) error {
+	b.Flavors = normalizeFlavors(b.Flavors)
+	if err := normalizeOrigin(b, stored); err != nil {
+		return err
+	}
+	return normalizeRoast(b, stored)
+}
+
+// clearManagedFields drops the batches, offerings and availability of a new
+// bean, they are managed separately
+func clearManagedFields(b *Bean) {
+	b.Batches = nil
+	b.Offerings = nil
+	b.Availability = ""
+	b.CheckedAt = time.Time{}
+}
+
+// errBeanNotFound is returned when no bean matches a slug
+var errBeanNotFound = notFoundError("bean not found")
+
+// getBeanDocBySlug fetches the firestore document for a bean
+func (h *Handler) getBeanDocBySlug(ctx context.Context, slug string) (*firestore.DocumentSnapshot, error) {
+	iter := h.database.Collection("beans").Where("slug", "==", slug).Limit(1).Documents(ctx)
+	defer iter.Stop()
+	doc, err := iter.Next()
+	if err == iterator.Done {
+		return nil, errBeanNotFound
+	}
+	return doc, err
+}
+
+// createBean adds a bean and publishes the change
+func (h *Handler) createBean(ctx context.Context, req BeanReq, userEmail string) (*firestore.DocumentRef, error) {
+	if err := prepareBean(&req.Bean); err != nil {
+		return nil, err
+	}
+
+	clearManagedFields(&req.Bean)
+
+	doc, _, err := h.database.Collection("beans").Add(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	h.logger.Infow(
+		"Bean added",
+		"id", doc.ID,
+		"updated_by", userEmail,
+	)
+
+	// Publish an entry in BigQuery
+	h.recordBeanChange(req, userEmail)
+
+	// Send a webhook event to Discord
+	h.detach("discord webhook", func(context.Context) {
+		if _, err := h.postBeanToDiscord(req, userEmail, "add"); err != nil {
+			h.logger.Error(err)
+		}
+	})
+
+	h.publishEvent(ctx, beanEvent(eventBeanAdded, req.Bean), userEmail)
+
+	return doc, nil
+}
+
+// updateBean updates an existing bean and publishes the change
+func (h *Handler) updateBean(ctx context.Context, bean *firestore.DocumentRef, req BeanReq, userEmail string) error {
+	current, err := bean.Get(ctx)
+	if err != nil {
+		return err
+	}
+	stored := docToBean(current)
+	if err := prepareBeanUpdate(&req.Bean, stored); err != nil {
+		return err
+	}
+
+	updates := []firestore.Update{
+		{Path: "countries", Value: req.Countries},
+		{Path: "flavors", Value: req.Flavors},
+		{Path: "description", Value: req.Description},
+		{Path: "name", Value: req.Name},
+		{Path: "photo", Value: req.Photo},
+		{Path: "roaster.name", Value: req.Roaster.Name},
+		{Path: "roaster.slug", Value: req.Roaster.Slug},
+		{Path: "slug", Value: req.Slug},
+		{Path: "url", Value: req.URL},
+		{Path: "region", Value: req.Region},
+		{Path: "farm", Value: req.Farm},
+		{Path: "producer", Value: req.Producer},
+		{Path: "altitude", Value: req.Altitude},
+		{Path: "varietals", Value: req.Varietals},
+		{Path: "process", Value: req.Process},
+		{Path: "shade", Value: req.Shade},
+		{Path: "roast_level", Value: req.RoastLevel},
+		{Path: "agtron", Value: req.Agtron},
+	}
+
+	// The availability was checked on the old product page
+	if req.URL != stored.URL {
+		updates = append(updates,
+			firestore.Update{Path: "availability", Value: ""},
+			firestore.Update{Path: "checked_at", Value: time.Time{}},
+		)
+	}
+
+	result, err := bean.Update(ctx, updates)
+	if err != nil {
+		return err
+	}
+	h.logger.Infow(
+		"Bean updated",
+		"id", bean.ID,
+		"updated_at", result.UpdateTime,
+		"updated_by", userEmail,
+	)
+
+	// Batches, offerings and availability aren't part of the update, record
+	// what's stored
+	if updated, err := bean.Get(ctx); err == nil {
+		stored := docToBean(updated)
+		req.Batches = stored.Batches
+		req.Offerings = stored.Offerings
+		req.Availability = stored.Availability
+	}
+
+	// Publish an entry in BigQuery
+	h.recordBeanChange(req, userEmail)
+
+	// Send a webhook event to Discord
+	h.detach("discord webhook", func(context.Context) {
+		if _, err := h.postBeanToDiscord(req, userEmail, "edit"); err != nil {
+			h.logger.Error(err)
+		}
+	})
+
+	h.publishEvent(ctx, beanEvent(eventBeanUpdated, req.Bean), userEmail)
+
+	return nil
+}
+
+// recordBeanChange posts a changelog event to BigQuery in the background
+func (h *Handler) recordBeanChange(req BeanReq, userEmail string) {
 	dataset := h.bq.DatasetInProject("cafebean", "bean")
 	table := dataset.Table("changelog")
 
+	var altitude Altitude
+	if req.Altitude != nil {
+		altitude = *req.Altitude
+	}
+
 	u := table.Inserter()
 	items := []*BeanBQItem{
 		{
 			Bean: BeanBQ{
-				Countries:   strings.Join(req.Countries, ", "),
-				Description: req.Description,
-				Flavors:     strings.Join(req.Flavors, ", "),
-				Name:        req.Name,
-				Photo:       req.Photo,
-				Roaster:     req.Roaster,
-				Shade:       req.Shade,
-				Slug:        req.Slug,
-				Year:        req.Year,
-				URL:         req.URL,
+				Agtron:       req.Agtron,
+				AltitudeMin:  altitude.Min,
+				Availability: req.Availability,
+				AltitudeMax:  altitude.Max,
+				Countries:    strings.Join(req.Countries, ", "),
+				Description:  req.Description,
+				DirectSun:    req.DirectSun,
+				FairTrade:    req.FairTrade,
+				Farm:         req.Farm,
+				Flavors:      strings.Join(req.Flavors, ", "),
+				Name:         req.Name,
+				Offerings:    offeringsToBQ(req.Offerings),
+				Organic:      req.Organic,
+				Photo:        req.Photo,
+				Process:      req.Process,
+				Producer:     req.Producer,
+				Region:       req.Region,
+				RoastLevel:   req.RoastLevel,
+				Roaster:      req.Roaster,
+				Shade:        req.Shade,
+				Slug:         req.Slug,
+				Varietals:    strings.Join(req.Varietals, ", "),
+				Year:         req.Year,
+				URL:          req.URL,
 			},
 			UpdatedBy: userEmail,
 			UpdatedAt: time.Now().Format(time.RFC3339),
 		},
 	}
-	if err := u.Put(ctx, items); err != nil {
-		h.logger.Error(err)
-	}
+	h.detach("bean changelog", func(ctx context.Context) {
+		if err := u.Put(ctx, items); err != nil {
+			h.logger.Error(err)
+		}
+	})
 }
 
 // postBeanToDiscord posts a webhook to Discord when a bean is added or updated
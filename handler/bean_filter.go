@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BeanFilter narrows down the beans returned by GET /beans
+type BeanFilter struct {
+	Country string
+	// Flavor matches any level of the flavor wheel or a free-text flavor
+	Flavor   string
+	Process  string
+	Region   string
+	Roaster  string
+	Varietal string
+	// RoastMin and RoastMax bound the roast level, inclusive
+	RoastMin int64
+	RoastMax int64
+	// RoastedAfter matches beans with a batch roasted on or after the date
+	RoastedAfter time.Time
+	// FreshAt matches beans with a batch that is fresh at the time
+	FreshAt time.Time
+	// MaxPricePer100g matches beans with an in-stock offering at or under
+	// the price, in Currency
+	MaxPricePer100g float64
+	Currency        string
+	// Certifications only match when they're asked for
+	Organic   bool
+	FairTrade bool
+	DirectSun bool
+}
+
+// parseBeanFilter reads a filter from the query string
+func parseBeanFilter(q url.Values) (BeanFilter, error) {
+	f := BeanFilter{
+		Country: strings.TrimSpace(q.Get("country")),
+		Flavor:  strings.TrimSpace(q.Get("flavor")),
+		Region:  strings.TrimSpace(q.Get("region")),
+		Roaster: strings.TrimSpace(q.Get("roaster")),
+	}
+
+	if v := strings.TrimSpace(q.Get("varietal")); v != "" {
+		t, ok := canonicalTerm(varietals, v)
+		if !ok {
+			return f, fmt.Errorf("unknown varietal %q", v)
+		}
+		f.Varietal = t
+	}
+
+	if p := strings.TrimSpace(q.Get("process")); p != "" {
+		t, ok := canonicalTerm(processes, p)
+		if !ok {
+			return f, fmt.Errorf("unknown process %q", p)
+		}
+		f.Process = t
+	}
+
+	if l := strings.TrimSpace(q.Get("roast")); l != "" {
+		level, ok := parseRoastLevel(l)
+		if !ok {
+			return f, fmt.Errorf("unknown roast level %q", l)
+		}
+		f.RoastMin, f.RoastMax = level, level
+	}
+	if l := strings.TrimSpace(q.Get("roast_min")); l != "" {
+		level, ok := parseRoastLevel(l)
+		if !ok {
+			return f, fmt.Errorf("unknown roast level %q", l)
+		}
+		f.RoastMin = level
+	}
+	if l := strings.TrimSpace(q.Get("roast_max")); l != "" {
+		level, ok := parseRoastLevel(l)
+		if !ok {
+			return f, fmt.Errorf("unknown roast level %q", l)
+		}
+		f.RoastMax = level
+	}
+
+	if d := strings.TrimSpace(q.Get("roasted_after")); d != "" {
+		t, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			return f, fmt.Errorf("roasted_after must be a date like 2006-01-02")
+		}
+		f.RoastedAfter = t
+	}
+
+	if fresh := q.Get("fresh"); fresh != "" {
+		ok, err := strconv.ParseBool(fresh)
+		if err != nil {
+			return f, fmt.Errorf("fresh must be true or false")
+		}
+		if ok {
+			f.FreshAt = time.Now()
+		}
+	}
+
+	for param, field := range map[string]*bool{
+		"organic":    &f.Organic,
+		"fair_trade": &f.FairTrade,
+		"direct_sun": &f.DirectSun,
+	} {
+		if v := q.Get(param); v != "" {
+			ok, err := strconv.ParseBool(v)
+			if err != nil {
+				return f, fmt.Errorf("%s must be true or false", param)
+			}
+			*field = ok
+		}
+	}
+
+	f.Currency = strings.ToUpper(strings.TrimSpace(q.Get("currency")))
+	if p := q.Get("max_price_per_100g"); p != "" {
+		price, err := strconv.ParseFloat(p, 64)
+		if err != nil || price <= 0 {
+			return f, fmt.Errorf("max_price_per_100g must be a positive number")
+		}
+		f.MaxPricePer100g = price
+	}
+
+	return f, nil
+}
+
+// Match checks if a bean passes the filter
+func (f BeanFilter) Match(b Bean) bool {
+	if f.Roaster != "" && b.Roaster.Slug != f.Roaster {
+		return false
+	}
+
+	if f.Country != "" && !containsFold(b.Countries, f.Country) {
+		return false
+	}
+
+	if f.Region != "" && !strings.EqualFold(b.Region, f.Region) {
+		return false
+	}
+
+	if f.Varietal != "" && !containsString(b.Varietals, f.Varietal) {
+		return false
+	}
+
+	if f.Process != "" && b.Process != f.Process {
+		return false
+	}
+
+	if f.RoastMin > 0 && (b.RoastLevel == 0 || b.RoastLevel < f.RoastMin) {
+		return false
+	}
+	if f.RoastMax > 0 && (b.RoastLevel == 0 || b.RoastLevel > f.RoastMax) {
+		return false
+	}
+
+	if !f.RoastedAfter.IsZero() {
+		latest := latestBatch(b)
+		if latest == nil || latest.RoastedAt.Before(f.RoastedAfter) {
+			return false
+		}
+	}
+
+	if !f.FreshAt.IsZero() && !isFresh(b, f.FreshAt) {
+		return false
+	}
+
+	if (f.Organic && !b.Organic) || (f.FairTrade && !b.FairTrade) || (f.DirectSun && !b.DirectSun) {
+		return false
+	}
+
+	if f.MaxPricePer100g > 0 && (b.PricePer100g == nil || b.PricePer100g.Amount > f.MaxPricePer100g) {
+		return false
+	}
+
+	if f.Flavor != "" {
+		found := false
+		for _, flavor := range b.Flavors {
+			if flavorMatches(flavor, f.Flavor) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// beanSortKeys are the keys GET /beans can be sorted by. Beans without a
+// value always sort last.
+var beanSortKeys = map[string]func(b Bean) (float64, bool){
+	"roast_level": func(b Bean) (float64, bool) {
+		return float64(b.RoastLevel), b.RoastLevel > 0
+	},
+	"agtron": func(b Bean) (float64, bool) {
+		return float64(b.Agtron), b.Agtron > 0
+	},
+	"price_per_100g": func(b Bean) (float64, bool) {
+		if b.PricePer100g == nil {
+			return 0, false
+		}
+		return b.PricePer100g.Amount, true
+	},
+	"roasted_at": func(b Bean) (float64, bool) {
+		latest := latestBatch(b)
+		if latest == nil {
+			return 0, false
+		}
+		return float64(latest.RoastedAt.Unix()), true
+	},
+}
+
+// sortBeans sorts beans by a key, descending when it is prefixed with "-"
+func sortBeans(beans []Bean, key string) error {
+	desc := strings.HasPrefix(key, "-")
+	value, ok := beanSortKeys[strings.TrimPrefix(key, "-")]
+	if !ok {
+		return fmt.Errorf("invalid sort %q", key)
+	}
+
+	sort.SliceStable(beans, func(i, j int) bool {
+		a, aok := value(beans[i])
+		b, bok := value(beans[j])
+		if aok != bok {
+			return aok
+		}
+		if desc {
+			return a > b
+		}
+		return a < b
+	})
+
+	return nil
+}
+
+// IsZero checks if the filter matches every bean
+func (f BeanFilter) IsZero() bool {
+	return f == BeanFilter{Currency: f.Currency}
+}
+
+func containsFold(list []string, s string) bool {
+	for _, l := range list {
+		if strings.EqualFold(strings.TrimSpace(l), s) {
+			return true
+		}
+	}
+	return false
+}
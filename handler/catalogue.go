@@ -0,0 +1,371 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// Catalogue formats
+const (
+	formatCSV   = "csv"
+	formatJSONL = "jsonl"
+)
+
+// listSeparator joins list values in a CSV cell
+const listSeparator = "; "
+
+// beanColumns are the CSV columns of a bean, in export order
+var beanColumns = []string{
+	"slug", "name", "roaster_slug", "description", "countries", "region",
+	"farm", "producer", "altitude_min", "altitude_max", "varietals", "process",
+	"flavors", "shade", "agtron", "year", "organic", "fair_trade", "direct_sun",
+	"url", "photo",
+}
+
+// roasterColumns are the CSV columns of a roaster, in export order
+var roasterColumns = []string{
+	"slug", "name", "city", "region", "country", "latitude", "longitude",
+	"url", "instagram", "twitter", "logo", "ships_to", "wholesale", "founded",
+}
+
+// CatalogueRow is a bean or roaster read from an import file
+type CatalogueRow struct {
+	Line    int
+	Bean    Bean
+	Roaster Roaster
+	Err     error
+}
+
+func joinList(l []string) string {
+	return strings.Join(l, listSeparator)
+}
+
+func splitCell(s string) []string {
+	var items = []string{}
+	for _, item := range strings.Split(s, ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func formatInt(i int64) string {
+	if i == 0 {
+		return ""
+	}
+	return strconv.FormatInt(i, 10)
+}
+
+func parseInt(column, s string) (int64, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return 0, nil
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a whole number", column)
+	}
+	return i, nil
+}
+
+func parseBool(column, s string) (bool, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(strings.ToLower(s))
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false", column)
+	}
+	return b, nil
+}
+
+// beanToRecord writes a bean as a CSV record
+func beanToRecord(b Bean) []string {
+	var altitude Altitude
+	if b.Altitude != nil {
+		altitude = *b.Altitude
+	}
+	return []string{
+		b.Slug, b.Name, b.Roaster.Slug, b.Description, joinList(b.Countries), b.Region,
+		b.Farm, b.Producer, formatInt(altitude.Min), formatInt(altitude.Max), joinList(b.Varietals), b.Process,
+		joinList(b.Flavors), b.Shade, formatInt(b.Agtron), formatInt(b.Year), strconv.FormatBool(b.Organic), strconv.FormatBool(b.FairTrade), strconv.FormatBool(b.DirectSun),
+		b.URL, b.Photo,
+	}
+}
+
+// recordToBean reads a bean from a CSV record keyed by column
+func recordToBean(r map[string]string) (Bean, error) {
+	var (
+		b = Bean{
+			Slug:        strings.TrimSpace(r["slug"]),
+			Name:        strings.TrimSpace(r["name"]),
+			Roaster:     RoasterMap{Slug: strings.TrimSpace(r["roaster_slug"])},
+			Description: r["description"],
+			Countries:   splitCell(r["countries"]),
+			Region:      r["region"],
+			Farm:        r["farm"],
+			Producer:    r["producer"],
+			Varietals:   splitCell(r["varietals"]),
+			Process:     r["process"],
+			Flavors:     splitCell(r["flavors"]),
+			Shade:       r["shade"],
+			URL:         strings.TrimSpace(r["url"]),
+			Photo:       strings.TrimSpace(r["photo"]),
+		}
+		altitude Altitude
+		err      error
+	)
+
+	if altitude.Min, err = parseInt("altitude_min", r["altitude_min"]); err != nil {
+		return b, err
+	}
+	if altitude.Max, err = parseInt("altitude_max", r["altitude_max"]); err != nil {
+		return b, err
+	}
+	if altitude.Min > 0 || altitude.Max > 0 {
+		b.Altitude = &altitude
+	}
+	if b.Agtron, err = parseInt("agtron", r["agtron"]); err != nil {
+		return b, err
+	}
+	if b.Year, err = parseInt("year", r["year"]); err != nil {
+		return b, err
+	}
+	if b.Organic, err = parseBool("organic", r["organic"]); err != nil {
+		return b, err
+	}
+	if b.FairTrade, err = parseBool("fair_trade", r["fair_trade"]); err != nil {
+		return b, err
+	}
+	if b.DirectSun, err = parseBool("direct_sun", r["direct_sun"]); err != nil {
+		return b, err
+	}
+
+	return b, nil
+}
+
+// roasterToRecord writes a roaster as a CSV record
+func roasterToRecord(r Roaster) []string {
+	var lat, lng string
+	if r.Location != nil {
+		lat = strconv.FormatFloat(r.Location.Latitude, 'f', -1, 64)
+		lng = strconv.FormatFloat(r.Location.Longitude, 'f', -1, 64)
+	}
+	return []string{
+		r.Slug, r.Name, r.City, r.Region, r.Country, lat, lng,
+		r.URL, r.Instagram, r.Twitter, r.Logo, joinList(r.ShipsTo), strconv.FormatBool(r.Wholesale), formatInt(r.Founded),
+	}
+}
+
+// recordToRoaster reads a roaster from a CSV record keyed by column
+func recordToRoaster(r map[string]string) (Roaster, error) {
+	roaster := Roaster{
+		Slug:      strings.TrimSpace(r["slug"]),
+		Name:      strings.TrimSpace(r["name"]),
+		City:      strings.TrimSpace(r["city"]),
+		Region:    strings.TrimSpace(r["region"]),
+		Country:   strings.TrimSpace(r["country"]),
+		URL:       strings.TrimSpace(r["url"]),
+		Instagram: strings.TrimSpace(r["instagram"]),
+		Twitter:   strings.TrimSpace(r["twitter"]),
+		Logo:      strings.TrimSpace(r["logo"]),
+		ShipsTo:   splitCell(r["ships_to"]),
+	}
+
+	var err error
+	if roaster.Wholesale, err = parseBool("wholesale", r["wholesale"]); err != nil {
+		return roaster, err
+	}
+	if roaster.Founded, err = parseInt("founded", r["founded"]); err != nil {
+		return roaster, err
+	}
+
+	lat, lng := strings.TrimSpace(r["latitude"]), strings.TrimSpace(r["longitude"])
+	if lat == "" && lng == "" {
+		return roaster, nil
+	}
+	latitude, err := strconv.ParseFloat(lat, 64)
+	if err != nil {
+		return roaster, fmt.Errorf("latitude must be a number")
+	}
+	longitude, err := strconv.ParseFloat(lng, 64)
+	if err != nil {
+		return roaster, fmt.Errorf("longitude must be a number")
+	}
+	roaster.Location = &latlng.LatLng{Latitude: latitude, Longitude: longitude}
+
+	return roaster, nil
+}
+
+// validateBeanRow checks the fields every imported bean needs. stored is the
+// bean the row updates, if any.
+func validateBeanRow(b *Bean, stored Bean) error {
+	switch {
+	case b.Slug == "":
+		return fmt.Errorf("slug is required")
+	case b.Slug != slugify(b.Slug):
+		return fmt.Errorf("slug must be lowercase letters, numbers and dashes")
+	case b.Name == "":
+		return fmt.Errorf("name is required")
+	case b.Roaster.Slug == "":
+		return fmt.Errorf("roaster_slug is required")
+	}
+	return prepareBeanUpdate(b, stored)
+}
+
+// validateRoasterRow checks the fields every imported roaster needs
+func validateRoasterRow(r *Roaster) error {
+	switch {
+	case r.Slug == "":
+		return fmt.Errorf("slug is required")
+	case r.Slug != slugify(r.Slug):
+		return fmt.Errorf("slug must be lowercase letters, numbers and dashes")
+	case r.Name == "":
+		return fmt.Errorf("name is required")
+	}
+	if err := prepareRoaster(r, time.Now()); err != nil {
+		return err
+	}
+	// Roasters are only verified through a claim
+	r.Verified = false
+	return nil
+}
+
+// readCatalogue reads the rows of a CSV or JSONL file. Rows that can't be
+// parsed are returned with an error so the rest of the file can still be
+// imported.
+func readCatalogue(body io.Reader, entity, format string) ([]CatalogueRow, error) {
+	switch format {
+	case formatCSV:
+		return readCatalogueCSV(body, entity)
+	case formatJSONL:
+		return readCatalogueJSONL(body, entity)
+	}
+	return nil, fmt.Errorf("format must be %s or %s", formatCSV, formatJSONL)
+}
+
+func readCatalogueCSV(body io.Reader, entity string) ([]CatalogueRow, error) {
+	var rows []CatalogueRow
+
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("could not read the header: %v", err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	if !containsString(header, "slug") {
+		return nil, fmt.Errorf("the header must have a slug column")
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		row := CatalogueRow{Line: line}
+		if err != nil {
+			row.Err = err
+			rows = append(rows, row)
+			continue
+		}
+
+		values := make(map[string]string)
+		for i, column := range header {
+			if i < len(record) {
+				values[column] = record[i]
+			}
+		}
+		if entity == "roasters" {
+			row.Roaster, row.Err = recordToRoaster(values)
+		} else {
+			row.Bean, row.Err = recordToBean(values)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func readCatalogueJSONL(body io.Reader, entity string) ([]CatalogueRow, error) {
+	var rows []CatalogueRow
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		row := CatalogueRow{Line: line}
+		if entity == "roasters" {
+			row.Err = json.Unmarshal(data, &row.Roaster)
+		} else {
+			row.Err = json.Unmarshal(data, &row.Bean)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, scanner.Err()
+}
+
+// beanImportUpdates are the fields of a bean an import writes. Batches,
+// offerings and availability are left alone.
+func beanImportUpdates(b Bean) []firestore.Update {
+	return []firestore.Update{
+		{Path: "slug", Value: b.Slug},
+		{Path: "name", Value: b.Name},
+		{Path: "roaster", Value: b.Roaster},
+		{Path: "description", Value: b.Description},
+		{Path: "countries", Value: b.Countries},
+		{Path: "region", Value: b.Region},
+		{Path: "farm", Value: b.Farm},
+		{Path: "producer", Value: b.Producer},
+		{Path: "altitude", Value: b.Altitude},
+		{Path: "varietals", Value: b.Varietals},
+		{Path: "process", Value: b.Process},
+		{Path: "flavors", Value: b.Flavors},
+		{Path: "shade", Value: b.Shade},
+		{Path: "roast_level", Value: b.RoastLevel},
+		{Path: "agtron", Value: b.Agtron},
+		{Path: "year", Value: b.Year},
+		{Path: "organic", Value: b.Organic},
+		{Path: "fair_trade", Value: b.FairTrade},
+		{Path: "direct_sun", Value: b.DirectSun},
+		{Path: "url", Value: b.URL},
+		{Path: "photo", Value: b.Photo},
+	}
+}
+
+// roasterImportUpdates are the fields of a roaster an import writes. The
+// owner and verified badge are left alone.
+func roasterImportUpdates(r Roaster) []firestore.Update {
+	return []firestore.Update{
+		{Path: "slug", Value: r.Slug},
+		{Path: "name", Value: r.Name},
+		{Path: "city", Value: r.City},
+		{Path: "region", Value: r.Region},
+		{Path: "country", Value: r.Country},
+		{Path: "location", Value: r.Location},
+		{Path: "geohash", Value: roasterGeohash(r)},
+		{Path: "url", Value: r.URL},
+		{Path: "instagram", Value: r.Instagram},
+		{Path: "twitter", Value: r.Twitter},
+		{Path: "logo", Value: r.Logo},
+		{Path: "ships_to", Value: r.ShipsTo},
+		{Path: "wholesale", Value: r.Wholesale},
+		{Path: "founded", Value: r.Founded},
+	}
+}
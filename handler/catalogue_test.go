@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_beanRecordRoundTrip(t *testing.T) {
+	b := Bean{
+		Slug:      "ipsento-cascade-espresso",
+		Name:      "Cascade Espresso",
+		Roaster:   RoasterMap{Slug: "ipsento"},
+		Countries: []string{"Brazil", "Ethiopia"},
+		Flavors:   []string{"dark chocolate", "mixed nuts"},
+		Varietals: []string{"Bourbon"},
+		Altitude:  &Altitude{Min: 1100, Max: 1300},
+		Shade:     "dark",
+		Year:      2020,
+		Organic:   true,
+	}
+
+	var buf bytes.Buffer
+	out := csv.NewWriter(&buf)
+	out.Write(beanColumns)
+	out.Write(beanToRecord(b))
+	out.Flush()
+
+	rows, err := readCatalogue(&buf, "beans", formatCSV)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.NoError(t, rows[0].Err)
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Equal(t, b, rows[0].Bean)
+}
+
+func Test_readCatalogue(t *testing.T) {
+	rows, err := readCatalogue(strings.NewReader("slug,name,latitude,longitude\nipsento,Ipsento,41.9,-87.7\nbad,Bad,north,\n"), "roasters", formatCSV)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, 41.9, rows[0].Roaster.Location.Latitude)
+	assert.EqualError(t, rows[1].Err, "latitude must be a number")
+
+	rows, err = readCatalogue(strings.NewReader("{\"slug\": \"ipsento\", \"name\": \"Ipsento\"}\n\n{nope\n"), "roasters", formatJSONL)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, "ipsento", rows[0].Roaster.Slug)
+	assert.Equal(t, 3, rows[1].Line)
+	assert.Error(t, rows[1].Err)
+
+	_, err = readCatalogue(strings.NewReader("name\nIpsento\n"), "roasters", formatCSV)
+	assert.Error(t, err)
+}
+
+func Test_planCatalogueImport(t *testing.T) {
+	roasters := map[string]Roaster{"ipsento": {Name: "Ipsento", Slug: "ipsento"}}
+	beans := map[string]Bean{
+		"ipsento-cascade": {
+			Slug:      "ipsento-cascade",
+			Name:      "Cascade",
+			Roaster:   RoasterMap{Name: "Ipsento", Slug: "ipsento"},
+			Countries: []string{"Brazil"},
+			Flavors:   []string{"chocolate"},
+			Offerings: []Offering{{ID: "abc"}},
+		},
+	}
+	rows := []CatalogueRow{
+		{Line: 2, Bean: Bean{Slug: "ipsento-cascade", Name: "Cascade", Roaster: RoasterMap{Slug: "ipsento"}, Countries: []string{"Brazil"}, Flavors: []string{"Chocolate"}}},
+		{Line: 3, Bean: Bean{Slug: "ipsento-cascade", Name: "Cascade", Roaster: RoasterMap{Slug: "ipsento"}}},
+		{Line: 4, Bean: Bean{Slug: "ipsento-finch", Name: "Finch", Roaster: RoasterMap{Slug: "ipsento"}, Process: "wet", Offerings: []Offering{{Price: 18}}, Availability: availabilityInStock}},
+		{Line: 5, Bean: Bean{Slug: "nobody-beans", Name: "Beans", Roaster: RoasterMap{Slug: "nobody"}}},
+		{Line: 6, Bean: Bean{Slug: "Not A Slug", Name: "Beans", Roaster: RoasterMap{Slug: "ipsento"}}},
+	}
+
+	changes := planCatalogueImport(rows, "beans", beans, roasters)
+
+	var actions []string
+	for _, c := range changes {
+		actions = append(actions, c.Result.Action)
+	}
+	assert.Equal(t, []string{rowSkipped, rowFailed, rowCreated, rowFailed, rowFailed}, actions)
+	assert.Equal(t, "duplicate of line 2", changes[1].Result.Error)
+	assert.Equal(t, "Washed", changes[2].Bean.Process)
+	assert.Equal(t, "Ipsento", changes[2].Bean.Roaster.Name)
+	assert.Nil(t, changes[2].Bean.Offerings)
+	assert.Empty(t, changes[2].Bean.Availability)
+	assert.Equal(t, `unknown roaster "nobody"`, changes[3].Result.Error)
+
+	// Updates keep the fields the file doesn't have
+	rows[0].Bean.Flavors = []string{"caramel"}
+	changes = planCatalogueImport(rows[:1], "beans", beans, roasters)
+	assert.Equal(t, rowUpdated, changes[0].Result.Action)
+	assert.Equal(t, []Offering{{ID: "abc"}}, changes[0].Bean.Offerings)
+}
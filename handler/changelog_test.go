@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"io/ioutil"
+	"sort"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_changelogSchemas(t *testing.T) {
+	tests := []struct {
+		file string
+		item interface{}
+	}{
+		{"../bigquery/schemas/bean_changelog.json", BeanBQItem{}},
+		{"../bigquery/schemas/roaster_changelog.json", RoasterBQItem{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			b, err := ioutil.ReadFile(tt.file)
+			if !assert.NoError(t, err) {
+				return
+			}
+			table, err := bigquery.SchemaFromJSON(b)
+			if !assert.NoError(t, err) {
+				return
+			}
+			inferred, err := bigquery.InferSchema(tt.item)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, schemaColumns(inferred, ""), schemaColumns(table, ""))
+		})
+	}
+}
+
+// schemaColumns flattens a schema to sorted "path type" pairs, so the
+// comparison ignores column order and modes
+func schemaColumns(s bigquery.Schema, prefix string) []string {
+	var columns []string
+	for _, f := range s {
+		name := prefix + f.Name
+		column := name + " " + string(f.Type)
+		if f.Repeated {
+			column += " repeated"
+		}
+		columns = append(columns, column)
+		columns = append(columns, schemaColumns(f.Schema, name+".")...)
+	}
+	sort.Strings(columns)
+	return columns
+}
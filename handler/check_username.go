@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"context"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -11,7 +10,7 @@ import (
 // checkUsername checks if a username is taken
 func (h *Handler) checkUsername(w http.ResponseWriter, r *http.Request) {
 	var (
-		ctx      = context.TODO()
+		ctx      = r.Context()
 		vars     = mux.Vars(r)
 		username = vars["username"]
 	)
@@ -29,12 +28,12 @@ func (h *Handler) checkUsername(w http.ResponseWriter, r *http.Request) {
 
 		// Error case
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.writeError(w, r, err)
 			return
 		}
 
 		// User found, return 400
-		http.Error(w, "username taken", http.StatusBadRequest)
+		h.writeError(w, r, conflictError("username taken"))
 		return
 	}
 }
@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Claim statuses
+const (
+	claimPending  = "pending"
+	claimVerified = "verified"
+	claimRejected = "rejected"
+)
+
+// claimTokenPath is where roasters publish their verification token
+const claimTokenPath = "/.well-known/cafebean-verification.txt"
+
+// claimURLCooldown is how long a roaster's website must stay the same
+// before it can be used to verify a claim
+const claimURLCooldown = 7 * 24 * time.Hour
+
+// errClaimNotFound is returned when a claim doesn't exist
+var errClaimNotFound = notFoundError("claim not found")
+
+// Claim is a request from a user to become the owner of a roaster
+type Claim struct {
+	ID              string    `firestore:"-" json:"id"`
+	RoasterSlug     string    `firestore:"roaster_slug" json:"roaster_slug"`
+	Email           string    `firestore:"email" json:"email"`
+	Token           string    `firestore:"token" json:"token"`
+	VerificationURL string    `firestore:"verification_url" json:"verification_url"`
+	Method          string    `firestore:"method" json:"method,omitempty"`
+	Status          string    `firestore:"status" json:"status"`
+	CreatedAt       time.Time `firestore:"created_at" json:"created_at"`
+	ReviewedBy      string    `firestore:"reviewed_by" json:"reviewed_by,omitempty"`
+	ReviewedAt      time.Time `firestore:"reviewed_at" json:"reviewed_at,omitempty"`
+}
+
+func docToClaim(doc *firestore.DocumentSnapshot) Claim {
+	var c Claim
+	doc.DataTo(&c)
+	c.ID = doc.Ref.ID
+	return c
+}
+
+// newClaimToken generates a random verification token
+func newClaimToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "cafebean-verification=" + hex.EncodeToString(b), nil
+}
+
+// claimVerificationURL returns the URL on the roaster's domain that must
+// serve the verification token
+func claimVerificationURL(siteURL string) (string, error) {
+	u, err := url.Parse(siteURL)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("roaster has no valid website")
+	}
+	return fmt.Sprintf("https://%s%s", u.Host, claimTokenPath), nil
+}
+
+// checkClaimToken fetches the verification URL and makes sure it serves the token
+func checkClaimToken(ctx context.Context, fetcher Fetcher, verificationURL, token string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", verificationURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := fetcher.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not fetch %s: %v", verificationURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", verificationURL, resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return err
+	}
+	if !bytes.Contains(body, []byte(token)) {
+		return fmt.Errorf("token not found at %s", verificationURL)
+	}
+
+	return nil
+}
+
+// getPendingClaim fetches the open claim of a user on a roaster
+func (h *Handler) getPendingClaim(ctx context.Context, slug string, userEmail string) (*firestore.DocumentSnapshot, error) {
+	docs, err := h.database.Collection("claims").
+		Where("roaster_slug", "==", slug).
+		Where("email", "==", userEmail).
+		Where("status", "==", claimPending).
+		Limit(1).
+		Documents(ctx).
+		GetAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, errClaimNotFound
+	}
+	return docs[0], nil
+}
+
+// verifyClaim marks the roaster as verified and makes the claimant its owner.
+// The claim and the roaster are checked and updated in one transaction, and
+// the other open claims on the roaster are rejected.
+func (h *Handler) verifyClaim(ctx context.Context, ref *firestore.DocumentRef, c Claim, method string, reviewedBy string) (Claim, error) {
+	roasterDoc, err := h.getRoasterDocBySlug(ctx, c.RoasterSlug)
+	if err != nil {
+		return c, err
+	}
+
+	var competing []Claim
+	err = h.database.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return errClaimNotFound
+		}
+		if err != nil {
+			return err
+		}
+		c = docToClaim(doc)
+		if c.Status != claimPending {
+			return conflictError(fmt.Sprintf("claim already %s", c.Status))
+		}
+
+		doc, err = tx.Get(roasterDoc.Ref)
+		if err != nil {
+			return err
+		}
+		roaster := docToRoasterDB(doc)
+		if roaster.Verified || roaster.Owner != "" {
+			return conflictError("roaster is already verified")
+		}
+		if method == "domain" && !domainClaimAllowed(roaster, c.CreatedAt) {
+			return validationError("roaster website changed recently, wait for a moderator to approve the claim")
+		}
+
+		others, err := tx.Documents(h.database.Collection("claims").
+			Where("roaster_slug", "==", c.RoasterSlug).
+			Where("status", "==", claimPending)).
+			GetAll()
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		competing = nil
+		for _, other := range others {
+			if other.Ref.ID == ref.ID {
+				continue
+			}
+			competing = append(competing, docToClaim(other))
+			err = tx.Update(other.Ref, []firestore.Update{
+				{Path: "status", Value: claimRejected},
+				{Path: "reviewed_by", Value: reviewedBy},
+				{Path: "reviewed_at", Value: now},
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.Update(roasterDoc.Ref, []firestore.Update{
+			{Path: "verified", Value: true},
+			{Path: "owner", Value: c.Email},
+		})
+		if err != nil {
+			return err
+		}
+
+		c.Status = claimVerified
+		c.Method = method
+		c.ReviewedBy = reviewedBy
+		c.ReviewedAt = now
+		return tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: c.Status},
+			{Path: "method", Value: c.Method},
+			{Path: "reviewed_by", Value: c.ReviewedBy},
+			{Path: "reviewed_at", Value: c.ReviewedAt},
+		})
+	})
+	if err != nil {
+		return c, err
+	}
+
+	h.logger.Infow(
+		"Roaster verified",
+		"slug", c.RoasterSlug,
+		"owner", c.Email,
+		"method", method,
+		"rejected_claims", len(competing),
+	)
+
+	h.notify(ctx, Notification{
+		User:    c.Email,
+		Message: fmt.Sprintf("You are now the verified owner of %s", c.RoasterSlug),
+	})
+	for _, other := range competing {
+		h.notify(ctx, Notification{
+			User:    other.Email,
+			Message: fmt.Sprintf("Your claim on %s was rejected: the roaster was verified by its owner", c.RoasterSlug),
+		})
+	}
+
+	return c, nil
+}
+
+// closeClaim rejects a pending claim in a transaction
+func (h *Handler) closeClaim(ctx context.Context, ref *firestore.DocumentRef, reviewedBy string) (Claim, error) {
+	var c Claim
+	err := h.database.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return errClaimNotFound
+		}
+		if err != nil {
+			return err
+		}
+		c = docToClaim(doc)
+		if c.Status != claimPending {
+			return conflictError(fmt.Sprintf("claim already %s", c.Status))
+		}
+
+		c.Status = claimRejected
+		c.ReviewedBy = reviewedBy
+		c.ReviewedAt = time.Now()
+		return tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: c.Status},
+			{Path: "reviewed_by", Value: c.ReviewedBy},
+			{Path: "reviewed_at", Value: c.ReviewedAt},
+		})
+	})
+	return c, err
+}
+
+// domainClaimAllowed checks that a roaster's website has been settled long
+// enough for a domain claim made at claimedAt. Otherwise a user could point
+// the website to a domain they control and claim the roaster.
+func domainClaimAllowed(roaster RoasterDB, claimedAt time.Time) bool {
+	if roaster.URLChangedAt.IsZero() {
+		return true
+	}
+	return roaster.URLChangedAt.Before(claimedAt) && claimedAt.Sub(roaster.URLChangedAt) >= claimURLCooldown
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ClaimResp is the response from the claim endpoints
+type ClaimResp struct {
+	Claim Claim `json:"claim"`
+}
+
+// claimRoaster starts a claim on a roaster. The user can prove ownership by
+// serving the returned token on the roaster's domain, or wait for a moderator.
+func (h *Handler) claimRoaster(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		vars      = mux.Vars(r)
+		slug      = vars["slug"]
+		resp      = &ClaimResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	if userEmail == "" {
+		h.writeError(w, r, validationError("invalid user"))
+		return
+	}
+
+	roasterDoc, err := h.getRoasterDocBySlug(ctx, slug)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	roaster := docToRoasterDB(roasterDoc)
+	if roaster.Verified || roaster.Owner != "" {
+		h.writeError(w, r, conflictError("roaster is already verified"))
+		return
+	}
+
+	// Reuse an open claim so the token stays the same
+	claimDoc, err := h.getPendingClaim(ctx, slug, userEmail)
+	if err == nil {
+		resp.Claim = docToClaim(claimDoc)
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if err != errClaimNotFound {
+		h.writeError(w, r, err)
+		return
+	}
+
+	token, err := newClaimToken()
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	// Domain verification is only possible if the roaster has a website that
+	// didn't change recently. The domain is pinned on the claim.
+	now := time.Now()
+	var verificationURL string
+	if domainClaimAllowed(roaster, now) {
+		verificationURL, _ = claimVerificationURL(roaster.URL)
+	}
+
+	c := Claim{
+		RoasterSlug:     slug,
+		Email:           userEmail,
+		Token:           token,
+		VerificationURL: verificationURL,
+		Status:          claimPending,
+		CreatedAt:       now,
+	}
+	doc, _, err := h.database.Collection("claims").Add(ctx, c)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	c.ID = doc.ID
+	h.logger.Infow(
+		"Roaster claimed",
+		"id", doc.ID,
+		"slug", slug,
+		"claimed_by", userEmail,
+	)
+
+	resp.Claim = c
+
+	w.WriteHeader(http.StatusAccepted)
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// verifyRoasterClaim checks the roaster's domain for the claim token
+func (h *Handler) verifyRoasterClaim(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		vars      = mux.Vars(r)
+		slug      = vars["slug"]
+		resp      = &ClaimResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	claimDoc, err := h.getPendingClaim(ctx, slug, userEmail)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	c := docToClaim(claimDoc)
+
+	if c.VerificationURL == "" {
+		h.writeError(w, r, validationError("roaster has no valid or settled website, wait for a moderator to approve the claim"))
+		return
+	}
+
+	if err = checkClaimToken(ctx, h.fetcher, c.VerificationURL, c.Token); err != nil {
+		h.writeError(w, r, validationError(err.Error()))
+		return
+	}
+
+	resp.Claim, err = h.verifyClaim(ctx, claimDoc.Ref, c, "domain", userEmail)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
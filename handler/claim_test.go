@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubFetcher returns a canned response for every request
+type stubFetcher struct {
+	status int
+	body   string
+	err    error
+	url    string
+}
+
+func (f *stubFetcher) Do(req *http.Request) (*http.Response, error) {
+	f.url = req.URL.String()
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &http.Response{
+		StatusCode: f.status,
+		Body:       ioutil.NopCloser(strings.NewReader(f.body)),
+	}, nil
+}
+
+func Test_claimVerificationURL(t *testing.T) {
+	u, err := claimVerificationURL("http://www.ipsento.com/shop?x=1")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://www.ipsento.com/.well-known/cafebean-verification.txt", u)
+
+	_, err = claimVerificationURL("")
+	assert.Error(t, err)
+}
+
+func Test_checkClaimToken(t *testing.T) {
+	type test struct {
+		name    string
+		fetcher *stubFetcher
+		ok      bool
+	}
+
+	token := "cafebean-verification=abc123"
+	verificationURL := "https://ipsento.com" + claimTokenPath
+
+	tests := []test{
+		{
+			name:    "token served",
+			fetcher: &stubFetcher{status: 200, body: token + "\n"},
+			ok:      true,
+		},
+		{
+			name:    "wrong token",
+			fetcher: &stubFetcher{status: 200, body: "cafebean-verification=nope"},
+		},
+		{
+			name:    "missing file",
+			fetcher: &stubFetcher{status: 404},
+		},
+		{
+			name:    "fetch error",
+			fetcher: &stubFetcher{err: errors.New("connection refused")},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkClaimToken(context.Background(), tc.fetcher, verificationURL, token)
+			assert.Equal(t, tc.ok, err == nil, err)
+			assert.Equal(t, verificationURL, tc.fetcher.url)
+		})
+	}
+}
+
+func Test_domainClaimAllowed(t *testing.T) {
+	now := time.Now()
+
+	type test struct {
+		name      string
+		changedAt time.Time
+		claimedAt time.Time
+		exp       bool
+	}
+
+	tests := []test{
+		{name: "website never changed", claimedAt: now, exp: true},
+		{name: "website settled before the claim", changedAt: now.Add(-30 * 24 * time.Hour), claimedAt: now, exp: true},
+		{name: "website changed recently", changedAt: now.Add(-time.Hour), claimedAt: now, exp: false},
+		{name: "website changed after the claim", changedAt: now, claimedAt: now.Add(-30 * 24 * time.Hour), exp: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			roaster := RoasterDB{URLChangedAt: tc.changedAt}
+			assert.Equal(t, tc.exp, domainClaimAllowed(roaster, tc.claimedAt))
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Collection visibilities
+const (
+	visibilityPrivate = "private"
+	visibilityPublic  = "public"
+)
+
+// defaultCollections every user has
+var defaultCollections = []string{"wishlist", "tried", "favorites"}
+
+// errCollectionNotFound is returned when a user has no collection with a slug
+var errCollectionNotFound = notFoundError("collection not found")
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Collection is a named list of beans saved by a user
+type Collection struct {
+	Owner      string    `firestore:"owner" json:"-"`
+	Username   string    `firestore:"username" json:"username"`
+	Name       string    `firestore:"name" json:"name"`
+	Slug       string    `firestore:"slug" json:"slug"`
+	Visibility string    `firestore:"visibility" json:"visibility"`
+	Beans      []string  `firestore:"beans" json:"beans"`
+	CreatedAt  time.Time `firestore:"created_at" json:"created_at"`
+	UpdatedAt  time.Time `firestore:"updated_at" json:"updated_at"`
+}
+
+// CollectionResp is the response for a single collection
+type CollectionResp struct {
+	Collection Collection `json:"collection"`
+}
+
+// CollectionsResp is the response for a list of collections
+type CollectionsResp struct {
+	Collections []Collection `json:"collections"`
+}
+
+func docToCollection(doc *firestore.DocumentSnapshot) Collection {
+	var c Collection
+	doc.DataTo(&c)
+	if c.Beans == nil {
+		c.Beans = []string{}
+	}
+	return c
+}
+
+// slugify turns a collection name into a URL friendly slug
+func slugify(name string) string {
+	return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+func isDefaultCollection(slug string) bool {
+	for _, c := range defaultCollections {
+		if c == slug {
+			return true
+		}
+	}
+	return false
+}
+
+func validVisibility(v string) bool {
+	return v == visibilityPrivate || v == visibilityPublic
+}
+
+// collectionRef is the document of a user's collection
+func (h *Handler) collectionRef(userEmail, slug string) *firestore.DocumentRef {
+	return h.database.Collection("collections").Doc(fmt.Sprintf("%s_%s", userEmail, slug))
+}
+
+// getCollection fetches a user's collection. Default collections always exist,
+// they are created on first use.
+func (h *Handler) getCollection(ctx context.Context, u UserDB, slug string) (Collection, error) {
+	doc, err := h.collectionRef(u.Email, slug).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		if !isDefaultCollection(slug) {
+			return Collection{}, errCollectionNotFound
+		}
+		return newCollection(u, slug, slug, visibilityPrivate), nil
+	}
+	if err != nil {
+		return Collection{}, err
+	}
+	return docToCollection(doc), nil
+}
+
+// getCollections fetches all of a user's collections, including empty default ones
+func (h *Handler) getCollections(ctx context.Context, u UserDB) ([]Collection, error) {
+	var collections []Collection
+
+	docs, err := h.database.Collection("collections").
+		Where("owner", "==", u.Email).
+		OrderBy("created_at", firestore.Asc).
+		Documents(ctx).
+		GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool)
+	for _, doc := range docs {
+		c := docToCollection(doc)
+		found[c.Slug] = true
+		collections = append(collections, c)
+	}
+
+	var defaults []Collection
+	for _, slug := range defaultCollections {
+		if !found[slug] {
+			defaults = append(defaults, newCollection(u, slug, slug, visibilityPrivate))
+		}
+	}
+
+	return append(defaults, collections...), nil
+}
+
+// saveCollection creates or updates a collection. The beans are left alone,
+// they only change through updateCollectionBeans.
+func (h *Handler) saveCollection(ctx context.Context, c *Collection) error {
+	c.UpdatedAt = time.Now()
+	_, err := h.collectionRef(c.Owner, c.Slug).Set(ctx, c, firestore.Merge(
+		[]string{"owner"},
+		[]string{"username"},
+		[]string{"name"},
+		[]string{"slug"},
+		[]string{"visibility"},
+		[]string{"created_at"},
+		[]string{"updated_at"},
+	))
+	return err
+}
+
+// updateCollectionBeans applies an array transform to the beans of a
+// collection, so concurrent adds and removes don't overwrite each other. c is
+// the collection after the change, it is created if it doesn't exist yet.
+func (h *Handler) updateCollectionBeans(ctx context.Context, c Collection, transform interface{}) error {
+	ref := h.collectionRef(c.Owner, c.Slug)
+	updates := []firestore.Update{
+		{Path: "beans", Value: transform},
+		{Path: "updated_at", Value: time.Now()},
+	}
+
+	_, err := ref.Update(ctx, updates)
+	if status.Code(err) != codes.NotFound {
+		return err
+	}
+
+	// Default collections are created on first use
+	c.UpdatedAt = time.Now()
+	_, err = ref.Create(ctx, c)
+	if status.Code(err) == codes.AlreadyExists {
+		_, err = ref.Update(ctx, updates)
+	}
+	return err
+}
+
+// withBean adds a bean to a list of saved beans, reporting whether it was added
+func withBean(beans []string, bean string) ([]string, bool) {
+	for _, b := range beans {
+		if b == bean {
+			return beans, false
+		}
+	}
+	return append(beans, bean), true
+}
+
+// withoutBean removes a bean from a list of saved beans
+func withoutBean(beans []string, bean string) []string {
+	out := []string{}
+	for _, b := range beans {
+		if b != bean {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// beanSavedCount counts the users that saved a bean to any collection
+func (h *Handler) beanSavedCount(ctx context.Context, slug string) (int, error) {
+	docs, err := h.database.Collection("collections").
+		Where("beans", "array-contains", slug).
+		Select("owner").
+		Documents(ctx).
+		GetAll()
+	if err != nil {
+		return 0, err
+	}
+
+	owners := make(map[string]bool)
+	for _, doc := range docs {
+		owner, err := doc.DataAt("owner")
+		if err != nil {
+			continue
+		}
+		if o, ok := owner.(string); ok {
+			owners[o] = true
+		}
+	}
+
+	return len(owners), nil
+}
+
+func newCollection(u UserDB, name, slug, visibility string) Collection {
+	now := time.Now()
+	return Collection{
+		Owner:      u.Email,
+		Username:   u.Username,
+		Name:       name,
+		Slug:       slug,
+		Visibility: visibility,
+		Beans:      []string{},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
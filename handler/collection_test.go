@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_withBean(t *testing.T) {
+	type test struct {
+		name  string
+		beans []string
+		bean  string
+		exp   []string
+		added bool
+	}
+
+	tests := []test{
+		{name: "empty collection", beans: []string{}, bean: "jumpstart", exp: []string{"jumpstart"}, added: true},
+		{name: "new bean is appended", beans: []string{"jumpstart"}, bean: "hair-bender", exp: []string{"jumpstart", "hair-bender"}, added: true},
+		{name: "saved bean is a no-op", beans: []string{"jumpstart", "hair-bender"}, bean: "jumpstart", exp: []string{"jumpstart", "hair-bender"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			beans, added := withBean(tc.beans, tc.bean)
+			assert.Equal(t, tc.exp, beans)
+			assert.Equal(t, tc.added, added)
+		})
+	}
+}
+
+func Test_withoutBean(t *testing.T) {
+	type test struct {
+		name  string
+		beans []string
+		bean  string
+		exp   []string
+	}
+
+	tests := []test{
+		{name: "removes the bean", beans: []string{"jumpstart", "hair-bender"}, bean: "jumpstart", exp: []string{"hair-bender"}},
+		{name: "missing bean", beans: []string{"jumpstart"}, bean: "hair-bender", exp: []string{"jumpstart"}},
+		{name: "empty collection", beans: nil, bean: "jumpstart", exp: []string{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.exp, withoutBean(tc.beans, tc.bean))
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// routeTimeout is the deadline of a request to path, from the longest prefix
+// in timeouts or the default. path is the route template when there is one,
+// e.g. /roasters/{slug}/imports.
+func routeTimeout(path string, timeouts map[string]time.Duration, fallback time.Duration) time.Duration {
+	var (
+		timeout = fallback
+		longest = -1
+	)
+	for prefix, t := range timeouts {
+		if strings.HasPrefix(path, prefix) && len(prefix) > longest {
+			timeout, longest = t, len(prefix)
+		}
+	}
+	return timeout
+}
+
+// timeoutMiddleware cancels the storage calls of a request when it runs past
+// its deadline or the client goes away
+func (h *Handler) timeoutMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		if route := mux.CurrentRoute(r); route != nil {
+			if tpl, err := route.GetPathTemplate(); err == nil {
+				path = tpl
+			}
+		}
+		timeout := routeTimeout(path, h.cfg.RouteTimeouts, h.cfg.RequestTimeout)
+		if timeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// sideEffects tracks the writes that finish after the response, so a client
+// going away doesn't cancel them and shutdown can wait for them. slots are
+// the side effects running, queue the ones running or waiting.
+type sideEffects struct {
+	slots chan struct{}
+	queue chan struct{}
+	wg    sync.WaitGroup
+}
+
+func newSideEffects(max, queued int) *sideEffects {
+	if max < 1 {
+		max = 1
+	}
+	if queued < 0 {
+		queued = 0
+	}
+	return &sideEffects{
+		slots: make(chan struct{}, max),
+		queue: make(chan struct{}, max+queued),
+	}
+}
+
+// detach runs fn in the background once one of the side effect slots is
+// free, with its own deadline so it outlives the request but not forever.
+// The deadline includes the wait for a slot, and side effects are dropped
+// when too many are already waiting, so a slow dependency can't pile up
+// goroutines.
+func (h *Handler) detach(name string, fn func(ctx context.Context)) {
+	s := h.sideEffects
+	select {
+	case s.queue <- struct{}{}:
+	default:
+		metrics.Add(metricDroppedSideEffects, 1)
+		h.logger.Warnw("Side effect queue is full, dropping it", "name", name)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.cfg.SideEffectTimeout)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() { <-s.queue }()
+		defer cancel()
+
+		select {
+		case s.slots <- struct{}{}:
+		case <-ctx.Done():
+			metrics.Add(metricDroppedSideEffects, 1)
+			h.logger.Warnw("Side effect timed out waiting for a slot", "name", name)
+			return
+		}
+		defer func() { <-s.slots }()
+
+		defer func() {
+			if p := recover(); p != nil {
+				metrics.Add(metricPanics, 1)
+				h.logger.Errorw("Recovered from panic in side effect", "name", name, "panic", p)
+			}
+		}()
+		fn(ctx)
+	}()
+}
+
+// Drain waits for the running side effects to finish, or for ctx to be done
+func (h *Handler) Drain(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		h.sideEffects.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
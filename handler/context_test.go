@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mager/cafebean-api/config"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func Test_routeTimeout(t *testing.T) {
+	timeouts := map[string]time.Duration{
+		"/import":                  5 * time.Minute,
+		"/analytics":               time.Minute,
+		"/analytics/entities":      2 * time.Minute,
+		"/roasters/{slug}/imports": 5 * time.Minute,
+	}
+
+	assert.Equal(t, 15*time.Second, routeTimeout("/beans", timeouts, 15*time.Second))
+	assert.Equal(t, 5*time.Minute, routeTimeout("/import", timeouts, 15*time.Second))
+	assert.Equal(t, time.Minute, routeTimeout("/analytics/activity", timeouts, 15*time.Second))
+	assert.Equal(t, 2*time.Minute, routeTimeout("/analytics/entities/jumpstart", timeouts, 15*time.Second))
+	assert.Equal(t, 5*time.Minute, routeTimeout("/roasters/{slug}/imports/{id}/confirm", timeouts, 15*time.Second))
+	assert.Equal(t, 15*time.Second, routeTimeout("/roasters/{slug}", timeouts, 15*time.Second))
+}
+
+func Test_detach(t *testing.T) {
+	h := &Handler{
+		cfg:         config.Config{SideEffectTimeout: time.Second},
+		logger:      zap.NewNop().Sugar(),
+		sideEffects: newSideEffects(2, 8),
+	}
+
+	var done int32
+	for i := 0; i < 5; i++ {
+		h.detach("test", func(ctx context.Context) {
+			_, ok := ctx.Deadline()
+			assert.True(t, ok)
+			atomic.AddInt32(&done, 1)
+		})
+	}
+	h.detach("test", func(ctx context.Context) {
+		panic("boom")
+	})
+
+	assert.NoError(t, h.Drain(context.Background()))
+	assert.Equal(t, int32(5), atomic.LoadInt32(&done))
+}
+
+func Test_detach_bounded(t *testing.T) {
+	h := &Handler{
+		cfg:         config.Config{SideEffectTimeout: 50 * time.Millisecond},
+		logger:      zap.NewNop().Sugar(),
+		sideEffects: newSideEffects(1, 1),
+	}
+
+	var done int32
+	started, release := make(chan struct{}), make(chan struct{})
+	h.detach("running", func(ctx context.Context) {
+		close(started)
+		<-release
+		atomic.AddInt32(&done, 1)
+	})
+	<-started
+	h.detach("waiting", func(ctx context.Context) {
+		atomic.AddInt32(&done, 1)
+	})
+	h.detach("dropped", func(ctx context.Context) {
+		atomic.AddInt32(&done, 1)
+	})
+
+	// the waiting side effect times out before the slot frees up
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+
+	assert.NoError(t, h.Drain(context.Background()))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&done))
+}
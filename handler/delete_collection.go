@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"cloud.google.com/go/firestore"
+	"github.com/gorilla/mux"
+)
+
+// deleteProfileCollection deletes a custom collection
+func (h *Handler) deleteProfileCollection(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		vars      = mux.Vars(r)
+		slug      = vars["collection"]
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	if isDefaultCollection(slug) {
+		h.writeError(w, r, validationError("default collections can't be deleted"))
+		return
+	}
+
+	u, err := h.getUserByEmail(ctx, userEmail)
+	if err != nil {
+		h.writeError(w, r, validationError("invalid user"))
+		return
+	}
+
+	if _, err = h.getCollection(ctx, u, slug); err == errCollectionNotFound {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if _, err = h.collectionRef(u.Email, slug).Delete(ctx); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	h.logger.Infow(
+		"Collection deleted",
+		"slug", slug,
+		"updated_by", userEmail,
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteCollectionBean removes a bean from one of the user's collections
+func (h *Handler) deleteCollectionBean(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		vars      = mux.Vars(r)
+		slug      = vars["collection"]
+		bean      = vars["slug"]
+		resp      = &CollectionResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	u, err := h.getUserByEmail(ctx, userEmail)
+	if err != nil {
+		h.writeError(w, r, validationError("invalid user"))
+		return
+	}
+
+	c, err := h.getCollection(ctx, u, slug)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	c.Beans = withoutBean(c.Beans, bean)
+	if err = h.updateCollectionBeans(ctx, c, firestore.ArrayRemove(bean)); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	h.logger.Infow(
+		"Bean removed from collection",
+		"collection", slug,
+		"bean", bean,
+		"updated_by", userEmail,
+	)
+
+	resp.Collection = c
+
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// unfollowUser stops following another user
+func (h *Handler) unfollowUser(w http.ResponseWriter, r *http.Request) {
+	h.deleteFollow(w, r, followUser, mux.Vars(r)["username"])
+}
+
+// unfollowRoaster stops following a roaster
+func (h *Handler) unfollowRoaster(w http.ResponseWriter, r *http.Request) {
+	h.deleteFollow(w, r, followRoaster, mux.Vars(r)["slug"])
+}
+
+func (h *Handler) deleteFollow(w http.ResponseWriter, r *http.Request, followType string, target string) {
+	var (
+		ctx       = r.Context()
+		resp      = &FollowResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	u, err := h.getUserByEmail(ctx, userEmail)
+	if err != nil {
+		h.writeError(w, r, validationError("invalid user"))
+		return
+	}
+
+	_, err = h.database.Collection("follows").Doc(followID(userEmail, followType, target)).Delete(ctx)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	h.logger.Infow(
+		"Unfollowed",
+		"follower", u.Username,
+		"type", followType,
+		"target", target,
+	)
+
+	json.NewEncoder(w).Encode(resp)
+}
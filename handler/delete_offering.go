@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// deleteOffering removes an offering from a bean
+func (h *Handler) deleteOffering(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		vars      = mux.Vars(r)
+		slug      = vars["slug"]
+		id        = vars["id"]
+		resp      = &OfferingResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	doc, err := h.getBeanDocBySlug(ctx, slug)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	bean := docToBean(doc)
+
+	allowed, err := h.canEditBean(ctx, bean, userEmail)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if !allowed {
+		h.writeError(w, r, forbiddenError("roaster is verified, suggest an edit instead"))
+		return
+	}
+
+	i := findOffering(bean.Offerings, id)
+	if i < 0 {
+		h.writeError(w, r, errOfferingNotFound)
+		return
+	}
+
+	// New contributors go through the moderation queue
+	if h.requiresModeration(ctx, userEmail) {
+		resp.SubmissionID, err = h.submitOffering(ctx, slug, offeringDelete, bean.Offerings[i], userEmail)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	err = h.updateOffering(ctx, slug, offeringDelete, bean.Offerings[i], userEmail)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
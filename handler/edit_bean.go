@@ -1,24 +1,21 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 
-	"cloud.google.com/go/firestore"
 	"github.com/gorilla/mux"
-	"google.golang.org/api/iterator"
 )
 
 // EditBeanResp is the response from the POST /beans/{slug} endpoint
 type EditBeanResp struct {
 	Bean
+	SubmissionID string `json:"submission_id,omitempty"`
 }
 
 func (h *Handler) editBean(w http.ResponseWriter, r *http.Request) {
 	var (
-		ctx       = context.TODO()
-		docID     string
+		ctx       = r.Context()
 		vars      = mux.Vars(r)
 		slug      = vars["slug"]
 		err       error
@@ -29,60 +26,66 @@ func (h *Handler) editBean(w http.ResponseWriter, r *http.Request) {
 
 	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.writeError(w, r, bodyError(err))
 		return
 	}
 
 	// Fetch the bean
-	q := h.database.Collection("beans").Where("slug", "==", slug)
-	iter := q.Documents(ctx)
-	defer iter.Stop()
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			// TODO: Handle error.
-		}
-		docID = doc.Ref.ID
+	docsnap, err := h.getBeanDocBySlug(ctx, slug)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	bean, stored := docsnap.Ref, docToBean(docsnap)
+
+	if err = prepareBeanUpdate(&req.Bean, stored); err != nil {
+		h.writeError(w, r, validationError(err.Error()))
+		return
 	}
 
-	bean := h.database.Collection("beans").Doc(docID)
-	docsnap, err := bean.Get(ctx)
+	// Only the owner of a verified roaster can edit its beans or move beans
+	// to it
+	allowed, err := h.canEditBean(ctx, stored, userEmail)
+	if err == nil && allowed && req.Roaster.Slug != stored.Roaster.Slug {
+		allowed, err = h.canEditBean(ctx, req.Bean, userEmail)
+	}
 	if err != nil {
-		h.logger.Error(err)
-		http.Error(w, "invalid bean slug", http.StatusBadRequest)
+		h.writeError(w, r, err)
+		return
+	}
+	if !allowed {
+		h.writeError(w, r, forbiddenError("roaster is verified, suggest an edit instead"))
 		return
 	}
 
-	// Update the bean
-	result, _ := bean.Update(
-		ctx,
-		[]firestore.Update{
-			{Path: "countries", Value: req.Countries},
-			{Path: "flavors", Value: req.Flavors},
-			{Path: "description", Value: req.Description},
-			{Path: "name", Value: req.Name},
-			{Path: "photo", Value: req.Photo},
-			{Path: "roaster.name", Value: req.Roaster.Name},
-			{Path: "roaster.slug", Value: req.Roaster.Slug},
-			{Path: "slug", Value: req.Slug},
-			{Path: "url", Value: req.URL},
-		},
-	)
-	h.logger.Infow(
-		"Bean updated",
-		"id", docsnap.Ref.ID,
-		"updated_at", result.UpdateTime,
-		"updated_by", userEmail,
-	)
+	// New contributors go through the moderation queue
+	if h.requiresModeration(ctx, userEmail) {
+		proposed := req.Bean
+		resp.SubmissionID, err = h.submit(ctx, Submission{
+			Kind:        "bean",
+			Action:      "edit",
+			Slug:        slug,
+			Bean:        &proposed,
+			SubmittedBy: userEmail,
+		})
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
 
-	// Publish an entry in BigQuery
-	h.recordBeanChange(ctx, req, userEmail)
+		w.WriteHeader(http.StatusAccepted)
 
-	// Send a webhook event to Discord
-	h.postBeanToDiscord(req, userEmail, "edit")
+		resp.Bean = docToBean(docsnap)
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	// Update the bean
+	err = h.updateBean(ctx, bean, req, userEmail)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
 
 	// Send updated bean response
 	w.WriteHeader(http.StatusAccepted)
@@ -91,7 +94,7 @@ func (h *Handler) editBean(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		h.logger.Errorw(
 			"Error fetching bean after updating it",
-			"id", updated.Ref.ID,
+			"id", bean.ID,
 		)
 	}
 	resp.Bean = docToBean(updated)
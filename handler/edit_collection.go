@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// editProfileCollection renames a custom collection or changes its visibility
+func (h *Handler) editProfileCollection(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		vars      = mux.Vars(r)
+		slug      = vars["collection"]
+		err       error
+		req       AddCollectionReq
+		resp      = &CollectionResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.writeError(w, r, bodyError(err))
+		return
+	}
+
+	u, err := h.getUserByEmail(ctx, userEmail)
+	if err != nil {
+		h.writeError(w, r, validationError("invalid user"))
+		return
+	}
+
+	c, err := h.getCollection(ctx, u, slug)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if req.Visibility != "" {
+		if !validVisibility(req.Visibility) {
+			h.writeError(w, r, validationError("invalid visibility"))
+			return
+		}
+		c.Visibility = req.Visibility
+	}
+
+	// The slug is the document key, so renaming only changes the display name
+	if name := strings.TrimSpace(req.Name); name != "" && name != c.Name {
+		if isDefaultCollection(c.Slug) {
+			h.writeError(w, r, validationError("default collections can't be renamed"))
+			return
+		}
+		c.Name = name
+	}
+
+	if err = h.saveCollection(ctx, &c); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	h.logger.Infow(
+		"Collection updated",
+		"slug", slug,
+		"visibility", c.Visibility,
+		"updated_by", userEmail,
+	)
+
+	resp.Collection = c
+
+	json.NewEncoder(w).Encode(resp)
+}
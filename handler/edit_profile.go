@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 
@@ -11,7 +10,7 @@ import (
 // TODO: Add better security
 func (h *Handler) editProfile(w http.ResponseWriter, r *http.Request) {
 	var (
-		ctx       = context.TODO()
+		ctx       = r.Context()
 		docID     string
 		err       error
 		req       ProfilePayload
@@ -21,7 +20,7 @@ func (h *Handler) editProfile(w http.ResponseWriter, r *http.Request) {
 
 	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.writeError(w, r, bodyError(err))
 		return
 	}
 	username := req.User.Username
@@ -33,12 +32,12 @@ func (h *Handler) editProfile(w http.ResponseWriter, r *http.Request) {
 		doc, err := iter.Next()
 
 		if doc == nil {
-			http.Error(w, "invalid user", http.StatusBadRequest)
+			h.writeError(w, r, validationError("invalid user"))
 			return
 		}
 
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.writeError(w, r, err)
 			return
 		}
 
@@ -46,7 +45,7 @@ func (h *Handler) editProfile(w http.ResponseWriter, r *http.Request) {
 		var u UserDB
 		doc.DataTo(&u)
 		if u.Email != userEmail {
-			http.Error(w, "only the user can update their profile", http.StatusBadRequest)
+			h.writeError(w, r, forbiddenError("only the user can update their profile"))
 			return
 		}
 
@@ -60,7 +59,7 @@ func (h *Handler) editProfile(w http.ResponseWriter, r *http.Request) {
 	docsnap, err := user.Get(ctx)
 	if err != nil {
 		h.logger.Error(err)
-		http.Error(w, "invalid user", http.StatusBadRequest)
+		h.writeError(w, r, validationError("invalid user"))
 		return
 	}
 
@@ -1,24 +1,22 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
+	"time"
 
-	"cloud.google.com/go/firestore"
 	"github.com/gorilla/mux"
-	"google.golang.org/api/iterator"
 )
 
 // EditRoasterResp is the response from the POST /roasters/{slug} endpoint
 type EditRoasterResp struct {
 	Roaster
+	SubmissionID string `json:"submission_id,omitempty"`
 }
 
 func (h *Handler) editRoaster(w http.ResponseWriter, r *http.Request) {
 	var (
-		ctx       = context.TODO()
-		docID     string
+		ctx       = r.Context()
 		vars      = mux.Vars(r)
 		slug      = vars["slug"]
 		err       error
@@ -29,58 +27,72 @@ func (h *Handler) editRoaster(w http.ResponseWriter, r *http.Request) {
 
 	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.writeError(w, r, bodyError(err))
+		return
+	}
+
+	if err = prepareRoaster(&req.Roaster, time.Now()); err != nil {
+		h.writeError(w, r, validationError(err.Error()))
 		return
 	}
 
 	// Fetch the roaster
-	q := h.database.Collection("roasters").Where("slug", "==", slug)
-	roasterIter := q.Documents(ctx)
-	defer roasterIter.Stop()
-	for {
-		doc, err := roasterIter.Next()
-		if err == iterator.Done {
-			break
+	docsnap, err := h.getRoasterDocBySlug(ctx, slug)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	roaster := docsnap.Ref
+
+	// Only the owner of a verified roaster can edit it
+	if !h.canEdit(docToRoasterDB(docsnap), userEmail) {
+		h.writeError(w, r, forbiddenError("roaster is verified, suggest an edit instead"))
+		return
+	}
+
+	// Keep the stored location unless the roaster moved, then fill in the
+	// location or city, and keep what's stored when the location can't be
+	// geocoded
+	stored := docToRoaster(docsnap)
+	keepStoredLocation(&req.Roaster, stored)
+	h.geocodeRoaster(&req.Roaster)
+	h.geocodeCafes(&req.Roaster)
+	if req.Location == nil {
+		req.Location = stored.Location
+		if req.Region == "" && req.Country == "" {
+			req.Region = stored.Region
+			req.Country = stored.Country
 		}
+	}
+
+	// New contributors go through the moderation queue
+	if h.requiresModeration(ctx, userEmail) {
+		proposed := req.Roaster
+		resp.SubmissionID, err = h.submit(ctx, Submission{
+			Kind:        "roaster",
+			Action:      "edit",
+			Slug:        slug,
+			Roaster:     &proposed,
+			SubmittedBy: userEmail,
+		})
 		if err != nil {
-			// TODO: Handle error.
+			h.writeError(w, r, err)
+			return
 		}
-		docID = doc.Ref.ID
-	}
 
-	roaster := h.database.Collection("roasters").Doc(docID)
-	docsnap, err := roaster.Get(ctx)
-	if err != nil {
-		http.Error(w, "invalid roaster slug", http.StatusBadRequest)
+		w.WriteHeader(http.StatusAccepted)
+
+		resp.Roaster = docToRoaster(docsnap)
+		json.NewEncoder(w).Encode(resp)
 		return
 	}
 
 	// Update the roaster
-	result, err := roaster.Update(
-		ctx,
-		[]firestore.Update{
-			{Path: "city", Value: req.City},
-			{Path: "instagram", Value: req.Instagram},
-			{Path: "location", Value: req.Location},
-			{Path: "logo", Value: req.Logo},
-			{Path: "name", Value: req.Name},
-			{Path: "slug", Value: req.Slug},
-			{Path: "twitter", Value: req.Twitter},
-			{Path: "url", Value: req.URL},
-		},
-	)
-	h.logger.Infow(
-		"Roaster updated",
-		"id", docsnap.Ref.ID,
-		"updated_at", result.UpdateTime,
-		"updated_by", userEmail,
-	)
-
-	// Publish an entry in BigQuery
-	h.recordRoasterChange(ctx, req, userEmail)
-
-	// Send a webhook event to Discord
-	h.postRoasterToDiscord(req, userEmail, "edit")
+	err = h.updateRoaster(ctx, roaster, req, userEmail)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
 
 	// Send updated roaster response
 	w.WriteHeader(http.StatusAccepted)
@@ -89,7 +101,7 @@ func (h *Handler) editRoaster(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		h.logger.Errorw(
 			"Error fetching roaster after updating it",
-			"id", updated.Ref.ID,
+			"id", roaster.ID,
 		)
 	}
 	h.logger.Debug(updated)
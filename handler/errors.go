@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// Error codes sent in the error envelope
+const (
+	codeValidation = "validation_failed"
+	codeNotFound   = "not_found"
+	codeConflict   = "conflict"
+	codeForbidden  = "forbidden"
+	codeUpstream   = "upstream_failed"
+	codeTimeout    = "timeout"
+	codeCanceled   = "canceled"
+	codeInternal   = "internal"
+)
+
+// statusClientClosedRequest is sent when the client went away before the
+// response, nobody reads it but it shows up in the logs
+const statusClientClosedRequest = 499
+
+// requestIDHeader is set on every request by the router
+const requestIDHeader = "X-Request-ID"
+
+// domainError is an error a handler can explain to the client. Any other
+// error is reported as an internal error without its message.
+type domainError struct {
+	code    string
+	status  int
+	message string
+	details interface{}
+}
+
+func (e *domainError) Error() string {
+	return e.message
+}
+
+// validationError is returned when a request is malformed or breaks a rule
+func validationError(message string) error {
+	return &domainError{code: codeValidation, status: http.StatusBadRequest, message: message}
+}
+
+// bodyError is returned when the request body can't be decoded, naming the
+// field with the wrong type when there is one
+func bodyError(err error) error {
+	e := &domainError{code: codeValidation, status: http.StatusBadRequest, message: err.Error()}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		e.message = fmt.Sprintf("invalid value for %s", typeErr.Field)
+		e.details = map[string]string{"field": typeErr.Field, "expected": typeErr.Type.String()}
+	}
+	return e
+}
+
+// notFoundError is returned when a requested record doesn't exist
+func notFoundError(message string) error {
+	return &domainError{code: codeNotFound, status: http.StatusNotFound, message: message}
+}
+
+// conflictError is returned when a write clashes with the current state
+func conflictError(message string) error {
+	return &domainError{code: codeConflict, status: http.StatusConflict, message: message}
+}
+
+// forbiddenError is returned when the user isn't allowed to do something
+func forbiddenError(message string) error {
+	return &domainError{code: codeForbidden, status: http.StatusForbidden, message: message}
+}
+
+// upstreamError is returned when a third party the request depends on fails
+func upstreamError(err error) error {
+	return &domainError{code: codeUpstream, status: http.StatusBadGateway, message: err.Error()}
+}
+
+// writeError sends the error envelope with the status of the error. Errors
+// that aren't domain errors are logged and hidden from the client.
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var (
+		requestID = r.Header.Get(requestIDHeader)
+		status    = http.StatusInternalServerError
+		msg       = ErrorMessage{
+			Code:      codeInternal,
+			Message:   http.StatusText(http.StatusInternalServerError),
+			RequestID: requestID,
+		}
+		de *domainError
+	)
+
+	switch {
+	case errors.As(err, &de):
+		status = de.status
+		msg.Code = de.code
+		msg.Message = de.message
+		msg.Details = de.details
+	case errors.Is(err, context.DeadlineExceeded) || grpcstatus.Code(err) == codes.DeadlineExceeded:
+		status = http.StatusGatewayTimeout
+		msg.Code = codeTimeout
+		msg.Message = "the request took too long"
+		h.logger.Warnw(
+			"Request timed out",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"request_id", requestID,
+		)
+	case errors.Is(err, context.Canceled) || grpcstatus.Code(err) == codes.Canceled:
+		status = statusClientClosedRequest
+		msg.Code = codeCanceled
+		msg.Message = "the request was canceled"
+	default:
+		h.logger.Errorw(
+			"Error handling request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"request_id", requestID,
+			"error", err,
+		)
+	}
+
+	countError(status)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResp{Error: msg})
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mager/cafebean-api/config"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func Test_writeError(t *testing.T) {
+	h := &Handler{logger: zap.NewNop().Sugar()}
+	send := func(err error) (int, ErrorResp) {
+		r := httptest.NewRequest("GET", "/beans/unknown", nil)
+		r.Header.Set(requestIDHeader, "abc123")
+		w := httptest.NewRecorder()
+		h.writeError(w, r, err)
+
+		var resp ErrorResp
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		return w.Code, resp
+	}
+
+	status, resp := send(errBeanNotFound)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, ErrorMessage{Code: codeNotFound, Message: "bean not found", RequestID: "abc123"}, resp.Error)
+
+	status, resp = send(errors.New("rpc error: connection reset"))
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, codeInternal, resp.Error.Code)
+	assert.NotContains(t, resp.Error.Message, "rpc")
+
+	var bean Bean
+	status, resp = send(bodyError(json.NewDecoder(strings.NewReader(`{"name": 1}`)).Decode(&bean)))
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, codeValidation, resp.Error.Code)
+	assert.Equal(t, map[string]interface{}{"field": "name", "expected": "string"}, resp.Error.Details)
+}
+
+func Test_recoverMiddleware(t *testing.T) {
+	h := &Handler{logger: zap.NewNop().Sugar()}
+	panics := func() int64 {
+		if v, ok := metrics.Get(metricPanics).(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+	before := panics()
+
+	handler := h.recoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m map[string]int
+		m["boom"]++
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/beans", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), codeInternal)
+	assert.Equal(t, before+1, panics())
+}
+
+func Test_getMetrics(t *testing.T) {
+	h := &Handler{
+		cfg:    config.Config{Moderators: []string{"mod@cafebean.org"}},
+		logger: zap.NewNop().Sugar(),
+	}
+	get := func(user string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/debug/vars", nil)
+		r.Header.Set("X-User-Email", user)
+		w := httptest.NewRecorder()
+		h.getMetrics(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusForbidden, get("").Code)
+	assert.Equal(t, http.StatusForbidden, get("ana@cafebean.org").Code)
+
+	w := get("mod@cafebean.org")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"cafebean"`)
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// Domain event types
+const (
+	eventBeanAdded      = "bean.added"
+	eventBeanUpdated    = "bean.updated"
+	eventRoasterAdded   = "roaster.added"
+	eventRoasterUpdated = "roaster.updated"
+	eventReviewAdded    = "review.added"
+)
+
+// Event is a domain event produced by a bean, roaster or review write
+type Event struct {
+	Type        string    `firestore:"type" json:"type"`
+	Actor       string    `firestore:"actor" json:"-"`
+	Username    string    `firestore:"username" json:"username,omitempty"`
+	BeanName    string    `firestore:"bean_name" json:"bean_name,omitempty"`
+	BeanSlug    string    `firestore:"bean_slug" json:"bean_slug,omitempty"`
+	RoasterName string    `firestore:"roaster_name" json:"roaster_name,omitempty"`
+	RoasterSlug string    `firestore:"roaster_slug" json:"roaster_slug,omitempty"`
+	ReviewID    int       `firestore:"review_id" json:"review_id,omitempty"`
+	Rating      float64   `firestore:"rating" json:"rating,omitempty"`
+	Review      string    `firestore:"review" json:"review,omitempty"`
+	CreatedAt   time.Time `firestore:"created_at" json:"created_at"`
+}
+
+func beanEvent(eventType string, b Bean) Event {
+	return Event{
+		Type:        eventType,
+		BeanName:    b.Name,
+		BeanSlug:    b.Slug,
+		RoasterName: b.Roaster.Name,
+		RoasterSlug: b.Roaster.Slug,
+	}
+}
+
+func roasterEvent(eventType string, r Roaster) Event {
+	return Event{
+		Type:        eventType,
+		RoasterName: r.Name,
+		RoasterSlug: r.Slug,
+	}
+}
+
+// publishEvent stores a domain event in the activity log and publishes it
+// to Pub/Sub for other consumers in the background. The activity is written
+// before returning so the feed doesn't lose it when side effects are dropped.
+func (h *Handler) publishEvent(ctx context.Context, e Event, userEmail string) {
+	e.Actor = userEmail
+	e.CreatedAt = time.Now()
+	if e.Username == "" && userEmail != "" {
+		if u, err := h.getUserByEmail(ctx, userEmail); err == nil {
+			e.Username = u.Username
+		}
+	}
+
+	if _, _, err := h.database.Collection("activity").Add(ctx, e); err != nil {
+		h.logger.Errorw(
+			"Failed to store activity",
+			"type", e.Type,
+			"error", err,
+		)
+	}
+
+	if h.topic == nil {
+		return
+	}
+	h.detach("event", func(ctx context.Context) {
+		data, err := json.Marshal(e)
+		if err != nil {
+			h.logger.Error(err)
+			return
+		}
+		result := h.topic.Publish(ctx, &pubsub.Message{
+			Data:       data,
+			Attributes: map[string]string{"type": e.Type},
+		})
+		if _, err := result.Get(ctx); err != nil {
+			h.logger.Errorw(
+				"Failed to publish event",
+				"type", e.Type,
+				"error", err,
+			)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+)
+
+// exportCatalogue streams every bean or roaster as CSV or JSONL, in the
+// format POST /import reads
+func (h *Handler) exportCatalogue(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx    = r.Context()
+		vars   = mux.Vars(r)
+		entity = vars["entity"]
+		format = vars["format"]
+	)
+
+	docs, err := h.database.Collection(entity).Documents(ctx).GetAll()
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	// The router defaults to JSON
+	if format == formatCSV {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", entity, format))
+
+	// Sort by slug so exports diff cleanly
+	slug := func(i int) string {
+		s, _ := docs[i].Data()["slug"].(string)
+		return s
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		return slug(i) < slug(j)
+	})
+
+	if format == formatJSONL {
+		enc := json.NewEncoder(w)
+		for _, doc := range docs {
+			if entity == "roasters" {
+				enc.Encode(docToRoaster(doc))
+			} else {
+				enc.Encode(docToBean(doc))
+			}
+		}
+		return
+	}
+
+	out := csv.NewWriter(w)
+	if entity == "roasters" {
+		out.Write(roasterColumns)
+	} else {
+		out.Write(beanColumns)
+	}
+	for _, doc := range docs {
+		if entity == "roasters" {
+			out.Write(roasterToRecord(docToRoaster(doc)))
+		} else {
+			out.Write(beanToRecord(docToBean(doc)))
+		}
+	}
+	out.Flush()
+}
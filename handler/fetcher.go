@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// maxFetchRedirects is how many redirects an outbound request follows
+const maxFetchRedirects = 5
+
+// errBlockedAddress is returned when an outbound request would connect to a
+// private or local address
+var errBlockedAddress = errors.New("address is not allowed")
+
+// blockedNetworks are the ranges outbound requests can't connect to, so user
+// supplied URLs can't reach internal services or the metadata server
+var blockedNetworks = parseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+// Fetcher makes outbound HTTP requests. *http.Client satisfies it, tests
+// can swap in a stub.
+type Fetcher interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// newFetcher returns the default fetcher used for outbound requests. It only
+// connects to public addresses, including after redirects.
+func newFetcher() Fetcher {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: publicAddressOnly,
+	}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: checkFetchRedirect,
+	}
+}
+
+// publicAddressOnly refuses connections to blocked networks. It runs after
+// DNS resolution, so hostnames pointing to private addresses are caught too.
+func publicAddressOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || isBlockedIP(ip) {
+		return fmt.Errorf("%w: %s", errBlockedAddress, host)
+	}
+	return nil
+}
+
+// checkFetchRedirect limits redirects to a few hops over http(s)
+func checkFetchRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxFetchRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxFetchRedirects)
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+	}
+	return nil
+}
+
+func isBlockedIP(ip net.IP) bool {
+	for _, n := range blockedNetworks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
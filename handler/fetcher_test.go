@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_publicAddressOnly(t *testing.T) {
+	type test struct {
+		address string
+		ok      bool
+	}
+
+	tests := []test{
+		{address: "93.184.216.34:443", ok: true},
+		{address: "[2606:2800:220:1:248:1893:25c8:1946]:443", ok: true},
+		{address: "127.0.0.1:80"},
+		{address: "10.1.2.3:80"},
+		{address: "172.20.0.1:80"},
+		{address: "192.168.1.1:80"},
+		{address: "169.254.169.254:80"},
+		{address: "[::1]:80"},
+		{address: "[fd00::1]:80"},
+		{address: "[::ffff:127.0.0.1]:80"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.address, func(t *testing.T) {
+			err := publicAddressOnly("tcp", tc.address, nil)
+			assert.Equal(t, tc.ok, err == nil, err)
+		})
+	}
+}
+
+func Test_checkFetchRedirect(t *testing.T) {
+	redirect := func(u string) *http.Request {
+		parsed, _ := url.Parse(u)
+		return &http.Request{URL: parsed}
+	}
+
+	assert.NoError(t, checkFetchRedirect(redirect("https://ipsento.com/shop"), nil))
+	assert.Error(t, checkFetchRedirect(redirect("file:///etc/passwd"), nil))
+	assert.Error(t, checkFetchRedirect(redirect("https://ipsento.com/shop"), make([]*http.Request, maxFetchRedirects)))
+}
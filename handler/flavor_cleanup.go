@@ -0,0 +1,320 @@
+package handler
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxBatchSize is the maximum number of writes in a firestore batch
+const maxBatchSize = 500
+
+// FlavorMerge is a proposed rename of a flavor across all beans
+type FlavorMerge struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Count  int    `json:"count"`
+	Reason string `json:"reason"`
+}
+
+// BeanFlavorChange is a bean whose flavors were changed by a merge
+type BeanFlavorChange struct {
+	Slug string   `json:"slug"`
+	From []string `json:"from"`
+	To   []string `json:"to"`
+}
+
+// FlavorMergeResult summarizes a batch merge. Conflicts are the slugs of the
+// beans that kept being edited during the merge and were left unchanged.
+type FlavorMergeResult struct {
+	BeansScanned int                `json:"beans_scanned"`
+	BeansUpdated int                `json:"beans_updated"`
+	Changes      []BeanFlavorChange `json:"changes"`
+	Conflicts    []string           `json:"conflicts"`
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	var (
+		ra   = []rune(a)
+		rb   = []rune(b)
+		prev = make([]int, len(rb)+1)
+		cur  = make([]int, len(rb)+1)
+	)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxTypoDistance is how many edits a flavor of a given length can be away
+// from another one to count as a misspelling. Short words are never fuzzy
+// matched, "pear" and "peach" are different flavors.
+func maxTypoDistance(f string) int {
+	switch n := len([]rune(f)); {
+	case n <= 4:
+		return 0
+	case n <= 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// proposeFlavorMerges clusters near-duplicate flavors. counts maps each raw
+// flavor string to the number of beans using it. Flavors are first normalized
+// with the synonym list, unknown flavors are then matched to the taxonomy by
+// edit distance and finally clustered with each other, merging into the most
+// used spelling.
+func proposeFlavorMerges(counts map[string]int) []FlavorMerge {
+	var (
+		target     = make(map[string]string)
+		reasons    = make(map[string]string)
+		normalized = make(map[string]int)
+	)
+
+	// Synonyms and formatting
+	for raw, c := range counts {
+		n := normalizeFlavor(raw)
+		if n == "" {
+			continue
+		}
+		normalized[n] += c
+		if n != raw {
+			target[raw] = n
+			if n == cleanFlavor(raw) {
+				reasons[raw] = "format"
+			} else {
+				reasons[raw] = "synonym"
+			}
+		}
+	}
+
+	// Misspellings of flavors in the taxonomy
+	var (
+		fixed   = make(map[string]string)
+		unknown []string
+	)
+	for n := range normalized {
+		if lookupFlavor(n) != nil {
+			continue
+		}
+		best, bestDist := "", maxTypoDistance(n)+1
+		for key, node := range flavorIndex {
+			if d := levenshtein(n, key); d < bestDist || (d == bestDist && node.Name < best) {
+				best, bestDist = node.Name, d
+			}
+		}
+		if best != "" && bestDist <= maxTypoDistance(n) {
+			fixed[n] = best
+			continue
+		}
+		unknown = append(unknown, n)
+	}
+	sort.Strings(unknown)
+
+	// Misspellings of each other
+	parent := make(map[string]string)
+	var find func(string) string
+	find = func(f string) string {
+		if p, ok := parent[f]; ok && p != f {
+			parent[f] = find(p)
+			return parent[f]
+		}
+		return f
+	}
+	for i, a := range unknown {
+		for _, b := range unknown[i+1:] {
+			if levenshtein(a, b) <= minInt(maxTypoDistance(a), maxTypoDistance(b)) {
+				parent[find(b)] = find(a)
+			}
+		}
+	}
+	clusters := make(map[string][]string)
+	for _, f := range unknown {
+		root := find(f)
+		clusters[root] = append(clusters[root], f)
+	}
+	for _, members := range clusters {
+		best := members[0]
+		for _, m := range members[1:] {
+			if normalized[m] > normalized[best] {
+				best = m
+			}
+		}
+		for _, m := range members {
+			if m != best {
+				fixed[m] = best
+			}
+		}
+	}
+
+	// Resolve every raw flavor to its final spelling
+	var merges []FlavorMerge
+	for raw, c := range counts {
+		to, reason := raw, ""
+		if t, ok := target[raw]; ok {
+			to, reason = t, reasons[raw]
+		}
+		if t, ok := fixed[to]; ok {
+			to, reason = t, "spelling"
+		}
+		if to == raw || to == "" {
+			continue
+		}
+		merges = append(merges, FlavorMerge{From: raw, To: to, Count: c, Reason: reason})
+	}
+
+	sort.Slice(merges, func(i, j int) bool {
+		if merges[i].To != merges[j].To {
+			return merges[i].To < merges[j].To
+		}
+		return merges[i].From < merges[j].From
+	})
+
+	return merges
+}
+
+// applyFlavorMerges renames the flavors of a bean and drops duplicates
+func applyFlavorMerges(flavors []string, merges map[string]string) ([]string, bool) {
+	var (
+		updated = []string{}
+		seen    = make(map[string]bool)
+		changed bool
+	)
+	for _, f := range flavors {
+		to := f
+		if t, ok := merges[f]; ok {
+			to = strings.TrimSpace(t)
+		}
+		if to != f {
+			changed = true
+		}
+		if to == "" || seen[to] {
+			changed = true
+			continue
+		}
+		seen[to] = true
+		updated = append(updated, to)
+	}
+	return updated, changed
+}
+
+// ProposeFlavorMerges scans all beans and proposes merges of near-duplicate flavors
+func (h *Handler) ProposeFlavorMerges(ctx context.Context) ([]FlavorMerge, error) {
+	beans, err := h.database.Collection("beans").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, doc := range beans {
+		for _, f := range docToBean(doc).Flavors {
+			counts[f]++
+		}
+	}
+
+	return proposeFlavorMerges(counts), nil
+}
+
+// ApplyFlavorMerges renames flavors on every bean according to an approved
+// merge map. Each modified bean is recorded in the changelog. With dryRun
+// nothing is written. Beans edited while the merge runs are merged again,
+// the ones that keep changing are reported as conflicts.
+func (h *Handler) ApplyFlavorMerges(ctx context.Context, merges map[string]string, userEmail string, dryRun bool) (FlavorMergeResult, error) {
+	result := FlavorMergeResult{Changes: []BeanFlavorChange{}, Conflicts: []string{}}
+
+	beans, err := h.database.Collection("beans").Documents(ctx).GetAll()
+	if err != nil {
+		return result, err
+	}
+	result.BeansScanned = len(beans)
+
+	for _, doc := range beans {
+		change, err := h.mergeBeanFlavors(ctx, doc, merges, userEmail, dryRun)
+		if status.Code(err) == codes.FailedPrecondition {
+			result.Conflicts = append(result.Conflicts, docToBean(doc).Slug)
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+		if change == nil {
+			continue
+		}
+
+		result.BeansUpdated++
+		result.Changes = append(result.Changes, *change)
+	}
+
+	h.logger.Infow(
+		"Flavors merged",
+		"merges", len(merges),
+		"beans_updated", result.BeansUpdated,
+		"conflicts", len(result.Conflicts),
+		"dry_run", dryRun,
+		"updated_by", userEmail,
+	)
+
+	return result, nil
+}
+
+// mergeBeanFlavors renames the flavors of a bean if it wasn't edited since it
+// was read, otherwise it reads the bean again and retries. It returns nil when
+// nothing changed.
+func (h *Handler) mergeBeanFlavors(ctx context.Context, doc *firestore.DocumentSnapshot, merges map[string]string, userEmail string, dryRun bool) (*BeanFlavorChange, error) {
+	for attempt := 1; ; attempt++ {
+		bean := docToBean(doc)
+		flavors, changed := applyFlavorMerges(bean.Flavors, merges)
+		if !changed {
+			return nil, nil
+		}
+		change := &BeanFlavorChange{Slug: bean.Slug, From: bean.Flavors, To: flavors}
+		if dryRun {
+			return change, nil
+		}
+
+		_, err := doc.Ref.Update(ctx,
+			[]firestore.Update{{Path: "flavors", Value: flavors}},
+			firestore.LastUpdateTime(doc.UpdateTime),
+		)
+		if err == nil {
+			bean.Flavors = flavors
+			h.recordBeanChange(BeanReq{bean}, userEmail)
+			return change, nil
+		}
+		if status.Code(err) != codes.FailedPrecondition || attempt == retryAttempts {
+			return nil, err
+		}
+
+		doc, err = doc.Ref.Get(ctx)
+		if status.Code(err) == codes.NotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_levenshtein(t *testing.T) {
+	assert.Equal(t, 0, levenshtein("caramel", "caramel"))
+	assert.Equal(t, 1, levenshtein("carmel", "caramel"))
+	assert.Equal(t, 2, levenshtein("raspbery", "rasberry"))
+	assert.Equal(t, 4, levenshtein("", "pear"))
+	assert.Equal(t, 1, levenshtein("rosé", "rose"))
+}
+
+func Test_proposeFlavorMerges(t *testing.T) {
+	counts := map[string]int{
+		"Chocolate":       3,
+		"chocolate":       5,
+		"chocolatey":      1,
+		"rasberry":        1,
+		"pear":            2,
+		"peach":           2,
+		"bergamott":       1,
+		"stroopwafel":     4,
+		"stropwafel":      1,
+		"sweet tarts":     2,
+		"jordan almond":   1,
+		"jordan  almond ": 1,
+	}
+
+	merges := make(map[string]string)
+	for _, m := range proposeFlavorMerges(counts) {
+		merges[m.From] = m.To
+	}
+
+	assert.Equal(t, map[string]string{
+		"Chocolate":       "chocolate",
+		"chocolatey":      "chocolate",
+		"rasberry":        "raspberry",
+		"bergamott":       "bergamot",
+		"stropwafel":      "stroopwafel",
+		"jordan  almond ": "jordan almond",
+	}, merges)
+}
+
+func Test_applyFlavorMerges(t *testing.T) {
+	merges := map[string]string{"Chocolate": "chocolate", "rasberry": "raspberry"}
+
+	flavors, changed := applyFlavorMerges([]string{"Chocolate", "chocolate", "rasberry", "pear"}, merges)
+	assert.True(t, changed)
+	assert.Equal(t, []string{"chocolate", "raspberry", "pear"}, flavors)
+
+	flavors, changed = applyFlavorMerges([]string{"pear"}, merges)
+	assert.False(t, changed)
+	assert.Equal(t, []string{"pear"}, flavors)
+}
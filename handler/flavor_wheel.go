@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// flavorWheelData is the flavor taxonomy, modelled on the SCA coffee taster's
+// flavor wheel: category, subcategory and descriptor, one per line and
+// indented by two spaces per level. Synonyms follow a "|".
+const flavorWheelData = `
+fruity
+  berry | berries
+    blackberry
+    raspberry
+    blueberry
+    strawberry
+  dried fruit
+    raisin | sultana
+    prune
+    fig | dried fig
+    date
+  other fruit | fruit, tropical fruit, stone fruit
+    coconut
+    cherry | black cherry, red cherry, cherries
+    pomegranate
+    pineapple
+    grape | red grape, white grape, concord grape
+    apple | green apple, red apple, baked apple
+    peach | white peach, yellow peach, nectarine
+    pear | asian pear
+    apricot
+    plum
+    mango
+    papaya
+    passion fruit | passionfruit
+    lychee
+  citrus fruit | citrus
+    grapefruit | pink grapefruit
+    orange | blood orange, orange zest, tangerine, mandarin, clementine, orange peel
+    lemon | meyer lemon, lemon zest, lemongrass
+    lime | key lime
+    bergamot
+sour/fermented
+  sour
+    sour aromatics
+    acetic acid
+    butyric acid
+    isovaleric acid
+    citric acid
+    malic acid
+  alcohol/fermented | fermented
+    winey | wine, red wine, white wine
+    whiskey | whisky, bourbon
+    overripe
+    rum | rum raisin
+green/vegetative
+  olive oil
+  raw
+  vegetative | green, grassy
+    under-ripe | underripe
+    peapod
+    fresh
+    dark green
+    hay-like | hay
+    herb-like | herbal, herbs
+  beany
+other
+  papery/musty
+    stale
+    cardboard
+    papery
+    woody | wood, cedar, oak
+    moldy/damp
+    musty/dusty
+    musty/earthy | earthy
+    animalic
+    meaty brothy
+    phenolic
+  chemical
+    bitter
+    salty
+    medicinal
+    petroleum
+    skunky
+    rubber
+roasted
+  pipe tobacco
+  tobacco
+  burnt
+    acrid
+    ashy
+    smoky | smoke
+    brown roast | roasty
+  cereal
+    grain | graham cracker, toast, toasted bread, biscuit
+    malt | malty
+spices | spice, spicy
+  pungent
+  pepper | black pepper, pink peppercorn
+  brown spice | baking spice, baking spices
+    anise | licorice, liquorice
+    nutmeg
+    cinnamon
+    clove
+    cardamom
+nutty/cocoa
+  nutty | nuts, mixed nuts, nut
+    peanuts | peanut, peanut butter
+    hazelnut | hazelnuts
+    almond | almonds, marzipan, toasted almond
+    walnut
+    pecan
+    cashew
+    macadamia
+    pistachio
+  cocoa | cacao, cocoa nib, cocoa nibs, cacao nibs
+    chocolate | chocolatey, milk chocolate, chocolate milk, fudge, brownie
+    dark chocolate | bittersweet chocolate, dark cocoa, baker's chocolate
+    white chocolate
+sweet
+  brown sugar
+    molasses
+    maple syrup | maple
+    caramelized | caramel, caramelised, toffee, butterscotch, dulce de leche, burnt sugar
+    honey | honeycomb, wildflower honey
+  vanilla
+  vanillin
+  overall sweet | sugar, sugary, candy
+  sweet aromatics
+floral
+  black tea | tea, earl grey
+  flowers | flower, florals, flowery
+    chamomile
+    rose | rose hip
+    jasmine
+    lavender
+    hibiscus
+    orange blossom
+`
+
+// FlavorNode is a category, subcategory or descriptor of the flavor wheel
+type FlavorNode struct {
+	Name     string
+	Slug     string
+	Synonyms []string
+	Parent   *FlavorNode
+	Children []*FlavorNode
+}
+
+// Path returns the nodes from the category down to n
+func (n *FlavorNode) Path() []*FlavorNode {
+	var path []*FlavorNode
+	for node := n; node != nil; node = node.Parent {
+		path = append([]*FlavorNode{node}, path...)
+	}
+	return path
+}
+
+// Contains checks if other is n or one of its descendants
+func (n *FlavorNode) Contains(other *FlavorNode) bool {
+	for node := other; node != nil; node = node.Parent {
+		if node == n {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// flavorWheel holds the categories of the taxonomy
+	flavorWheel []*FlavorNode
+
+	// flavorIndex maps names, slugs and synonyms to nodes
+	flavorIndex = make(map[string]*FlavorNode)
+)
+
+func init() {
+	flavorWheel = parseFlavorWheel(flavorWheelData, flavorIndex)
+}
+
+// parseFlavorWheel builds the taxonomy tree and fills the index
+func parseFlavorWheel(data string, index map[string]*FlavorNode) []*FlavorNode {
+	var (
+		roots   []*FlavorNode
+		parents []*FlavorNode
+	)
+
+	for _, line := range strings.Split(data, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		depth := (len(line) - len(strings.TrimLeft(line, " "))) / 2
+
+		var (
+			parts = strings.SplitN(strings.TrimSpace(line), "|", 2)
+			node  = &FlavorNode{Name: strings.TrimSpace(parts[0])}
+		)
+		node.Slug = slugify(node.Name)
+		if len(parts) == 2 {
+			for _, s := range strings.Split(parts[1], ",") {
+				node.Synonyms = append(node.Synonyms, strings.TrimSpace(s))
+			}
+		}
+
+		if depth > len(parents) {
+			panic(fmt.Sprintf("flavor wheel: %q is indented too far", node.Name))
+		}
+		parents = parents[:depth]
+		if depth == 0 {
+			roots = append(roots, node)
+		} else {
+			node.Parent = parents[depth-1]
+			node.Parent.Children = append(node.Parent.Children, node)
+		}
+		parents = append(parents, node)
+
+		for _, key := range append([]string{node.Name, node.Slug}, node.Synonyms...) {
+			if existing, ok := index[key]; ok && existing != node {
+				panic(fmt.Sprintf("flavor wheel: %q is used by %q and %q", key, existing.Name, node.Name))
+			}
+			index[key] = node
+		}
+	}
+
+	return roots
+}
@@ -1,9 +1,9 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
+	"regexp"
 	"strings"
 
 	"cloud.google.com/go/firestore"
@@ -14,12 +14,117 @@ type FlavorsResp struct {
 	Flavors map[string]int `json:"flavors"`
 }
 
+var (
+	flavorSpaces  = regexp.MustCompile(`\s+`)
+	flavorPrefix  = regexp.MustCompile(`^(notes? of|hints? of|tastes? like|flavors? of) `)
+	flavorTrimSet = " .,;:!?\"'()[]"
+)
+
+// cleanFlavor lowercases a flavor and strips whitespace, punctuation and
+// filler like "notes of"
+func cleanFlavor(flavor string) string {
+	f := strings.ToLower(flavor)
+	f = flavorSpaces.ReplaceAllString(f, " ")
+	f = strings.Trim(f, flavorTrimSet)
+	f = flavorPrefix.ReplaceAllString(f, "")
+	return strings.Trim(f, flavorTrimSet)
+}
+
+// lookupFlavor finds the taxonomy node for a cleaned flavor, trying common
+// plural and adjective endings ("cherries", "chocolatey", "nutty")
+func lookupFlavor(f string) *FlavorNode {
+	if node, ok := flavorIndex[f]; ok {
+		return node
+	}
+
+	var variants []string
+	switch {
+	case strings.HasSuffix(f, "ies"):
+		variants = append(variants, strings.TrimSuffix(f, "ies")+"y")
+	case strings.HasSuffix(f, "es"):
+		variants = append(variants, strings.TrimSuffix(f, "es"), strings.TrimSuffix(f, "s"))
+	case strings.HasSuffix(f, "s"):
+		variants = append(variants, strings.TrimSuffix(f, "s"))
+	case strings.HasSuffix(f, "ey"):
+		variants = append(variants, strings.TrimSuffix(f, "ey"), strings.TrimSuffix(f, "y"))
+	case strings.HasSuffix(f, "y"):
+		variants = append(variants, strings.TrimSuffix(f, "y"), strings.TrimSuffix(f, "ty"))
+	case strings.HasSuffix(f, "ish"):
+		variants = append(variants, strings.TrimSuffix(f, "ish"))
+	case strings.HasSuffix(f, "-like"):
+		variants = append(variants, strings.TrimSuffix(f, "-like"))
+	}
+	for _, v := range variants {
+		if node, ok := flavorIndex[v]; ok {
+			return node
+		}
+	}
+
+	return nil
+}
+
+// classifyFlavor places a free-text flavor in the taxonomy. Flavors that
+// aren't in the index are classified by their words, last first, so
+// "ripe strawberry" lands under strawberry. Returns nil if nothing matches.
+func classifyFlavor(flavor string) *FlavorNode {
+	f := cleanFlavor(flavor)
+	if node := lookupFlavor(f); node != nil {
+		return node
+	}
+
+	words := strings.Fields(f)
+	for i := len(words) - 1; i >= 0 && len(words) > 1; i-- {
+		if node := lookupFlavor(words[i]); node != nil {
+			return node
+		}
+	}
+
+	return nil
+}
+
+// normalizeFlavor maps a flavor to its canonical name in the taxonomy.
+// Flavors that only partially match keep their own (cleaned) name so
+// we don't lose detail like "jordan almond".
+func normalizeFlavor(flavor string) string {
+	f := cleanFlavor(flavor)
+	if node := lookupFlavor(f); node != nil {
+		return node.Name
+	}
+	return f
+}
+
+// normalizeFlavors normalizes a list of flavors and drops duplicates
+func normalizeFlavors(flavors []string) []string {
+	var (
+		normalized = []string{}
+		seen       = make(map[string]bool)
+	)
+	for _, flavor := range flavors {
+		f := normalizeFlavor(flavor)
+		if f == "" || seen[f] {
+			continue
+		}
+		seen[f] = true
+		normalized = append(normalized, f)
+	}
+	return normalized
+}
+
+// flavorMatches checks if a flavor matches a query, which can be any level
+// of the taxonomy ("fruity", "berry", "raspberry") or a free-text flavor
+func flavorMatches(flavor string, query string) bool {
+	if node := lookupFlavor(cleanFlavor(query)); node != nil {
+		return node.Contains(classifyFlavor(flavor))
+	}
+	return normalizeFlavor(flavor) == normalizeFlavor(query)
+}
+
 func (h *Handler) getFlavorMap(beans []*firestore.DocumentSnapshot) map[string]int {
 	var flavorMap = make(map[string]int)
 
 	for _, bean := range beans {
 		for _, flavor := range docToBean(bean).Flavors {
-			f := strings.ToLower(flavor)
+			f := normalizeFlavor(flavor)
 			_, ok := flavorMap[f]
 			if ok {
 				flavorMap[f] += 1
@@ -35,13 +140,13 @@ func (h *Handler) getFlavorMap(beans []*firestore.DocumentSnapshot) map[string]i
 func (h *Handler) getFlavors(w http.ResponseWriter, r *http.Request) {
 	var (
 		resp = &FlavorsResp{}
-		ctx  = context.TODO()
+		ctx  = r.Context()
 	)
 
 	// Get bean count
 	beans, err := h.database.Collection("beans").Documents(ctx).GetAll()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.writeError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_normalizeFlavor(t *testing.T) {
+	tests := map[string]string{
+		"Chocolate":          "chocolate",
+		"chocolatey":         "chocolate",
+		"  Milk  Chocolate.": "chocolate",
+		"Dark Chocolate":     "dark chocolate",
+		"cherries":           "cherry",
+		"notes of Caramel":   "caramelized",
+		"nutty":              "nutty",
+		"Jordan Almond":      "jordan almond",
+		"something new":      "something new",
+	}
+
+	for in, exp := range tests {
+		assert.Equal(t, exp, normalizeFlavor(in), in)
+	}
+
+	assert.Equal(t,
+		[]string{"chocolate", "dark chocolate"},
+		normalizeFlavors([]string{"Chocolate", "chocolatey", "dark chocolate", ""}),
+	)
+}
+
+func Test_classifyFlavor(t *testing.T) {
+	var names = func(flavor string) []string {
+		var path []string
+		node := classifyFlavor(flavor)
+		if node == nil {
+			return nil
+		}
+		for _, n := range node.Path() {
+			path = append(path, n.Name)
+		}
+		return path
+	}
+
+	assert.Equal(t, []string{"nutty/cocoa", "cocoa", "dark chocolate"}, names("Dark Chocolate"))
+	assert.Equal(t, []string{"nutty/cocoa", "nutty", "almond"}, names("jordan almond"))
+	assert.Equal(t, []string{"fruity", "berry", "strawberry"}, names("ripe strawberries"))
+	assert.Equal(t, []string{"fruity", "other fruit", "pear"}, names("poached pear"))
+	assert.Nil(t, names("something new"))
+}
+
+func Test_flavorMatches(t *testing.T) {
+	assert.True(t, flavorMatches("raspberry", "fruity"))
+	assert.True(t, flavorMatches("raspberry", "berry"))
+	assert.True(t, flavorMatches("raspberry", "Raspberries"))
+	assert.True(t, flavorMatches("chocolatey", "nutty-cocoa"))
+	assert.False(t, flavorMatches("raspberry", "citrus"))
+	assert.True(t, flavorMatches("jordan almond", "jordan almond"))
+	assert.False(t, flavorMatches("almond", "jordan almond"))
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/api/iterator"
+)
+
+// Follow targets
+const (
+	followUser    = "user"
+	followRoaster = "roaster"
+)
+
+// Follow is a user following another user or a roaster
+type Follow struct {
+	Follower      string    `firestore:"follower" json:"follower"`
+	FollowerEmail string    `firestore:"follower_email" json:"-"`
+	Type          string    `firestore:"type" json:"type"`
+	Target        string    `firestore:"target" json:"target"`
+	CreatedAt     time.Time `firestore:"created_at" json:"created_at"`
+}
+
+// FollowResp is the response from the follow endpoints
+type FollowResp struct {
+	Following bool `json:"following"`
+}
+
+// followID is the document ID of a follow, so following twice is a no-op
+func followID(follower, followType, target string) string {
+	return fmt.Sprintf("%s_%s_%s", follower, followType, target)
+}
+
+// getFollowing returns the usernames and roaster slugs a user follows
+func (h *Handler) getFollowing(ctx context.Context, userEmail string) ([]string, []string, error) {
+	var (
+		users    []string
+		roasters []string
+	)
+
+	iter := h.database.Collection("follows").Where("follower_email", "==", userEmail).Documents(ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var f Follow
+		doc.DataTo(&f)
+		switch f.Type {
+		case followUser:
+			users = append(users, f.Target)
+		case followRoaster:
+			roasters = append(roasters, f.Target)
+		}
+	}
+
+	return users, roasters, nil
+}
@@ -0,0 +1,21 @@
+package handler
+
+import "math"
+
+// earthRadiusKm is the mean radius of the earth
+const earthRadiusKm = 6371.0
+
+// haversineKm returns the great-circle distance between two points in kilometers
+func haversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	var (
+		dLat = toRadians(lat2 - lat1)
+		dLng = toRadians(lng2 - lng1)
+		a    = math.Sin(dLat/2)*math.Sin(dLat/2) +
+			math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
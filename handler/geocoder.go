@@ -0,0 +1,424 @@
+package handler
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// maxReverseKm is how far a point can be from a city and still be placed in it
+const maxReverseKm = 50.0
+
+// errPlaceNotFound is returned when a geocoder can't place a query
+var errPlaceNotFound = errors.New("place not found")
+
+// Geocoder turns a city or address into coordinates and back
+type Geocoder interface {
+	Geocode(query string) (Place, error)
+	ReverseGeocode(lat, lng float64) (Place, error)
+}
+
+// Place is a normalized city with its coordinates
+type Place struct {
+	City    string  `json:"city"`
+	Region  string  `json:"region"`
+	Country string  `json:"country"`
+	Lat     float64 `json:"lat"`
+	Lng     float64 `json:"lng"`
+}
+
+// countryNames maps the country codes in the cities file to their name,
+// followed by other names people use for them
+var countryNames = map[string][]string{
+	"AE": {"United Arab Emirates", "UAE"},
+	"AR": {"Argentina"},
+	"AT": {"Austria"},
+	"AU": {"Australia"},
+	"BE": {"Belgium"},
+	"BR": {"Brazil"},
+	"CA": {"Canada"},
+	"CH": {"Switzerland"},
+	"CL": {"Chile"},
+	"CN": {"China"},
+	"CO": {"Colombia"},
+	"CR": {"Costa Rica"},
+	"CZ": {"Czech Republic", "Czechia"},
+	"DE": {"Germany", "Deutschland"},
+	"DK": {"Denmark"},
+	"EC": {"Ecuador"},
+	"EE": {"Estonia"},
+	"ES": {"Spain", "España"},
+	"ET": {"Ethiopia"},
+	"FI": {"Finland"},
+	"FR": {"France"},
+	"GB": {"United Kingdom", "UK", "Great Britain", "England", "Scotland", "Wales"},
+	"GR": {"Greece"},
+	"GT": {"Guatemala"},
+	"HK": {"Hong Kong"},
+	"HU": {"Hungary"},
+	"ID": {"Indonesia"},
+	"IE": {"Ireland"},
+	"IL": {"Israel"},
+	"IN": {"India"},
+	"IS": {"Iceland"},
+	"IT": {"Italy", "Italia"},
+	"JP": {"Japan"},
+	"KE": {"Kenya"},
+	"KR": {"South Korea", "Korea"},
+	"LT": {"Lithuania"},
+	"LV": {"Latvia"},
+	"MX": {"Mexico", "México"},
+	"MY": {"Malaysia"},
+	"NL": {"Netherlands", "The Netherlands", "Holland"},
+	"NO": {"Norway"},
+	"NZ": {"New Zealand"},
+	"PA": {"Panama"},
+	"PE": {"Peru"},
+	"PH": {"Philippines"},
+	"PL": {"Poland"},
+	"PT": {"Portugal"},
+	"RW": {"Rwanda"},
+	"SE": {"Sweden"},
+	"SG": {"Singapore"},
+	"TH": {"Thailand"},
+	"TR": {"Turkey"},
+	"TW": {"Taiwan"},
+	"UG": {"Uganda"},
+	"US": {"United States", "USA", "United States of America", "America"},
+	"VN": {"Vietnam"},
+	"ZA": {"South Africa"},
+}
+
+// countryName returns the name of a country code, or the code if it's unknown
+func countryName(code string) string {
+	if names, ok := countryNames[code]; ok {
+		return names[0]
+	}
+	return code
+}
+
+// city is a row of the cities file
+type city struct {
+	Names       []string
+	Name        string
+	Lat         float64
+	Lng         float64
+	CountryCode string
+	RegionCode  string
+	Region      string
+	Population  int64
+}
+
+func (c city) place() Place {
+	return Place{
+		City:    c.Name,
+		Region:  c.Region,
+		Country: countryName(c.CountryCode),
+		Lat:     c.Lat,
+		Lng:     c.Lng,
+	}
+}
+
+// matches checks if a qualifier like "OR", "Oregon" or "USA" names the
+// region or country of a city
+func (c city) matches(qualifier string) bool {
+	if strings.EqualFold(qualifier, c.RegionCode) ||
+		strings.EqualFold(qualifier, c.Region) ||
+		strings.EqualFold(qualifier, c.CountryCode) {
+		return true
+	}
+	for _, name := range countryNames[c.CountryCode] {
+		if strings.EqualFold(qualifier, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// OfflineGeocoder places cities from a GeoNames style cities file
+type OfflineGeocoder struct {
+	cities []city
+	byName map[string][]int
+}
+
+// NewOfflineGeocoder loads a tab separated cities file with the columns name,
+// ascii name, alternate names (comma separated), latitude, longitude, country
+// code, region code, region name and population. Lines starting with # are
+// ignored.
+func NewOfflineGeocoder(path string) (*OfflineGeocoder, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseCities(f)
+}
+
+func parseCities(r io.Reader) (*OfflineGeocoder, error) {
+	var (
+		g       = &OfflineGeocoder{byName: make(map[string][]int)}
+		scanner = bufio.NewScanner(r)
+		line    int
+	)
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		cols := strings.Split(text, "\t")
+		if len(cols) != 9 {
+			return nil, fmt.Errorf("line %d: expected 9 columns, got %d", line, len(cols))
+		}
+		lat, err := strconv.ParseFloat(cols[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid latitude: %v", line, err)
+		}
+		lng, err := strconv.ParseFloat(cols[4], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid longitude: %v", line, err)
+		}
+		population, _ := strconv.ParseInt(cols[8], 10, 64)
+
+		c := city{
+			Name:        cols[0],
+			Lat:         lat,
+			Lng:         lng,
+			CountryCode: cols[5],
+			RegionCode:  cols[6],
+			Region:      cols[7],
+			Population:  population,
+		}
+		c.Names = append([]string{cols[0], cols[1]}, splitAlternateNames(cols[2])...)
+
+		i := len(g.cities)
+		g.cities = append(g.cities, c)
+
+		seen := make(map[string]bool)
+		for _, name := range c.Names {
+			key := strings.ToLower(name)
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			g.byName[key] = append(g.byName[key], i)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+func splitAlternateNames(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// addressParts splits a city or address on commas and drops the words with
+// digits, like street numbers and postal codes
+func addressParts(query string) []string {
+	var parts []string
+	for _, part := range strings.Split(query, ",") {
+		var words []string
+		for _, word := range strings.Fields(part) {
+			if !strings.ContainsAny(word, "0123456789") {
+				words = append(words, word)
+			}
+		}
+		if len(words) > 0 {
+			parts = append(parts, strings.Join(words, " "))
+		}
+	}
+	return parts
+}
+
+// Geocode places a city like "Portland, OR" or an address like
+// "123 Main St, Portland, Maine 04101". The first part that names a known city
+// wins, the parts after it pick between cities with the same name, and ties go
+// to the largest city.
+func (g *OfflineGeocoder) Geocode(query string) (Place, error) {
+	parts := addressParts(query)
+	for i, part := range parts {
+		candidates := g.byName[strings.ToLower(part)]
+		if len(candidates) == 0 {
+			continue
+		}
+
+		qualifiers := parts[i+1:]
+		score := func(c city) int {
+			var n int
+			for _, q := range qualifiers {
+				if c.matches(q) {
+					n++
+				}
+			}
+			return n
+		}
+
+		sorted := make([]city, len(candidates))
+		for j, idx := range candidates {
+			sorted[j] = g.cities[idx]
+		}
+		sort.SliceStable(sorted, func(a, b int) bool {
+			sa, sb := score(sorted[a]), score(sorted[b])
+			if sa != sb {
+				return sa > sb
+			}
+			return sorted[a].Population > sorted[b].Population
+		})
+
+		// A qualifier that rules out every candidate means it's another city
+		// with the same name that we don't know about
+		if len(qualifiers) > 0 && score(sorted[0]) == 0 && g.knownQualifier(qualifiers) {
+			return Place{}, errPlaceNotFound
+		}
+
+		return sorted[0].place(), nil
+	}
+
+	return Place{}, errPlaceNotFound
+}
+
+// knownQualifier checks if any qualifier names a region or country in the file
+func (g *OfflineGeocoder) knownQualifier(qualifiers []string) bool {
+	for _, c := range g.cities {
+		for _, q := range qualifiers {
+			if c.matches(q) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReverseGeocode returns the closest city within maxReverseKm of a point
+func (g *OfflineGeocoder) ReverseGeocode(lat, lng float64) (Place, error) {
+	var (
+		best     *city
+		bestDist = maxReverseKm
+	)
+	for i := range g.cities {
+		c := &g.cities[i]
+		if d := haversineKm(lat, lng, c.Lat, c.Lng); d <= bestDist {
+			best, bestDist = c, d
+		}
+	}
+	if best == nil {
+		return Place{}, errPlaceNotFound
+	}
+
+	return best.place(), nil
+}
+
+// noopGeocoder is used when no cities file is available
+type noopGeocoder struct{}
+
+func (noopGeocoder) Geocode(string) (Place, error) { return Place{}, errPlaceNotFound }
+
+func (noopGeocoder) ReverseGeocode(float64, float64) (Place, error) {
+	return Place{}, errPlaceNotFound
+}
+
+// newGeocoder loads the offline geocoder, roasters are saved as they are sent
+// if the cities file can't be read
+func newGeocoder(path string, logger *zap.SugaredLogger) Geocoder {
+	g, err := NewOfflineGeocoder(path)
+	if err != nil {
+		if logger != nil {
+			logger.Warnw(
+				"Geocoding disabled, couldn't load cities",
+				"path", path,
+				"error", err,
+			)
+		}
+		return noopGeocoder{}
+	}
+	return g
+}
+
+// geocodeRoaster fills the location of a roaster from its address or city, or
+// its city, region and country from its location
+func (h *Handler) geocodeRoaster(r *Roaster) {
+	switch {
+	case r.Location == nil && (strings.TrimSpace(r.City) != "" || r.Address != nil):
+		query := r.City
+		if r.Address != nil && r.Address.City != "" {
+			query = r.Address.String()
+		}
+		place, err := h.geocoder.Geocode(query)
+		if err != nil {
+			h.logger.Infow(
+				"Couldn't geocode roaster city",
+				"slug", r.Slug,
+				"city", query,
+			)
+			return
+		}
+		r.Location = &latlng.LatLng{Latitude: place.Lat, Longitude: place.Lng}
+		if r.Region == "" {
+			r.Region = place.Region
+		}
+		if r.Country == "" {
+			r.Country = place.Country
+		}
+	case r.Location != nil && strings.TrimSpace(r.City) == "":
+		place, err := h.geocoder.ReverseGeocode(r.Location.Latitude, r.Location.Longitude)
+		if err != nil {
+			h.logger.Infow(
+				"Couldn't reverse geocode roaster location",
+				"slug", r.Slug,
+				"lat", r.Location.Latitude,
+				"lng", r.Location.Longitude,
+			)
+			return
+		}
+		r.City = place.City
+		r.Region = place.Region
+		r.Country = place.Country
+	}
+}
+
+// keepStoredLocation reuses the stored location of a roaster when an edit
+// omits it but doesn't move the roaster, so precise coordinates aren't
+// replaced by the center of the city
+func keepStoredLocation(r *Roaster, stored Roaster) {
+	if r.Location != nil || stored.Location == nil {
+		return
+	}
+	if !strings.EqualFold(strings.TrimSpace(r.City), strings.TrimSpace(stored.City)) {
+		return
+	}
+	if addressString(r.Address) != addressString(stored.Address) {
+		return
+	}
+
+	r.Location = stored.Location
+	if r.Region == "" && r.Country == "" {
+		r.Region = stored.Region
+		r.Country = stored.Country
+	}
+}
+
+func addressString(a *Address) string {
+	if a == nil {
+		return ""
+	}
+	return strings.ToLower(a.String())
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+const testCities = `# name	ascii_name	alternate_names	latitude	longitude	country_code	admin1_code	admin1_name	population
+Portland	Portland		45.5051	-122.6750	US	OR	Oregon	654741
+Portland	Portland		43.6591	-70.2568	US	ME	Maine	66215
+Montréal	Montreal		45.5017	-73.5673	CA	QC	Quebec	1704694
+London	London		51.5074	-0.1278	GB	ENG	England	8961989
+`
+
+func Test_OfflineGeocoder(t *testing.T) {
+	g, err := parseCities(strings.NewReader(testCities))
+	assert.NoError(t, err)
+
+	// Ties go to the largest city
+	place, err := g.Geocode("Portland")
+	assert.NoError(t, err)
+	assert.Equal(t, "Oregon", place.Region)
+	assert.Equal(t, "United States", place.Country)
+
+	// Qualifiers pick between cities with the same name
+	place, err = g.Geocode("Portland, Maine")
+	assert.NoError(t, err)
+	assert.Equal(t, 43.6591, place.Lat)
+
+	// Street numbers and postal codes are skipped
+	place, err = g.Geocode("123 Congress St, Portland, ME 04101")
+	assert.NoError(t, err)
+	assert.Equal(t, "Maine", place.Region)
+
+	place, err = g.Geocode("montreal")
+	assert.NoError(t, err)
+	assert.Equal(t, "Montréal", place.City)
+	assert.Equal(t, "Canada", place.Country)
+
+	// A known country that rules out every match is another city
+	_, err = g.Geocode("London, Canada")
+	assert.Equal(t, errPlaceNotFound, err)
+
+	_, err = g.Geocode("Springfield")
+	assert.Equal(t, errPlaceNotFound, err)
+
+	place, err = g.ReverseGeocode(51.52, -0.08)
+	assert.NoError(t, err)
+	assert.Equal(t, "London", place.City)
+	assert.Equal(t, "United Kingdom", place.Country)
+
+	_, err = g.ReverseGeocode(0, 0)
+	assert.Equal(t, errPlaceNotFound, err)
+
+	_, err = parseCities(strings.NewReader("Paris\tParis\n"))
+	assert.Error(t, err)
+}
+
+func Test_bundledCities(t *testing.T) {
+	g, err := NewOfflineGeocoder("../data/cities.tsv")
+	assert.NoError(t, err)
+
+	place, err := g.Geocode("Chicago, IL")
+	assert.NoError(t, err)
+	assert.Equal(t, "Illinois", place.Region)
+}
+
+func Test_keepStoredLocation(t *testing.T) {
+	precise := &latlng.LatLng{Latitude: 45.5231, Longitude: -122.6765}
+	stored := Roaster{
+		City:     "Portland",
+		Region:   "Oregon",
+		Country:  "United States",
+		Address:  &Address{Street: "123 SE Ankeny St", City: "Portland"},
+		Location: precise,
+	}
+
+	type test struct {
+		name string
+		edit Roaster
+		exp  *latlng.LatLng
+	}
+
+	tests := []test{
+		{name: "same city and address", edit: Roaster{City: "portland", Address: &Address{Street: "123 SE Ankeny St", City: "Portland"}}, exp: precise},
+		{name: "city changed", edit: Roaster{City: "Seattle", Address: &Address{Street: "123 SE Ankeny St", City: "Portland"}}},
+		{name: "address changed", edit: Roaster{City: "Portland", Address: &Address{Street: "9 NW 23rd Ave", City: "Portland"}}},
+		{name: "location given", edit: Roaster{City: "Portland", Location: &latlng.LatLng{Latitude: 1, Longitude: 2}}, exp: &latlng.LatLng{Latitude: 1, Longitude: 2}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := tc.edit
+			keepStoredLocation(&r, stored)
+			assert.Equal(t, tc.exp, r.Location)
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+)
+
+// geohashPrecision is the length of the geohash stored on roasters, about
+// 5m x 5m cells
+const geohashPrecision = 9
+
+// maxCoverCells is the most geohash prefixes queried for an area
+const maxCoverCells = 16
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// BBox is an area between two corners, in degrees
+type BBox struct {
+	MinLat, MinLng, MaxLat, MaxLng float64
+}
+
+// encodeGeohash returns the geohash of a point
+func encodeGeohash(lat, lng float64, precision int) string {
+	var (
+		hash           strings.Builder
+		latMin, latMax = -90.0, 90.0
+		lngMin, lngMax = -180.0, 180.0
+		bit, ch        int
+		even           = true
+	)
+
+	for hash.Len() < precision {
+		if even {
+			mid := (lngMin + lngMax) / 2
+			if lng >= mid {
+				ch |= 1 << (4 - bit)
+				lngMin = mid
+			} else {
+				lngMax = mid
+			}
+		} else {
+			mid := (latMin + latMax) / 2
+			if lat >= mid {
+				ch |= 1 << (4 - bit)
+				latMin = mid
+			} else {
+				latMax = mid
+			}
+		}
+		even = !even
+
+		if bit < 4 {
+			bit++
+			continue
+		}
+		hash.WriteByte(geohashAlphabet[ch])
+		bit, ch = 0, 0
+	}
+
+	return hash.String()
+}
+
+// geohashCellSize returns the height and width of a geohash cell in degrees
+func geohashCellSize(precision int) (float64, float64) {
+	bits := 5 * precision
+	lngBits := (bits + 1) / 2
+	latBits := bits / 2
+	return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(lngBits))
+}
+
+// geohashesCovering returns the geohash prefixes of the cells that cover a
+// box, using the longest prefixes that need at most maxCoverCells queries
+func geohashesCovering(b BBox) []string {
+	// Boxes across the antimeridian are split in two
+	if b.MinLng > b.MaxLng {
+		return append(
+			geohashesCovering(BBox{b.MinLat, b.MinLng, b.MaxLat, 180}),
+			geohashesCovering(BBox{b.MinLat, -180, b.MaxLat, b.MaxLng})...,
+		)
+	}
+
+	for precision := geohashPrecision; precision > 0; precision-- {
+		height, width := geohashCellSize(precision)
+		var (
+			minRow = math.Floor((b.MinLat + 90) / height)
+			maxRow = math.Floor((math.Min(b.MaxLat, 90-height/2) + 90) / height)
+			minCol = math.Floor((b.MinLng + 180) / width)
+			maxCol = math.Floor((math.Min(b.MaxLng, 180-width/2) + 180) / width)
+		)
+		if (maxRow-minRow+1)*(maxCol-minCol+1) > maxCoverCells && precision > 1 {
+			continue
+		}
+
+		var (
+			hashes []string
+			seen   = make(map[string]bool)
+		)
+		for row := minRow; row <= maxRow; row++ {
+			for col := minCol; col <= maxCol; col++ {
+				lat := (row+0.5)*height - 90
+				lng := (col+0.5)*width - 180
+				hash := encodeGeohash(lat, lng, precision)
+				if !seen[hash] {
+					seen[hash] = true
+					hashes = append(hashes, hash)
+				}
+			}
+		}
+		return hashes
+	}
+
+	return nil
+}
+
+// radiusBBox returns the box around a circle
+func radiusBBox(lat, lng, radiusKm float64) BBox {
+	dLat := radiusKm / (earthRadiusKm * math.Pi / 180)
+	b := BBox{
+		MinLat: math.Max(-90, lat-dLat),
+		MaxLat: math.Min(90, lat+dLat),
+		MinLng: -180,
+		MaxLng: 180,
+	}
+
+	// Near the poles the circle covers every longitude
+	if cos := math.Cos(toRadians(lat)); b.MinLat > -90 && b.MaxLat < 90 && cos > 0 {
+		dLng := dLat / cos
+		if dLng < 180 {
+			b.MinLng = lng - dLng
+			b.MaxLng = lng + dLng
+			if b.MinLng < -180 {
+				b.MinLng += 360
+			}
+			if b.MaxLng > 180 {
+				b.MaxLng -= 360
+			}
+		}
+	}
+
+	return b
+}
+
+// Contains checks if a point is inside the box
+func (b BBox) Contains(lat, lng float64) bool {
+	if lat < b.MinLat || lat > b.MaxLat {
+		return false
+	}
+	if b.MinLng > b.MaxLng {
+		return lng >= b.MinLng || lng <= b.MaxLng
+	}
+	return lng >= b.MinLng && lng <= b.MaxLng
+}
+
+// parseBBox reads a box as "minLng,minLat,maxLng,maxLat", the GeoJSON order
+func parseBBox(s string) (BBox, error) {
+	var (
+		b     BBox
+		parts = strings.Split(s, ",")
+		v     [4]float64
+	)
+	if len(parts) != 4 {
+		return b, fmt.Errorf("bbox must be minLng,minLat,maxLng,maxLat")
+	}
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return b, fmt.Errorf("bbox must be minLng,minLat,maxLng,maxLat")
+		}
+		v[i] = f
+	}
+	b = BBox{MinLng: v[0], MinLat: v[1], MaxLng: v[2], MaxLat: v[3]}
+	if b.MinLat > b.MaxLat || b.MinLat < -90 || b.MaxLat > 90 || b.MinLng < -180 || b.MaxLng > 180 {
+		return b, fmt.Errorf("bbox is out of range")
+	}
+	return b, nil
+}
+
+// roastersInBBox queries the geohash index for the roasters inside a box
+func (h *Handler) roastersInBBox(ctx context.Context, b BBox) ([]*firestore.DocumentSnapshot, error) {
+	var (
+		docs []*firestore.DocumentSnapshot
+		seen = make(map[string]bool)
+	)
+
+	for _, prefix := range geohashesCovering(b) {
+		found, err := h.database.Collection("roasters").
+			Where("geohash", ">=", prefix).
+			Where("geohash", "<", prefix+"~").
+			Documents(ctx).
+			GetAll()
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range found {
+			if seen[doc.Ref.ID] {
+				continue
+			}
+			seen[doc.Ref.ID] = true
+			r := docToRoaster(doc)
+			if r.Location != nil && b.Contains(r.Location.Latitude, r.Location.Longitude) {
+				docs = append(docs, doc)
+			}
+		}
+	}
+
+	return docs, nil
+}
+
+// roasterGeohash returns the geohash stored for a roaster's location
+func roasterGeohash(r Roaster) string {
+	if r.Location == nil {
+		return ""
+	}
+	return encodeGeohash(r.Location.Latitude, r.Location.Longitude, geohashPrecision)
+}
+
+// BackfillGeohashes indexes the roasters written before the geohash index
+func (h *Handler) BackfillGeohashes(ctx context.Context) (int, error) {
+	docs, err := h.database.Collection("roasters").Documents(ctx).GetAll()
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		batch   = h.database.Batch()
+		pending int
+		updated int
+	)
+	for _, doc := range docs {
+		r := docToRoasterDB(doc)
+		hash := roasterGeohash(r.Roaster)
+		if hash == r.Geohash {
+			continue
+		}
+		batch.Update(doc.Ref, []firestore.Update{{Path: "geohash", Value: hash}})
+		pending++
+		updated++
+		if pending == maxBatchSize {
+			if _, err := batch.Commit(ctx); err != nil {
+				return updated - pending, err
+			}
+			batch = h.database.Batch()
+			pending = 0
+		}
+	}
+	if pending > 0 {
+		if _, err := batch.Commit(ctx); err != nil {
+			return updated - pending, err
+		}
+	}
+
+	return updated, nil
+}
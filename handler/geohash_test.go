@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_encodeGeohash(t *testing.T) {
+	assert.Equal(t, "u4pruydqqvj", encodeGeohash(57.64911, 10.40744, 11))
+	assert.Equal(t, "dp3wj", encodeGeohash(41.8781, -87.6298, 5))
+}
+
+func Test_geohashesCovering(t *testing.T) {
+	covered := func(hashes []string, lat, lng float64) bool {
+		hash := encodeGeohash(lat, lng, geohashPrecision)
+		for _, prefix := range hashes {
+			if strings.HasPrefix(hash, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Chicago and around
+	b := radiusBBox(41.8781, -87.6298, 50)
+	hashes := geohashesCovering(b)
+	assert.LessOrEqual(t, len(hashes), maxCoverCells)
+	assert.True(t, covered(hashes, 41.8781, -87.6298))
+	assert.True(t, covered(hashes, 42.0451, -87.6877))
+	assert.False(t, covered(hashes, 40.7128, -74.0060))
+
+	// Across the antimeridian
+	b = BBox{MinLat: -20, MinLng: 175, MaxLat: -15, MaxLng: -175}
+	hashes = geohashesCovering(b)
+	assert.True(t, b.Contains(-17.7, 178.1))
+	assert.True(t, covered(hashes, -17.7, 178.1))
+	assert.True(t, covered(hashes, -18.1, -178.4))
+	assert.False(t, b.Contains(-17.7, 170))
+}
+
+func Test_parseBBox(t *testing.T) {
+	b, err := parseBBox("-88.0,41.6,-87.5,42.1")
+	assert.NoError(t, err)
+	assert.Equal(t, BBox{MinLat: 41.6, MinLng: -88, MaxLat: 42.1, MaxLng: -87.5}, b)
+
+	_, err = parseBBox("1,2,3")
+	assert.Error(t, err)
+	_, err = parseBBox("0,50,10,40")
+	assert.Error(t, err)
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"sort"
+)
+
+// maxClusterZoom is the zoom level from which roasters are no longer clustered
+const maxClusterZoom = 13
+
+// FeatureCollection is a GeoJSON FeatureCollection
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Feature is a GeoJSON Feature
+type Feature struct {
+	Type       string                 `json:"type"`
+	Geometry   Geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// Geometry is a GeoJSON Point, in longitude, latitude order
+type Geometry struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// RoasterPoint is a roaster on the map and the number of its beans that
+// match the filters
+type RoasterPoint struct {
+	Roaster   Roaster
+	BeanCount int
+}
+
+func pointFeature(lat, lng float64, properties map[string]interface{}) Feature {
+	return Feature{
+		Type:       "Feature",
+		Geometry:   Geometry{Type: "Point", Coordinates: [2]float64{lng, lat}},
+		Properties: properties,
+	}
+}
+
+func roasterFeature(p RoasterPoint) Feature {
+	return pointFeature(p.Roaster.Location.Latitude, p.Roaster.Location.Longitude, map[string]interface{}{
+		"name":       p.Roaster.Name,
+		"slug":       p.Roaster.Slug,
+		"city":       p.Roaster.City,
+		"logo":       p.Roaster.Logo,
+		"url":        p.Roaster.URL,
+		"verified":   p.Roaster.Verified,
+		"bean_count": p.BeanCount,
+	})
+}
+
+// clusterPrecision is the geohash length roasters are grouped by at a zoom
+// level, roughly a few map tiles per cluster
+func clusterPrecision(zoom int) int {
+	switch {
+	case zoom <= 2:
+		return 1
+	case zoom <= 4:
+		return 2
+	case zoom <= 7:
+		return 3
+	case zoom <= 9:
+		return 4
+	case zoom < maxClusterZoom:
+		return 5
+	}
+	return 0
+}
+
+// roasterFeatures builds the features of a map. Below maxClusterZoom,
+// roasters that share a geohash cell are merged into a cluster at their
+// centroid. A negative zoom turns clustering off.
+func roasterFeatures(points []RoasterPoint, zoom int) FeatureCollection {
+	var (
+		fc        = FeatureCollection{Type: "FeatureCollection", Features: []Feature{}}
+		precision = 0
+	)
+	if zoom >= 0 {
+		precision = clusterPrecision(zoom)
+	}
+
+	if precision == 0 {
+		for _, p := range points {
+			fc.Features = append(fc.Features, roasterFeature(p))
+		}
+		return fc
+	}
+
+	var (
+		cells = make(map[string][]RoasterPoint)
+		keys  []string
+	)
+	for _, p := range points {
+		key := encodeGeohash(p.Roaster.Location.Latitude, p.Roaster.Location.Longitude, precision)
+		if _, ok := cells[key]; !ok {
+			keys = append(keys, key)
+		}
+		cells[key] = append(cells[key], p)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		cell := cells[key]
+		if len(cell) == 1 {
+			fc.Features = append(fc.Features, roasterFeature(cell[0]))
+			continue
+		}
+
+		var lat, lng float64
+		beans := 0
+		for _, p := range cell {
+			lat += p.Roaster.Location.Latitude
+			lng += p.Roaster.Location.Longitude
+			beans += p.BeanCount
+		}
+		n := float64(len(cell))
+		fc.Features = append(fc.Features, pointFeature(lat/n, lng/n, map[string]interface{}{
+			"cluster":       true,
+			"cluster_id":    key,
+			"roaster_count": len(cell),
+			"bean_count":    beans,
+		}))
+	}
+
+	return fc
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+func Test_roasterFeatures(t *testing.T) {
+	point := func(slug string, lat, lng float64, beans int) RoasterPoint {
+		return RoasterPoint{
+			Roaster:   Roaster{Slug: slug, Location: &latlng.LatLng{Latitude: lat, Longitude: lng}},
+			BeanCount: beans,
+		}
+	}
+	points := []RoasterPoint{
+		point("ipsento", 41.9097, -87.6773, 3),
+		point("metric", 41.8865, -87.6640, 2),
+		point("heart", 45.5231, -122.6765, 4),
+	}
+
+	// Zoomed in, every roaster is its own feature
+	fc := roasterFeatures(points, 14)
+	assert.Equal(t, "FeatureCollection", fc.Type)
+	assert.Len(t, fc.Features, 3)
+	assert.Equal(t, [2]float64{-87.6773, 41.9097}, fc.Features[0].Geometry.Coordinates)
+	assert.Equal(t, 3, fc.Features[0].Properties["bean_count"])
+
+	// Zoomed out, the Chicago roasters are clustered
+	fc = roasterFeatures(points, 4)
+	assert.Len(t, fc.Features, 2)
+	var cluster Feature
+	for _, f := range fc.Features {
+		if f.Properties["cluster"] == true {
+			cluster = f
+		}
+	}
+	assert.Equal(t, 2, cluster.Properties["roaster_count"])
+	assert.Equal(t, 5, cluster.Properties["bean_count"])
+	assert.InDelta(t, 41.8981, cluster.Geometry.Coordinates[1], 0.0001)
+
+	// Without a zoom nothing is clustered
+	assert.Len(t, roasterFeatures(points, -1).Features, 3)
+}
+
+func Test_BeanFilterIsZero(t *testing.T) {
+	assert.True(t, BeanFilter{Currency: "USD"}.IsZero())
+	assert.False(t, BeanFilter{Organic: true, Currency: "USD"}.IsZero())
+}
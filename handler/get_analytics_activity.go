@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// AnalyticsActivityResp is the response for the GET /analytics/activity endpoint
+type AnalyticsActivityResp struct {
+	Window AnalyticsWindow `json:"window"`
+	Days   []DayActivity   `json:"days"`
+}
+
+// getAnalyticsActivity counts the edits per day over a window
+func (h *Handler) getAnalyticsActivity(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		q    = r.URL.Query()
+		resp = &AnalyticsActivityResp{}
+		kind string
+		err  error
+	)
+
+	resp.Window, err = parseAnalyticsWindow(q, time.Now())
+	if err != nil {
+		h.writeError(w, r, validationError(err.Error()))
+		return
+	}
+
+	// Both beans and roasters unless a kind is asked for
+	if v := q.Get("kind"); v != "" {
+		if kind, err = parseEntityKind(v); err != nil {
+			h.writeError(w, r, validationError(err.Error()))
+			return
+		}
+	}
+
+	resp.Days, err = h.analytics.Activity(ctx, resp.Window, kind, "")
+	if err != nil {
+		h.logger.Error(err)
+		h.writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// AnalyticsContributorsResp is the response for the GET /analytics/contributors endpoint
+type AnalyticsContributorsResp struct {
+	Window       AnalyticsWindow    `json:"window"`
+	Contributors []ContributorCount `json:"contributors"`
+}
+
+// getAnalyticsContributors ranks contributors by their edits over a window
+func (h *Handler) getAnalyticsContributors(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		q     = r.URL.Query()
+		resp  = &AnalyticsContributorsResp{}
+		limit = defaultContributors
+		err   error
+	)
+
+	resp.Window, err = parseAnalyticsWindow(q, time.Now())
+	if err != nil {
+		h.writeError(w, r, validationError(err.Error()))
+		return
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxContributors {
+			h.writeError(w, r, validationError(fmt.Sprintf("limit must be between 1 and %d", maxContributors)))
+			return
+		}
+	}
+
+	resp.Contributors, err = h.analytics.Contributors(ctx, resp.Window, limit)
+	if err != nil {
+		h.logger.Error(err)
+		h.writeError(w, r, err)
+		return
+	}
+	h.setContributorUsernames(ctx, resp.Contributors)
+
+	json.NewEncoder(w).Encode(resp)
+}
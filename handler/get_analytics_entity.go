@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// AnalyticsEntityResp is the response for the GET /analytics/entities/{slug} endpoint
+type AnalyticsEntityResp struct {
+	Window AnalyticsWindow `json:"window"`
+	EntityEdits
+	Days []DayActivity `json:"days"`
+}
+
+// getAnalyticsEntity sums up how often a bean or roaster is edited
+func (h *Handler) getAnalyticsEntity(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		vars = mux.Vars(r)
+		slug = vars["slug"]
+		q    = r.URL.Query()
+		resp = &AnalyticsEntityResp{}
+	)
+
+	window, err := parseAnalyticsWindow(q, time.Now())
+	if err != nil {
+		h.writeError(w, r, validationError(err.Error()))
+		return
+	}
+	resp.Window = window
+
+	kind, err := parseEntityKind(q.Get("kind"))
+	if err != nil {
+		h.writeError(w, r, validationError(err.Error()))
+		return
+	}
+
+	// Make sure the bean or roaster exists
+	if kind == "bean" {
+		_, err = h.getBeanDocBySlug(ctx, slug)
+	} else {
+		_, err = h.getRoasterDocBySlug(ctx, slug)
+	}
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	resp.EntityEdits, err = h.analytics.Entity(ctx, window, kind, slug)
+	if err != nil {
+		h.logger.Error(err)
+		h.writeError(w, r, err)
+		return
+	}
+	resp.Days, err = h.analytics.Activity(ctx, window, kind, slug)
+	if err != nil {
+		h.logger.Error(err)
+		h.writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 	"time"
@@ -12,13 +11,14 @@ import (
 
 // GetBeanResp is the response for the GET /bean/{slug} endpoint
 type GetBeanResp struct {
-	Bean    Bean     `json:"bean"`
-	Reviews []Review `json:"reviews"`
+	Bean       Bean     `json:"bean"`
+	Reviews    []Review `json:"reviews"`
+	SavedCount int      `json:"saved_count"`
 }
 
 func (h *Handler) getBean(w http.ResponseWriter, r *http.Request) {
 	var (
-		ctx     = context.TODO()
+		ctx     = r.Context()
 		resp    = &GetBeanResp{}
 		vars    = mux.Vars(r)
 		slug    = vars["slug"]
@@ -27,38 +27,42 @@ func (h *Handler) getBean(w http.ResponseWriter, r *http.Request) {
 		reviews []Review
 	)
 	// Get the bean
-	beanIter := h.database.Collection("beans").Where("slug", "==", slug).Documents(ctx)
-	for {
-		beanDoc, err = beanIter.Next()
-		if beanDoc == nil {
-			http.Error(w, "invalid bean", http.StatusBadRequest)
-			return
-		}
+	beanDoc, err = h.getBeanDocBySlug(ctx, slug)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	resp.Bean = docToBean(beanDoc)
 
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
+	h.logger.Info(beanDoc.Ref.ID)
 
-		resp.Bean = docToBean(beanDoc)
-		break
+	// Show the verified badge of the roaster
+	if roasterDoc, err := h.getRoasterDocBySlug(ctx, resp.Bean.Roaster.Slug); err == nil {
+		resp.Bean.Roaster.Verified = docToRoaster(roasterDoc).Verified
 	}
 
-	h.logger.Info(beanDoc.Ref.ID)
+	// Count the users that saved the bean
+	resp.SavedCount, err = h.beanSavedCount(ctx, slug)
+	if err != nil {
+		h.logger.Error(err)
+	}
 
 	if h.cfg.ReviewsEnabled {
 		// Get reviews
-		rows, err := h.postgres.Query(`
+		rows, err := h.postgres.QueryContext(ctx, `
 		SELECT
 			r.review_id,
 			r.rating,
 			r.review,
 			r.bean_ref,
 			r.updated_at,
+			r.helpful_count,
+			r.not_helpful_count,
 			u.username as user
 		FROM reviews r
 		LEFT JOIN users u on r.user_id = u.user_id
-		WHERE r.bean_ref = $1;
+		WHERE r.bean_ref = $1 AND NOT r.hidden
+		ORDER BY r.helpful_count - r.not_helpful_count DESC, r.updated_at DESC;
 	`, beanDoc.Ref.ID)
 		if err != nil {
 			h.logger.Error(err)
@@ -70,8 +74,10 @@ func (h *Handler) getBean(w http.ResponseWriter, r *http.Request) {
 			var review string
 			var beanRef string
 			var updatedAt time.Time
+			var helpfulCount int
+			var notHelpfulCount int
 			var user string
-			err = rows.Scan(&reviewId, &rating, &review, &beanRef, &updatedAt, &user)
+			err = rows.Scan(&reviewId, &rating, &review, &beanRef, &updatedAt, &helpfulCount, &notHelpfulCount, &user)
 			if err != nil {
 				h.logger.Error(err)
 			}
@@ -86,11 +92,14 @@ func (h *Handler) getBean(w http.ResponseWriter, r *http.Request) {
 			)
 
 			reviews = append(reviews, Review{
-				Review:    review,
-				Rating:    rating,
-				UpdatedAt: updatedAt,
-				User:      user,
-				Bean:      slug,
+				ID:              reviewId,
+				Review:          review,
+				Rating:          rating,
+				UpdatedAt:       updatedAt,
+				User:            user,
+				Bean:            slug,
+				HelpfulCount:    helpfulCount,
+				NotHelpfulCount: notHelpfulCount,
 			})
 		}
 		err = rows.Err()
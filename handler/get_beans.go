@@ -1,32 +1,57 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
-
-	"google.golang.org/api/iterator"
+	"strings"
 )
 
 func (h *Handler) getBeans(w http.ResponseWriter, r *http.Request) {
 	var (
 		resp = &BeansResp{}
-		ctx  = context.TODO()
+		ctx  = r.Context()
 	)
 
+	filter, err := parseBeanFilter(r.URL.Query())
+	if err != nil {
+		h.writeError(w, r, validationError(err.Error()))
+		return
+	}
+
+	// Prices are compared in the requested currency
+	rates := h.exchangeRates()
+	if filter.Currency == "" {
+		filter.Currency = strings.ToUpper(h.cfg.BaseCurrency)
+	}
+	if _, ok := rates[filter.Currency]; !ok {
+		h.writeError(w, r, errUnknownCurrency)
+		return
+	}
+
 	// Call Firestore API
-	iter := h.database.Collection("beans").Documents(ctx)
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			h.logger.Fatalf("Failed to iterate: %v", err)
+	docs, err := getAllDocs(ctx, h.database.Collection("beans").Query)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	for _, doc := range docs {
+		bean := docToBean(doc)
+		bean.PricePer100g = lowestPricePer100g(bean, rates, filter.Currency)
+		if !filter.Match(bean) {
+			continue
 		}
 
-		resp.Beans = append(resp.Beans, docToBean(doc))
+		resp.Beans = append(resp.Beans, bean)
+	}
+
+	if key := r.URL.Query().Get("sort"); key != "" {
+		if err := sortBeans(resp.Beans, key); err != nil {
+			h.writeError(w, r, validationError(err.Error()))
+			return
+		}
 	}
 
+	h.setVerifiedBadges(ctx, resp.Beans)
+
 	json.NewEncoder(w).Encode(resp)
 }
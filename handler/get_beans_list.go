@@ -1,12 +1,8 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
-	"log"
 	"net/http"
-
-	"google.golang.org/api/iterator"
 )
 
 func (h *Handler) getBeansList(w http.ResponseWriter, r *http.Request) {
@@ -15,35 +11,18 @@ func (h *Handler) getBeansList(w http.ResponseWriter, r *http.Request) {
 	)
 
 	// Call Firestore API
-	iter := h.database.Collection("beans").Documents(context.TODO())
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			log.Fatalf("Failed to iterate: %v", err)
-		}
-
-		name, err := doc.DataAt("name")
-		if err != nil {
-			h.logger.Error(err.Error())
-		}
-
-		roaster, err := doc.DataAt("roaster.name")
-		if err != nil {
-			h.logger.Error(err.Error())
-		}
-
-		slug, err := doc.DataAt("slug")
-		if err != nil {
-			h.logger.Error(err.Error())
-		}
-
+	docs, err := getAllDocs(r.Context(), h.database.Collection("beans").Query)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	for _, doc := range docs {
+		// A bean missing a field is listed with it empty
+		b := docToBean(doc)
 		bean := BeanSimple{
-			Name:    name.(string),
-			Roaster: roaster.(string),
-			Slug:    slug.(string),
+			Name:    b.Name,
+			Roaster: b.Roaster.Name,
+			Slug:    b.Slug,
 		}
 
 		resp.Beans = append(resp.Beans, bean)
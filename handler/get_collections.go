@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// getProfileCollections lists the user's collections
+func (h *Handler) getProfileCollections(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		resp      = &CollectionsResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	u, err := h.getUserByEmail(ctx, userEmail)
+	if err != nil {
+		h.writeError(w, r, validationError("invalid user"))
+		return
+	}
+
+	resp.Collections, err = h.getCollections(ctx, u)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// getProfileCollection fetches one of the user's collections
+func (h *Handler) getProfileCollection(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		vars      = mux.Vars(r)
+		slug      = vars["collection"]
+		resp      = &CollectionResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	u, err := h.getUserByEmail(ctx, userEmail)
+	if err != nil {
+		h.writeError(w, r, validationError("invalid user"))
+		return
+	}
+
+	resp.Collection, err = h.getCollection(ctx, u, slug)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// maxFeedSize caps the number of items returned by the feed
+const maxFeedSize = 100
+
+// firestoreInLimit is the maximum number of values in a firestore "in" filter
+const firestoreInLimit = 10
+
+// GetFeedResp is the response for the GET /feed endpoint
+type GetFeedResp struct {
+	Feed []Event `json:"feed"`
+}
+
+// getFeed returns new reviews by followed users and new beans from followed
+// roasters, newest first. Use ?before= with the created_at of the last item
+// to page through older activity.
+func (h *Handler) getFeed(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		resp      = &GetFeedResp{Feed: []Event{}}
+		userEmail = r.Header.Get("X-User-Email")
+		before    = time.Now()
+		limit     = 50
+	)
+
+	if v := r.URL.Query().Get("before"); v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			h.writeError(w, r, validationError("invalid before"))
+			return
+		}
+		before = t
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 1 {
+			h.writeError(w, r, validationError("invalid limit"))
+			return
+		}
+		if l < maxFeedSize {
+			limit = l
+		} else {
+			limit = maxFeedSize
+		}
+	}
+
+	users, roasters, err := h.getFollowing(ctx, userEmail)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	activity := h.database.Collection("activity")
+	var queries []firestore.Query
+	for _, chunk := range chunkStrings(users, firestoreInLimit) {
+		queries = append(queries, activity.
+			Where("type", "==", eventReviewAdded).
+			Where("username", "in", chunk).
+			Where("created_at", "<", before))
+	}
+	for _, chunk := range chunkStrings(roasters, firestoreInLimit) {
+		queries = append(queries, activity.
+			Where("type", "==", eventBeanAdded).
+			Where("roaster_slug", "in", chunk).
+			Where("created_at", "<", before))
+	}
+
+	for _, q := range queries {
+		docs, err := q.OrderBy("created_at", firestore.Desc).Limit(limit).Documents(ctx).GetAll()
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+		for _, doc := range docs {
+			var e Event
+			doc.DataTo(&e)
+			resp.Feed = append(resp.Feed, e)
+		}
+	}
+
+	sort.SliceStable(resp.Feed, func(i, j int) bool {
+		return resp.Feed[i].CreatedAt.After(resp.Feed[j].CreatedAt)
+	})
+	if len(resp.Feed) > limit {
+		resp.Feed = resp.Feed[:limit]
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// chunkStrings splits s into slices of at most n elements
+func chunkStrings(s []string, n int) [][]string {
+	var chunks [][]string
+	for len(s) > n {
+		chunks = append(chunks, s[:n])
+		s = s[n:]
+	}
+	if len(s) > 0 {
+		chunks = append(chunks, s)
+	}
+	return chunks
+}
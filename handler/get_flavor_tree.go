@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"cloud.google.com/go/firestore"
+)
+
+// FlavorTreeNode is a level of the flavor wheel with the number of beans
+// that have a flavor at or below it
+type FlavorTreeNode struct {
+	Name     string           `json:"name"`
+	Slug     string           `json:"slug"`
+	Count    int              `json:"count"`
+	Children []FlavorTreeNode `json:"children,omitempty"`
+}
+
+// FlavorTreeResp is the response for the GET /flavors/tree endpoint
+type FlavorTreeResp struct {
+	Tree         []FlavorTreeNode `json:"tree"`
+	Unclassified map[string]int   `json:"unclassified"`
+}
+
+// getFlavorTree returns the flavor wheel with bean counts rolled up at every level
+func (h *Handler) getFlavorTree(w http.ResponseWriter, r *http.Request) {
+	var (
+		resp = &FlavorTreeResp{}
+		ctx  = r.Context()
+	)
+
+	beans, err := h.database.Collection("beans").Documents(ctx).GetAll()
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	counts, unclassified := flavorTreeCounts(beans)
+	resp.Tree = buildFlavorTree(flavorWheel, counts)
+	resp.Unclassified = unclassified
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// flavorTreeCounts counts the beans under each node. A bean is only counted
+// once per node, even if several of its flavors fall under it.
+func flavorTreeCounts(beans []*firestore.DocumentSnapshot) (map[*FlavorNode]int, map[string]int) {
+	var (
+		counts       = make(map[*FlavorNode]int)
+		unclassified = make(map[string]int)
+	)
+
+	for _, doc := range beans {
+		nodes := make(map[*FlavorNode]bool)
+		for _, flavor := range docToBean(doc).Flavors {
+			node := classifyFlavor(flavor)
+			if node == nil {
+				if f := cleanFlavor(flavor); f != "" {
+					unclassified[f]++
+				}
+				continue
+			}
+			for _, n := range node.Path() {
+				nodes[n] = true
+			}
+		}
+		for n := range nodes {
+			counts[n]++
+		}
+	}
+
+	return counts, unclassified
+}
+
+func buildFlavorTree(nodes []*FlavorNode, counts map[*FlavorNode]int) []FlavorTreeNode {
+	var tree []FlavorTreeNode
+	for _, n := range nodes {
+		tree = append(tree, FlavorTreeNode{
+			Name:     n.Name,
+			Slug:     n.Slug,
+			Count:    counts[n],
+			Children: buildFlavorTree(n.Children, counts),
+		})
+	}
+	return tree
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+)
+
+// getMapRoasters returns roasters as GeoJSON for maps, clustered by the
+// zoom level and narrowed down by the same filters as GET /beans
+func (h *Handler) getMapRoasters(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		q    = r.URL.Query()
+		zoom = -1
+	)
+
+	filter, err := parseBeanFilter(q)
+	if err != nil {
+		h.writeError(w, r, validationError(err.Error()))
+		return
+	}
+	rates := h.exchangeRates()
+	if filter.Currency == "" {
+		filter.Currency = strings.ToUpper(h.cfg.BaseCurrency)
+	}
+	if _, ok := rates[filter.Currency]; !ok {
+		h.writeError(w, r, errUnknownCurrency)
+		return
+	}
+
+	if z := q.Get("zoom"); z != "" {
+		zoom, err = strconv.Atoi(z)
+		if err != nil || zoom < 0 || zoom > 22 {
+			h.writeError(w, r, validationError("zoom must be between 0 and 22"))
+			return
+		}
+	}
+
+	var roasterDocs []*firestore.DocumentSnapshot
+	if bbox := q.Get("bbox"); bbox != "" {
+		b, err := parseBBox(bbox)
+		if err != nil {
+			h.writeError(w, r, validationError(err.Error()))
+			return
+		}
+		roasterDocs, err = h.roastersInBBox(ctx, b)
+	} else {
+		roasterDocs, err = h.database.Collection("roasters").Documents(ctx).GetAll()
+	}
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	// Count the matching beans of each roaster
+	beanDocs, err := h.database.Collection("beans").Documents(ctx).GetAll()
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	counts := make(map[string]int)
+	for _, doc := range beanDocs {
+		bean := docToBean(doc)
+		bean.PricePer100g = lowestPricePer100g(bean, rates, filter.Currency)
+		if filter.Match(bean) {
+			counts[bean.Roaster.Slug]++
+		}
+	}
+
+	var points []RoasterPoint
+	for _, doc := range roasterDocs {
+		roaster := docToRoaster(doc)
+		if roaster.Location == nil {
+			continue
+		}
+		// With filters, only roasters that sell a matching bean are shown
+		if !filter.IsZero() && counts[roaster.Slug] == 0 {
+			continue
+		}
+		points = append(points, RoasterPoint{Roaster: roaster, BeanCount: counts[roaster.Slug]})
+	}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+
+	json.NewEncoder(w).Encode(roasterFeatures(points, zoom))
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/lib/pq"
+)
+
+// ReportedReview is a review hidden after too many reports
+type ReportedReview struct {
+	Review
+	ReportCount int      `json:"report_count"`
+	Reasons     []string `json:"reasons"`
+}
+
+// ModerationQueueResp is the response for the GET /moderation/queue endpoint
+type ModerationQueueResp struct {
+	Submissions []Submission     `json:"submissions"`
+	Reviews     []ReportedReview `json:"reviews"`
+	Claims      []Claim          `json:"claims"`
+}
+
+// getModerationQueue lists pending submissions, roaster claims and hidden reviews
+func (h *Handler) getModerationQueue(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		resp      = &ModerationQueueResp{Submissions: []Submission{}, Reviews: []ReportedReview{}, Claims: []Claim{}}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	if !h.isModerator(userEmail) {
+		h.writeError(w, r, forbiddenError("only moderators can view the queue"))
+		return
+	}
+
+	// Pending bean and roaster submissions, oldest first
+	docs, err := h.database.Collection("submissions").
+		Where("status", "==", submissionPending).
+		OrderBy("submitted_at", firestore.Asc).
+		Documents(ctx).
+		GetAll()
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	for _, doc := range docs {
+		s := docToSubmission(doc)
+		s.Changes = h.submissionChanges(ctx, s)
+		resp.Submissions = append(resp.Submissions, s)
+	}
+
+	// Pending roaster claims
+	docs, err = h.database.Collection("claims").
+		Where("status", "==", claimPending).
+		OrderBy("created_at", firestore.Asc).
+		Documents(ctx).
+		GetAll()
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	for _, doc := range docs {
+		resp.Claims = append(resp.Claims, docToClaim(doc))
+	}
+
+	// Reviews hidden by reports
+	if h.cfg.ReviewsEnabled {
+		rows, err := h.postgres.QueryContext(ctx, `
+		SELECT
+			r.review_id,
+			r.rating,
+			r.review,
+			r.bean_ref,
+			r.updated_at,
+			r.report_count,
+			u.username as user,
+			array_agg(rr.reason ORDER BY rr.created_at) as reasons
+		FROM reviews r
+		LEFT JOIN users u on r.user_id = u.user_id
+		LEFT JOIN review_reports rr on r.review_id = rr.review_id
+		WHERE r.hidden
+		GROUP BY r.review_id, u.username
+		ORDER BY r.updated_at;
+	`)
+		if err != nil {
+			h.logger.Error(err)
+			h.writeError(w, r, err)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var review ReportedReview
+			var updatedAt time.Time
+			err = rows.Scan(
+				&review.ID,
+				&review.Rating,
+				&review.Review,
+				&review.Bean,
+				&updatedAt,
+				&review.ReportCount,
+				&review.User,
+				pq.Array(&review.Reasons),
+			)
+			if err != nil {
+				h.logger.Error(err)
+				continue
+			}
+			review.UpdatedAt = updatedAt
+			resp.Reviews = append(resp.Reviews, review)
+		}
+		if err = rows.Err(); err != nil {
+			h.logger.Error(err)
+		}
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
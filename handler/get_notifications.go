@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"cloud.google.com/go/firestore"
+)
+
+// GetNotificationsResp is the response for the GET /profile/notifications endpoint
+type GetNotificationsResp struct {
+	Notifications []Notification `json:"notifications"`
+}
+
+// getNotifications lists the latest notifications for the user
+func (h *Handler) getNotifications(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		resp      = &GetNotificationsResp{Notifications: []Notification{}}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	docs, err := h.database.Collection("notifications").
+		Where("user", "==", userEmail).
+		OrderBy("created_at", firestore.Desc).
+		Limit(50).
+		Documents(ctx).
+		GetAll()
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	for _, doc := range docs {
+		var n Notification
+		doc.DataTo(&n)
+		resp.Notifications = append(resp.Notifications, n)
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// RegionCount is the number of beans from a region
+type RegionCount struct {
+	Region string `json:"region"`
+	Count  int    `json:"count"`
+}
+
+// OriginCount is the number of beans from a country, broken down by region
+type OriginCount struct {
+	Country string        `json:"country"`
+	Count   int           `json:"count"`
+	Regions []RegionCount `json:"regions"`
+}
+
+// OriginsResp is the response for the GET /origins endpoint
+type OriginsResp struct {
+	Origins []OriginCount `json:"origins"`
+}
+
+// getOrigins returns the number of beans per country and region
+func (h *Handler) getOrigins(w http.ResponseWriter, r *http.Request) {
+	var (
+		resp  = &OriginsResp{}
+		ctx   = r.Context()
+		beans []Bean
+	)
+
+	docs, err := h.database.Collection("beans").Documents(ctx).GetAll()
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	for _, doc := range docs {
+		beans = append(beans, docToBean(doc))
+	}
+
+	resp.Origins = countOrigins(beans)
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// countOrigins groups beans by country and region, ignoring case. A region is
+// only attributed when a bean comes from a single country, since blends
+// don't say which country the region belongs to.
+func countOrigins(beans []Bean) []OriginCount {
+	var (
+		origins []*OriginCount
+		byKey   = make(map[string]*OriginCount)
+		regions = make(map[string]map[string]*RegionCount)
+	)
+
+	for _, b := range beans {
+		for _, c := range b.Countries {
+			c = strings.TrimSpace(c)
+			if c == "" {
+				continue
+			}
+			key := strings.ToLower(c)
+			o, ok := byKey[key]
+			if !ok {
+				o = &OriginCount{Country: c}
+				byKey[key] = o
+				regions[key] = make(map[string]*RegionCount)
+				origins = append(origins, o)
+			}
+			o.Count++
+
+			if b.Region == "" || len(b.Countries) != 1 {
+				continue
+			}
+			rkey := strings.ToLower(b.Region)
+			rc, ok := regions[key][rkey]
+			if !ok {
+				rc = &RegionCount{Region: b.Region}
+				regions[key][rkey] = rc
+			}
+			rc.Count++
+		}
+	}
+
+	var resp = []OriginCount{}
+	for _, o := range origins {
+		key := strings.ToLower(o.Country)
+		o.Regions = []RegionCount{}
+		for _, rc := range regions[key] {
+			o.Regions = append(o.Regions, *rc)
+		}
+		sort.Slice(o.Regions, func(i, j int) bool {
+			if o.Regions[i].Count != o.Regions[j].Count {
+				return o.Regions[i].Count > o.Regions[j].Count
+			}
+			return o.Regions[i].Region < o.Regions[j].Region
+		})
+		resp = append(resp, *o)
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		if resp[i].Count != resp[j].Count {
+			return resp[i].Count > resp[j].Count
+		}
+		return resp[i].Country < resp[j].Country
+	})
+
+	return resp
+}
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 )
@@ -14,7 +13,7 @@ type GetProfileResp struct {
 // TODO: Add better security
 func (h *Handler) getProfile(w http.ResponseWriter, r *http.Request) {
 	var (
-		ctx       = context.TODO()
+		ctx       = r.Context()
 		resp      = &GetProfileResp{}
 		userEmail = r.Header.Get("X-User-Email")
 	)
@@ -27,7 +26,7 @@ func (h *Handler) getProfile(w http.ResponseWriter, r *http.Request) {
 		doc, err := iter.Next()
 
 		if doc == nil {
-			http.Error(w, "user not found", http.StatusNotFound)
+			h.writeError(w, r, errUserNotFound)
 			return
 		}
 
@@ -36,7 +35,7 @@ func (h *Handler) getProfile(w http.ResponseWriter, r *http.Request) {
 		resp.User = u
 
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.writeError(w, r, err)
 			return
 		}
 
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// maxSimilarityScore is the highest score similarityScore can return
+const maxSimilarityScore = flavorWeight + countryWeight + shadeWeight + proximityWeight
+
+// getRecommendations recommends beans to the user. Beans similar to the ones
+// the user rated highly are combined with collaborative filtering over other
+// users' ratings. Users without ratings get popular beans instead.
+func (h *Handler) getRecommendations(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		resp      = &RecommendationsResp{Beans: []Recommendation{}}
+		userEmail = r.Header.Get("X-User-Email")
+		ratings   []rating
+		userID    = -1
+	)
+
+	limit, err := recommendationLimit(r)
+	if err != nil {
+		h.writeError(w, r, validationError(err.Error()))
+		return
+	}
+
+	beanDocs, err := h.database.Collection("beans").Documents(ctx).GetAll()
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	beans := make(map[string]Bean)
+	for _, doc := range beanDocs {
+		beans[doc.Ref.ID] = docToBean(doc)
+	}
+
+	if h.cfg.ReviewsEnabled {
+		ratings, err = h.getRatings(ctx)
+		if err != nil {
+			h.logger.Error(err)
+		}
+		if u, err := h.getUserByEmail(ctx, userEmail); err == nil {
+			if id, err := h.getPostgresUserID(ctx, u.Username); err == nil {
+				userID = id
+			}
+		}
+	}
+
+	// Beans the user already rated, and the ones they liked
+	var (
+		rated = make(map[string]bool)
+		liked []string
+	)
+	for _, r := range ratings {
+		if r.UserID != userID {
+			continue
+		}
+		rated[r.BeanRef] = true
+		if r.Rating >= highRating {
+			liked = append(liked, r.BeanRef)
+		}
+	}
+
+	if len(rated) > 0 {
+		resp.Beans = h.personalRecommendations(ctx, beans, ratings, userID, rated, liked)
+	}
+
+	// Cold start
+	if len(resp.Beans) == 0 {
+		for ref, score := range popularityScores(ratings) {
+			bean, ok := beans[ref]
+			if !ok || rated[ref] {
+				continue
+			}
+			resp.Beans = append(resp.Beans, Recommendation{
+				Bean:   bean,
+				Score:  score / 5,
+				Reason: "Popular",
+			})
+		}
+	}
+
+	resp.Beans = topRecommendations(resp.Beans, limit)
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// personalRecommendations scores unrated beans for a user with ratings
+func (h *Handler) personalRecommendations(
+	ctx context.Context,
+	beans map[string]Bean,
+	ratings []rating,
+	userID int,
+	rated map[string]bool,
+	liked []string,
+) []Recommendation {
+	var recs []Recommendation
+
+	locations, err := h.roasterLocations(ctx)
+	if err != nil {
+		h.logger.Error(err)
+	}
+	predicted := collaborativeScores(ratings, userID)
+
+	for ref, bean := range beans {
+		if rated[ref] {
+			continue
+		}
+
+		// Closest match among the beans the user liked
+		var (
+			content float64
+			reason  string
+		)
+		for _, likedRef := range liked {
+			likedBean, ok := beans[likedRef]
+			if !ok {
+				continue
+			}
+			s := similarityScore(likedBean, bean, roasterDistance(locations, likedBean, bean)) / maxSimilarityScore
+			if s > content {
+				content = s
+				reason = "Similar to " + likedBean.Name
+			}
+		}
+
+		score := content
+		if p, ok := predicted[ref]; ok {
+			collaborative := p / 5
+			score = 0.6*collaborative + 0.4*content
+			if collaborative >= content {
+				reason = "Liked by people with similar taste"
+			}
+		}
+		if score <= 0 {
+			continue
+		}
+
+		recs = append(recs, Recommendation{Bean: bean, Score: score, Reason: reason})
+	}
+
+	return recs
+}
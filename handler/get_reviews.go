@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 	"time"
@@ -17,7 +16,7 @@ type GetReviewsResp struct {
 func (h *Handler) getReviews(w http.ResponseWriter, r *http.Request) {
 	var (
 		resp     = &GetReviewsResp{}
-		ctx      = context.TODO()
+		ctx      = r.Context()
 		beanDocs = []*firestore.DocumentRef{}
 		reviews  []ReviewWithBean
 		beans    = h.database.Collection("beans")
@@ -26,16 +25,20 @@ func (h *Handler) getReviews(w http.ResponseWriter, r *http.Request) {
 	if h.cfg.ReviewsEnabled {
 
 		// Call Postgres
-		rows, err := h.postgres.Query(`
+		rows, err := h.postgres.QueryContext(ctx, `
 		SELECT
 			r.review_id,
 			r.rating,
 			r.review,
 			r.bean_ref,
 			r.updated_at,
+			r.helpful_count,
+			r.not_helpful_count,
 			u.username as user
 		FROM reviews r
-		LEFT JOIN users u on r.user_id = u.user_id;
+		LEFT JOIN users u on r.user_id = u.user_id
+		WHERE NOT r.hidden
+		ORDER BY r.helpful_count - r.not_helpful_count DESC, r.updated_at DESC;
 	`)
 		if err != nil {
 			h.logger.Error(err)
@@ -47,8 +50,10 @@ func (h *Handler) getReviews(w http.ResponseWriter, r *http.Request) {
 			var review string
 			var beanRef string
 			var updatedAt time.Time
+			var helpfulCount int
+			var notHelpfulCount int
 			var user string
-			err = rows.Scan(&reviewId, &rating, &review, &beanRef, &updatedAt, &user)
+			err = rows.Scan(&reviewId, &rating, &review, &beanRef, &updatedAt, &helpfulCount, &notHelpfulCount, &user)
 			if err != nil {
 				h.logger.Error(err)
 			}
@@ -63,10 +68,13 @@ func (h *Handler) getReviews(w http.ResponseWriter, r *http.Request) {
 			)
 
 			reviews = append(reviews, ReviewWithBean{
-				Review:    review,
-				Rating:    rating,
-				UpdatedAt: updatedAt,
-				User:      user,
+				ID:              reviewId,
+				Review:          review,
+				Rating:          rating,
+				UpdatedAt:       updatedAt,
+				User:            user,
+				HelpfulCount:    helpfulCount,
+				NotHelpfulCount: notHelpfulCount,
 			})
 			beanDocs = append(beanDocs, beans.Doc(beanRef))
 		}
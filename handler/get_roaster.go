@@ -1,9 +1,9 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -13,27 +13,16 @@ func (h *Handler) getRoaster(w http.ResponseWriter, r *http.Request) {
 		resp = &RoasterResp{}
 		vars = mux.Vars(r)
 		slug = vars["slug"]
-		ctx  = context.TODO()
+		ctx  = r.Context()
 	)
 
 	// Get the roaster
-	roasterIter := h.database.Collection("roasters").Where("slug", "==", slug).Documents(ctx)
-	for {
-		doc, err := roasterIter.Next()
-		if doc == nil {
-			http.Error(w, "invalid roaster", http.StatusBadRequest)
-			return
-		}
-
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-
-		resp.Roaster = docToRoaster(doc)
-
-		break
+	doc, err := h.getRoasterDocBySlug(ctx, slug)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
 	}
+	resp.Roaster = docToRoaster(doc)
 
 	// Get the beans for that roaster
 	beansIter := h.database.Collection("beans").Where("roaster.slug", "==", resp.Roaster.Slug).Documents(ctx)
@@ -44,12 +33,16 @@ func (h *Handler) getRoaster(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.writeError(w, r, err)
 			return
 		}
 
-		resp.Beans = append(resp.Beans, docToBean(doc))
+		bean := docToBean(doc)
+		bean.Roaster.Verified = resp.Roaster.Verified
+		resp.Beans = append(resp.Beans, bean)
 	}
 
+	resp.RoastSummary = summarizeRoasts(resp.Beans, time.Now())
+
 	json.NewEncoder(w).Encode(resp)
 }
@@ -1,34 +1,58 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
-	"log"
 	"net/http"
-
-	"google.golang.org/api/iterator"
 )
 
 func (h *Handler) getRoasters(w http.ResponseWriter, r *http.Request) {
 	var (
 		resp = &RoastersResp{}
+		ctx  = r.Context()
 	)
 
-	// Call Firestore API
-	iter := h.database.Collection("roasters").Documents(context.TODO())
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
+	filter, err := parseRoasterFilter(r.URL.Query())
+	if err != nil {
+		h.writeError(w, r, validationError(err.Error()))
+		return
+	}
+
+	// Map viewports only read the roasters inside the box
+	if bbox := r.URL.Query().Get("bbox"); bbox != "" {
+		b, err := parseBBox(bbox)
+		if err != nil {
+			h.writeError(w, r, validationError(err.Error()))
+			return
 		}
+		docs, err := h.roastersInBBox(ctx, b)
 		if err != nil {
-			log.Fatalf("Failed to iterate: %v", err)
+			h.writeError(w, r, err)
+			return
+		}
+		for _, doc := range docs {
+			if roaster := docToRoaster(doc); filter.Match(roaster) {
+				resp.Roasters = append(resp.Roasters, roaster)
+			}
 		}
 
-		var r Roaster
-		doc.DataTo(&r)
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
 
-		resp.Roasters = append(resp.Roasters, r)
+	// Call Firestore API
+	query := h.database.Collection("roasters").Query
+	if filter.ShipsTo != "" {
+		query = query.Where("ships_to", "array-contains", filter.ShipsTo)
+	}
+	docs, err := getAllDocs(ctx, query)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	for _, doc := range docs {
+		if roaster := docToRoaster(doc); filter.Match(roaster) {
+			resp.Roasters = append(resp.Roasters, roaster)
+		}
 	}
 
 	json.NewEncoder(w).Encode(resp)
@@ -1,12 +1,8 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
-	"log"
 	"net/http"
-
-	"google.golang.org/api/iterator"
 )
 
 func (h *Handler) getRoastersList(w http.ResponseWriter, r *http.Request) {
@@ -15,20 +11,19 @@ func (h *Handler) getRoastersList(w http.ResponseWriter, r *http.Request) {
 	)
 
 	// Call Firestore API
-	iter := h.database.Collection("roasters").Documents(context.TODO())
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			log.Fatalf("Failed to iterate: %v", err)
-		}
-
-		var r RoasterMap
-		doc.DataTo(&r)
+	docs, err := getAllDocs(r.Context(), h.database.Collection("roasters").Query)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	for _, doc := range docs {
+		r := docToRoaster(doc)
 
-		resp.Roasters = append(resp.Roasters, r)
+		resp.Roasters = append(resp.Roasters, RoasterMap{
+			Name:     r.Name,
+			Slug:     r.Slug,
+			Verified: r.Verified,
+		})
 	}
 
 	json.NewEncoder(w).Encode(resp)
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// Limits of the radius of GET /roasters/near
+const (
+	defaultNearRadiusKm = 50.0
+	maxNearRadiusKm     = 1000.0
+)
+
+var errInvalidPoint = validationError("lat and lng must be valid coordinates")
+
+// RoasterDistance is a roaster and how far it is from a point
+type RoasterDistance struct {
+	Roaster
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// RoastersNearResp is the response for the GET /roasters/near endpoint
+type RoastersNearResp struct {
+	Roasters []RoasterDistance `json:"roasters"`
+}
+
+// getRoastersNear returns the roasters within a radius of a point, closest first
+func (h *Handler) getRoastersNear(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx    = r.Context()
+		q      = r.URL.Query()
+		resp   = &RoastersNearResp{Roasters: []RoasterDistance{}}
+		radius = defaultNearRadiusKm
+	)
+
+	lat, err := strconv.ParseFloat(q.Get("lat"), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		h.writeError(w, r, errInvalidPoint)
+		return
+	}
+	lng, err := strconv.ParseFloat(q.Get("lng"), 64)
+	if err != nil || lng < -180 || lng > 180 {
+		h.writeError(w, r, errInvalidPoint)
+		return
+	}
+	if s := q.Get("radius_km"); s != "" {
+		radius, err = strconv.ParseFloat(s, 64)
+		if err != nil || radius <= 0 || radius > maxNearRadiusKm {
+			h.writeError(w, r, validationError("radius_km must be between 0 and 1000"))
+			return
+		}
+	}
+
+	docs, err := h.roastersInBBox(ctx, radiusBBox(lat, lng, radius))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	for _, doc := range docs {
+		roaster := docToRoaster(doc)
+		d := haversineKm(lat, lng, roaster.Location.Latitude, roaster.Location.Longitude)
+		if d > radius {
+			continue
+		}
+		resp.Roasters = append(resp.Roasters, RoasterDistance{Roaster: roaster, DistanceKm: d})
+	}
+	sort.Slice(resp.Roasters, func(i, j int) bool {
+		return resp.Roasters[i].DistanceKm < resp.Roasters[j].DistanceKm
+	})
+
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// maxRecommendations caps the number of beans returned by the recommendation endpoints
+const maxRecommendations = 50
+
+var errInvalidLimit = validationError("invalid limit")
+
+// getSimilarBeans ranks other beans by how similar they are to a bean
+func (h *Handler) getSimilarBeans(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		vars = mux.Vars(r)
+		slug = vars["slug"]
+		resp = &RecommendationsResp{Beans: []Recommendation{}}
+	)
+
+	limit, err := recommendationLimit(r)
+	if err != nil {
+		h.writeError(w, r, validationError(err.Error()))
+		return
+	}
+
+	beanDoc, err := h.getBeanDocBySlug(ctx, slug)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	bean := docToBean(beanDoc)
+
+	beans, err := h.database.Collection("beans").Documents(ctx).GetAll()
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	locations, err := h.roasterLocations(ctx)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	for _, doc := range beans {
+		if doc.Ref.ID == beanDoc.Ref.ID {
+			continue
+		}
+		other := docToBean(doc)
+		score := similarityScore(bean, other, roasterDistance(locations, bean, other))
+		if score <= 0 {
+			continue
+		}
+		resp.Beans = append(resp.Beans, Recommendation{
+			Bean:   other,
+			Score:  score,
+			Reason: "Similar to " + bean.Name,
+		})
+	}
+
+	resp.Beans = topRecommendations(resp.Beans, limit)
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// recommendationLimit parses the ?limit= query param
+func recommendationLimit(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return 10, nil
+	}
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit < 1 {
+		return 0, errInvalidLimit
+	}
+	if limit > maxRecommendations {
+		limit = maxRecommendations
+	}
+	return limit, nil
+}
+
+// roasterLocations maps roaster slugs to their locations
+func (h *Handler) roasterLocations(ctx context.Context) (map[string]*latlng.LatLng, error) {
+	var locations = make(map[string]*latlng.LatLng)
+
+	roasters, err := h.database.Collection("roasters").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range roasters {
+		r := docToRoaster(doc)
+		if r.Location != nil {
+			locations[r.Slug] = r.Location
+		}
+	}
+
+	return locations, nil
+}
+
+// roasterDistance returns the distance between the roasters of two beans,
+// or -1 if either location is unknown
+func roasterDistance(locations map[string]*latlng.LatLng, a, b Bean) float64 {
+	la, okA := locations[a.Roaster.Slug]
+	lb, okB := locations[b.Roaster.Slug]
+	if !okA || !okB {
+		return -1
+	}
+	return haversineKm(la.Latitude, la.Longitude, lb.Latitude, lb.Longitude)
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"cloud.google.com/go/firestore"
+	"github.com/gorilla/mux"
+)
+
+// GetSuggestionsResp is the response for the GET /{beans,roasters}/{slug}/suggestions endpoints
+type GetSuggestionsResp struct {
+	Suggestions []Suggestion `json:"suggestions"`
+}
+
+// getBeanSuggestions lists open suggestions for a bean
+func (h *Handler) getBeanSuggestions(w http.ResponseWriter, r *http.Request) {
+	h.getSuggestions(w, r, "bean")
+}
+
+// getRoasterSuggestions lists open suggestions for a roaster
+func (h *Handler) getRoasterSuggestions(w http.ResponseWriter, r *http.Request) {
+	h.getSuggestions(w, r, "roaster")
+}
+
+func (h *Handler) getSuggestions(w http.ResponseWriter, r *http.Request, kind string) {
+	var (
+		ctx  = r.Context()
+		vars = mux.Vars(r)
+		slug = vars["slug"]
+		resp = &GetSuggestionsResp{Suggestions: []Suggestion{}}
+	)
+
+	docs, err := h.database.Collection("suggestions").
+		Where("kind", "==", kind).
+		Where("slug", "==", slug).
+		Where("status", "==", suggestionOpen).
+		OrderBy("suggested_at", firestore.Asc).
+		Documents(ctx).
+		GetAll()
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	for _, doc := range docs {
+		s := docToSuggestion(doc)
+		if _, current, proposed, err := h.suggestionTarget(ctx, s); err == nil {
+			s.Changes = diffFields(current, proposed)
+		}
+		resp.Suggestions = append(resp.Suggestions, s)
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 
@@ -11,7 +10,7 @@ import (
 // getUser fetches public user information
 func (h *Handler) getUser(w http.ResponseWriter, r *http.Request) {
 	var (
-		ctx       = context.TODO()
+		ctx       = r.Context()
 		vars      = mux.Vars(r)
 		username  = vars["username"]
 		resp      = &UserResp{}
@@ -30,12 +29,12 @@ func (h *Handler) getUser(w http.ResponseWriter, r *http.Request) {
 		doc, err := iter.Next()
 
 		if doc == nil {
-			http.Error(w, "user not found", http.StatusNotFound)
+			h.writeError(w, r, errUserNotFound)
 			return
 		}
 
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.writeError(w, r, err)
 			return
 		}
 
@@ -43,6 +42,27 @@ func (h *Handler) getUser(w http.ResponseWriter, r *http.Request) {
 		doc.DataTo(&u)
 		resp.User = u
 
+		// Only public collections are shown to other users
+		var private UserDB
+		doc.DataTo(&private)
+		collections, err := h.getCollections(ctx, private)
+		if err != nil {
+			h.logger.Error(err)
+		}
+		resp.Collections = []Collection{}
+		for _, c := range collections {
+			if c.Visibility == visibilityPublic {
+				resp.Collections = append(resp.Collections, c)
+			}
+		}
+
+		reputation, err := h.reputation(ctx, private)
+		if err != nil {
+			h.logger.Errorw("Error computing reputation", "username", username, "error", err)
+		} else {
+			resp.Reputation = &reputation
+		}
+
 		break
 	}
 
@@ -1,12 +1,9 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 	"strings"
-
-	"google.golang.org/api/iterator"
 )
 
 type GlobalSearchReq struct {
@@ -36,7 +33,7 @@ type GlobalSearchBean struct {
 // globalSearch initializes the profile for the user.
 func (h *Handler) globalSearch(w http.ResponseWriter, r *http.Request) {
 	var (
-		ctx       = context.TODO()
+		ctx       = r.Context()
 		err       error
 		query     string
 		userEmail = r.Header.Get("X-User-Email")
@@ -46,7 +43,7 @@ func (h *Handler) globalSearch(w http.ResponseWriter, r *http.Request) {
 
 	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		http.Error(w, "error handling request", http.StatusBadRequest)
+		h.writeError(w, r, validationError("error handling request"))
 		return
 	}
 
@@ -55,16 +52,12 @@ func (h *Handler) globalSearch(w http.ResponseWriter, r *http.Request) {
 	h.logger.Infof("New global search request from %s for %s", userEmail, query)
 
 	// Fetch all the roasters
-	roasterIter := h.database.Collection("roasters").Documents(ctx)
-	for {
-		doc, err := roasterIter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			h.logger.Fatalf("Failed to iterate: %v", err)
-		}
-
+	roasterDocs, err := getAllDocs(ctx, h.database.Collection("roasters").Query)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	for _, doc := range roasterDocs {
 		r := docToRoaster(doc)
 
 		// Check if the search query is a substring of a slug or a "spaced" version
@@ -83,16 +76,12 @@ func (h *Handler) globalSearch(w http.ResponseWriter, r *http.Request) {
 
 	// Fetch all the beans
 	if req.Only != "roaster" {
-		beansIter := h.database.Collection("beans").Documents(ctx)
-		for {
-			doc, err := beansIter.Next()
-			if err == iterator.Done {
-				break
-			}
-			if err != nil {
-				h.logger.Fatalf("Failed to iterate: %v", err)
-			}
-
+		beanDocs, err := getAllDocs(ctx, h.database.Collection("beans").Query)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+		for _, doc := range beanDocs {
 			b := docToBean(doc)
 
 			// Check if the search query is a substring of a slug or a "spaced" version
@@ -112,9 +101,10 @@ func (h *Handler) globalSearch(w http.ResponseWriter, r *http.Request) {
 				})
 			}
 
-			// Check if the search query matches any of the bean flavors
+			// Check if the search query matches any of the bean flavors, or a
+			// category of the flavor wheel they belong to
 			for _, f := range b.Flavors {
-				if f == query {
+				if f == query || flavorMatches(f, query) {
 					resp.Results = append(resp.Results, GlobalSearchResult{
 						Roaster: GlobalSearchRoaster{
 							Name: b.Roaster.Name,
@@ -126,6 +116,7 @@ func (h *Handler) globalSearch(w http.ResponseWriter, r *http.Request) {
 							Flavors: b.Flavors,
 						},
 					})
+					break
 				}
 			}
 		}
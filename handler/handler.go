@@ -14,54 +14,136 @@ import (
 
 // Handler for http requests
 type Handler struct {
-	bq       *bigquery.Client
-	cfg      config.Config
-	database *firestore.Client
-	discord  *discordgo.Session
-	events   *pubsub.Client
-	logger   *zap.SugaredLogger
-	postgres *sql.DB
-	router   *mux.Router
+	bq          *bigquery.Client
+	cfg         config.Config
+	database    *firestore.Client
+	discord     *discordgo.Session
+	events      *pubsub.Client
+	logger      *zap.SugaredLogger
+	postgres    *sql.DB
+	router      *mux.Router
+	fetcher     Fetcher
+	geocoder    Geocoder
+	stats       *statsCache
+	analytics   AnalyticsStore
+	reputations *reputationCache
+	sideEffects *sideEffects
+	topic       *pubsub.Topic
 }
 
-// ErrorMessage is a custom error message
+// ErrorMessage is the body of every error response
 type ErrorMessage struct {
-	Message string `json:"message"`
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// ErrorResp is the response for a failed request
+type ErrorResp struct {
+	Error ErrorMessage `json:"error"`
 }
 
 // RegisterRoutes for all http endpoints
 func (h *Handler) registerRoutes() {
+	h.router.Use(h.recoverMiddleware, h.timeoutMiddleware)
+
+	// Metrics
+	h.router.HandleFunc("/debug/vars", h.getMetrics).Methods("GET")
+
 	// Stats
 	// TODO: Cache
 	h.router.HandleFunc("/ip", h.getIP).Methods("GET")
 	h.router.HandleFunc("/stats", h.getStats).Methods("GET")
 	h.router.HandleFunc("/flavors", h.getFlavors).Methods("GET")
+	h.router.HandleFunc("/flavors/tree", h.getFlavorTree).Methods("GET")
+	h.router.HandleFunc("/origins", h.getOrigins).Methods("GET")
 
 	// Beans
 	h.router.HandleFunc("/beans", h.getBeans).Methods("GET")
 	h.router.HandleFunc("/beans", h.addBean).Methods("POST")
 	h.router.HandleFunc("/beans/{slug}", h.getBean).Methods("GET")
 	h.router.HandleFunc("/beans/{slug}", h.editBean).Methods("POST")
+	h.router.HandleFunc("/beans/{slug}/batches", h.addBatch).Methods("POST")
+	h.router.HandleFunc("/beans/{slug}/offerings", h.addOffering).Methods("POST")
+	h.router.HandleFunc("/beans/{slug}/offerings/{id}", h.editOffering).Methods("POST")
+	h.router.HandleFunc("/beans/{slug}/offerings/{id}", h.deleteOffering).Methods("DELETE")
+	h.router.HandleFunc("/beans/{slug}/similar", h.getSimilarBeans).Methods("GET")
+	h.router.HandleFunc("/beans/{slug}/suggestions", h.getBeanSuggestions).Methods("GET")
+	h.router.HandleFunc("/beans/{slug}/suggestions", h.addBeanSuggestion).Methods("POST")
 	h.router.HandleFunc("/beans_list", h.getBeansList).Methods("GET")
 
 	// Roasters
 	h.router.HandleFunc("/roasters", h.getRoasters).Methods("GET")
 	h.router.HandleFunc("/roasters", h.addRoaster).Methods("POST")
+	h.router.HandleFunc("/roasters/near", h.getRoastersNear).Methods("GET")
 	h.router.HandleFunc("/roasters/{slug}", h.getRoaster).Methods("GET")
 	h.router.HandleFunc("/roasters/{slug}", h.editRoaster).Methods("POST")
+	h.router.HandleFunc("/roasters/{slug}/claim", h.claimRoaster).Methods("POST")
+	h.router.HandleFunc("/roasters/{slug}/imports", h.previewRoasterImport).Methods("POST")
+	h.router.HandleFunc("/roasters/{slug}/imports/{id}/confirm", h.confirmRoasterImport).Methods("POST")
+	h.router.HandleFunc("/roasters/{slug}/follow", h.followRoaster).Methods("POST")
+	h.router.HandleFunc("/roasters/{slug}/follow", h.unfollowRoaster).Methods("DELETE")
+	h.router.HandleFunc("/roasters/{slug}/claim/verify", h.verifyRoasterClaim).Methods("POST")
+	h.router.HandleFunc("/roasters/{slug}/suggestions", h.getRoasterSuggestions).Methods("GET")
+	h.router.HandleFunc("/roasters/{slug}/suggestions", h.addRoasterSuggestion).Methods("POST")
 	h.router.HandleFunc("/roasters_list", h.getRoastersList).Methods("GET")
 
+	// Suggestions
+	h.router.HandleFunc("/suggestions/{id}/accept", h.acceptSuggestion).Methods("POST")
+	h.router.HandleFunc("/suggestions/{id}/decline", h.declineSuggestion).Methods("POST")
+
 	// Profile
 	h.router.HandleFunc("/check_username", h.checkUsername).Methods("GET").Queries("username", "{username}")
 	h.router.HandleFunc("/profile", h.getProfile).Methods("GET")
 	h.router.HandleFunc("/profile", h.addProfile).Methods("POST")
 	h.router.HandleFunc("/profile", h.editProfile).Methods("PATCH")
+	h.router.HandleFunc("/profile/notifications", h.getNotifications).Methods("GET")
+	h.router.HandleFunc("/profile/recommendations", h.getRecommendations).Methods("GET")
+	h.router.HandleFunc("/profile/collections", h.getProfileCollections).Methods("GET")
+	h.router.HandleFunc("/profile/collections", h.addProfileCollection).Methods("POST")
+	h.router.HandleFunc("/profile/collections/{collection}", h.getProfileCollection).Methods("GET")
+	h.router.HandleFunc("/profile/collections/{collection}", h.editProfileCollection).Methods("PATCH")
+	h.router.HandleFunc("/profile/collections/{collection}", h.deleteProfileCollection).Methods("DELETE")
+	h.router.HandleFunc("/profile/collections/{collection}/beans", h.addCollectionBean).Methods("POST")
+	h.router.HandleFunc("/profile/collections/{collection}/beans/{slug}", h.deleteCollectionBean).Methods("DELETE")
 
 	// Users
 	h.router.HandleFunc("/users/{username}", h.getUser).Methods("GET")
+	h.router.HandleFunc("/users/{username}/follow", h.followUser).Methods("POST")
+	h.router.HandleFunc("/users/{username}/follow", h.unfollowUser).Methods("DELETE")
+	h.router.HandleFunc("/feed", h.getFeed).Methods("GET")
 
 	// Reviews
 	h.router.HandleFunc("/reviews", h.getReviews).Methods("GET")
+	h.router.HandleFunc("/reviews", h.addReview).Methods("POST")
+	h.router.HandleFunc("/reviews/{id}/vote", h.voteReview).Methods("POST")
+	h.router.HandleFunc("/reviews/{id}/report", h.reportReview).Methods("POST")
+
+	// Moderation
+	h.router.HandleFunc("/moderation/queue", h.getModerationQueue).Methods("GET")
+	h.router.HandleFunc("/moderation/submissions/{id}/approve", h.approveSubmission).Methods("POST")
+	h.router.HandleFunc("/moderation/submissions/{id}/reject", h.rejectSubmission).Methods("POST")
+	h.router.HandleFunc("/moderation/reviews/{id}/approve", h.approveReview).Methods("POST")
+	h.router.HandleFunc("/moderation/reviews/{id}/reject", h.rejectReview).Methods("POST")
+	h.router.HandleFunc("/moderation/claims/{id}/approve", h.approveClaim).Methods("POST")
+	h.router.HandleFunc("/moderation/claims/{id}/reject", h.rejectClaim).Methods("POST")
+
+	// Admin
+	h.router.HandleFunc("/admin/flavors/merges", h.getFlavorMerges).Methods("GET")
+	h.router.HandleFunc("/admin/flavors/merges", h.mergeFlavors).Methods("POST")
+
+	// Map
+	h.router.HandleFunc("/map/roasters.geojson", h.getMapRoasters).Methods("GET")
+
+	// Catalogue
+	h.router.HandleFunc("/export/{entity:beans|roasters}.{format:csv|jsonl}", h.exportCatalogue).Methods("GET")
+	h.router.HandleFunc("/import", h.importCatalogue).Methods("POST")
+
+	// Analytics
+	h.router.HandleFunc("/analytics/contributors", h.getAnalyticsContributors).Methods("GET")
+	h.router.HandleFunc("/analytics/activity", h.getAnalyticsActivity).Methods("GET")
+	h.router.HandleFunc("/analytics/entities/{slug}", h.getAnalyticsEntity).Methods("GET")
 
 	// Search
 	h.router.HandleFunc("/search", h.globalSearch).Methods("POST")
@@ -78,7 +160,25 @@ func New(
 	postgres *sql.DB,
 	router *mux.Router,
 ) *Handler {
-	h := Handler{bq, cfg, database, discord, events, logger, postgres, router}
+	h := Handler{
+		bq:          bq,
+		cfg:         cfg,
+		database:    database,
+		discord:     discord,
+		events:      events,
+		logger:      logger,
+		postgres:    postgres,
+		router:      router,
+		fetcher:     newFetcher(),
+		geocoder:    newGeocoder(cfg.CitiesFile, logger),
+		stats:       &statsCache{},
+		analytics:   newAnalyticsStore(cfg.AnalyticsChangelogFile, bq, logger),
+		reputations: &reputationCache{entries: make(map[string]cachedReputation)},
+		sideEffects: newSideEffects(cfg.MaxSideEffects, cfg.MaxQueuedSideEffects),
+	}
+	if events != nil {
+		h.topic = events.Topic(cfg.EventsTopic)
+	}
 	h.registerRoutes()
 
 	return &h
@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Import statuses
+const (
+	importPending = "pending"
+	importApplied = "applied"
+)
+
+// Import item actions
+const (
+	importCreate    = "create"
+	importUpdate    = "update"
+	importUnchanged = "unchanged"
+	importInvalid   = "invalid"
+)
+
+// errImportNotFound is returned when an import doesn't exist
+var errImportNotFound = notFoundError("import not found")
+
+// Import is a preview of the beans a product feed would create or update
+type Import struct {
+	ID          string       `firestore:"-" json:"id"`
+	RoasterSlug string       `firestore:"roaster_slug" json:"roaster_slug"`
+	Source      string       `firestore:"source" json:"source"`
+	FeedURL     string       `firestore:"feed_url" json:"feed_url,omitempty"`
+	Status      string       `firestore:"status" json:"status"`
+	Items       []ImportItem `firestore:"items" json:"items"`
+	CreatedBy   string       `firestore:"created_by" json:"created_by"`
+	CreatedAt   time.Time    `firestore:"created_at" json:"created_at"`
+	AppliedAt   time.Time    `firestore:"applied_at" json:"applied_at,omitempty"`
+}
+
+// ImportItem is a bean candidate from a feed and what importing it would do.
+// Bean is the preview, Candidate what the feed has, which is merged again
+// with the stored bean when the import is applied.
+type ImportItem struct {
+	Action    string        `firestore:"action" json:"action"`
+	Slug      string        `firestore:"slug" json:"slug"`
+	Bean      Bean          `firestore:"bean" json:"bean"`
+	Candidate Bean          `firestore:"candidate" json:"-"`
+	Error     string        `firestore:"error" json:"error,omitempty"`
+	Changes   []FieldChange `firestore:"-" json:"changes,omitempty"`
+}
+
+func docToImport(doc *firestore.DocumentSnapshot) Import {
+	var i Import
+	doc.DataTo(&i)
+	i.ID = doc.Ref.ID
+	return i
+}
+
+// claimImport checks that an import is pending and marks it applied in one
+// transaction, so confirming twice doesn't apply it twice
+func (h *Handler) claimImport(ctx context.Context, id, slug, userEmail string) (Import, error) {
+	var (
+		ref = h.database.Collection("imports").Doc(id)
+		imp Import
+	)
+	err := h.database.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return errImportNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		imp = docToImport(doc)
+		if imp.RoasterSlug != slug {
+			return errImportNotFound
+		}
+		if imp.CreatedBy != userEmail && !h.isModerator(userEmail) {
+			return forbiddenError("only the user who previewed the import can confirm it")
+		}
+		if imp.Status != importPending {
+			return conflictError("import was already applied")
+		}
+
+		imp.Status = importApplied
+		imp.AppliedAt = time.Now()
+		return tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: imp.Status},
+			{Path: "applied_at", Value: imp.AppliedAt},
+		})
+	})
+	return imp, err
+}
+
+// applyImportedBean creates or updates a bean from a feed candidate. Updates
+// are merged with the bean as it is now rather than at preview, and the
+// offerings are merged in a transaction.
+func (h *Handler) applyImportedBean(ctx context.Context, action, slug string, candidate Bean, userEmail string) error {
+	var ref *firestore.DocumentRef
+
+	doc, err := h.getBeanDocBySlug(ctx, slug)
+	switch {
+	case err != nil && err != errBeanNotFound:
+		return err
+	case action == importCreate && err == nil:
+		return fmt.Errorf("bean already exists")
+	case action == importCreate:
+		bean := candidate
+		bean.Slug = slug
+		if ref, err = h.createBean(ctx, BeanReq{bean}, userEmail); err != nil {
+			return err
+		}
+	case action == importUpdate && err == nil:
+		bean := mergeImported(docToBean(doc), candidate)
+		bean.Slug = slug
+		if err = h.updateBean(ctx, doc.Ref, BeanReq{bean}, userEmail); err != nil {
+			return err
+		}
+		ref = doc.Ref
+	case action == importUpdate:
+		return err
+	default:
+		return fmt.Errorf("invalid import action %q", action)
+	}
+
+	if len(candidate.Offerings) == 0 {
+		return nil
+	}
+	return h.updateOfferings(ctx, ref, func(current []Offering) ([]Offering, error) {
+		return withOfferingIDs(mergeOfferings(current, candidate.Offerings))
+	}, userEmail)
+}
+
+// withOfferingIDs gives new offerings an ID
+func withOfferingIDs(offerings []Offering) ([]Offering, error) {
+	for i := range offerings {
+		if offerings[i].ID != "" {
+			continue
+		}
+		id, err := newOfferingID()
+		if err != nil {
+			return nil, err
+		}
+		offerings[i].ID = id
+	}
+	return offerings, nil
+}
+
+// mergeImported applies the fields a feed has to an existing bean, keeping
+// what the feed doesn't know about
+func mergeImported(current, candidate Bean) Bean {
+	merged := current
+
+	setString := func(field *string, value string) {
+		if value != "" {
+			*field = value
+		}
+	}
+	setString(&merged.Name, candidate.Name)
+	setString(&merged.Description, candidate.Description)
+	setString(&merged.Photo, candidate.Photo)
+	setString(&merged.URL, candidate.URL)
+	setString(&merged.Region, candidate.Region)
+	setString(&merged.Farm, candidate.Farm)
+	setString(&merged.Producer, candidate.Producer)
+	setString(&merged.Process, candidate.Process)
+
+	if len(candidate.Flavors) > 0 {
+		merged.Flavors = candidate.Flavors
+	}
+	if len(candidate.Countries) > 0 {
+		merged.Countries = candidate.Countries
+	}
+	if len(candidate.Varietals) > 0 {
+		merged.Varietals = candidate.Varietals
+	}
+	if candidate.Altitude != nil {
+		merged.Altitude = candidate.Altitude
+	}
+	if len(candidate.Offerings) > 0 {
+		merged.Offerings = mergeOfferings(current.Offerings, candidate.Offerings)
+	}
+
+	return merged
+}
+
+// mergeOfferings keeps the IDs and check times of offerings that are still
+// sold, so an unchanged feed doesn't show up as a change
+func mergeOfferings(current, candidates []Offering) []Offering {
+	var merged []Offering
+	for _, c := range candidates {
+		for _, o := range current {
+			if o.WeightGrams == c.WeightGrams && o.Grind == c.Grind && o.Subscription == c.Subscription {
+				c.ID = o.ID
+				c.CheckedAt = o.CheckedAt
+				break
+			}
+		}
+		merged = append(merged, c)
+	}
+	return merged
+}
+
+// buildImportItems compares bean candidates with the roaster's beans,
+// matching them by slug or name
+func buildImportItems(existing, candidates []Bean, rates ExchangeRates) []ImportItem {
+	var (
+		items  []ImportItem
+		bySlug = make(map[string]Bean)
+		byName = make(map[string]Bean)
+	)
+	for _, b := range existing {
+		bySlug[b.Slug] = b
+		byName[strings.ToLower(b.Name)] = b
+	}
+
+	for _, c := range candidates {
+		var offerings []Offering
+		for _, o := range c.Offerings {
+			if normalizeOffering(&o, rates) == nil {
+				offerings = append(offerings, o)
+			}
+		}
+		c.Offerings = offerings
+
+		current, ok := bySlug[c.Slug]
+		if !ok {
+			current, ok = byName[strings.ToLower(c.Name)]
+		}
+
+		item := ImportItem{Action: importCreate, Slug: c.Slug, Bean: c, Candidate: c}
+		if ok {
+			item.Slug = current.Slug
+			item.Bean = mergeImported(current, c)
+			item.Bean.Slug = current.Slug
+		}
+
+		if err := prepareBeanUpdate(&item.Bean, current); err != nil {
+			item.Action = importInvalid
+			item.Error = err.Error()
+			items = append(items, item)
+			continue
+		}
+
+		if ok {
+			item.Changes = diffFields(current, item.Bean)
+			item.Action = importUpdate
+			if len(item.Changes) == 0 {
+				item.Action = importUnchanged
+			}
+		} else {
+			item.Changes = diffFields(nil, item.Bean)
+		}
+
+		items = append(items, item)
+	}
+
+	return items
+}
@@ -0,0 +1,347 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+)
+
+// Outcomes of an imported row
+const (
+	rowCreated = "created"
+	rowUpdated = "updated"
+	rowSkipped = "skipped"
+	rowFailed  = "failed"
+)
+
+// CatalogueRowResult is what happened to a row of an import file
+type CatalogueRowResult struct {
+	Line    int           `json:"line"`
+	Slug    string        `json:"slug"`
+	Action  string        `json:"action"`
+	Error   string        `json:"error,omitempty"`
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+// CatalogueImportResp is the response from the POST /import endpoint
+type CatalogueImportResp struct {
+	DryRun  bool                 `json:"dry_run"`
+	Created int                  `json:"created"`
+	Updated int                  `json:"updated"`
+	Skipped int                  `json:"skipped"`
+	Failed  int                  `json:"failed"`
+	Rows    []CatalogueRowResult `json:"rows"`
+}
+
+// catalogueChange is a planned write for a row
+type catalogueChange struct {
+	Result  CatalogueRowResult
+	Bean    Bean
+	Roaster Roaster
+}
+
+// setBeanColumns copies the fields an import writes from a row to a bean
+func setBeanColumns(dst *Bean, src Bean) {
+	dst.Slug = src.Slug
+	dst.Name = src.Name
+	dst.Roaster = src.Roaster
+	dst.Description = src.Description
+	dst.Countries = src.Countries
+	dst.Region = src.Region
+	dst.Farm = src.Farm
+	dst.Producer = src.Producer
+	dst.Altitude = src.Altitude
+	dst.Varietals = src.Varietals
+	dst.Process = src.Process
+	dst.Flavors = src.Flavors
+	dst.Shade = src.Shade
+	dst.RoastLevel = src.RoastLevel
+	dst.Agtron = src.Agtron
+	dst.Year = src.Year
+	dst.Organic = src.Organic
+	dst.FairTrade = src.FairTrade
+	dst.DirectSun = src.DirectSun
+	dst.URL = src.URL
+	dst.Photo = src.Photo
+}
+
+// setRoasterColumns copies the fields an import writes from a row to a roaster
+func setRoasterColumns(dst *Roaster, src Roaster) {
+	dst.Slug = src.Slug
+	dst.Name = src.Name
+	dst.City = src.City
+	dst.Region = src.Region
+	dst.Country = src.Country
+	dst.Location = src.Location
+	dst.URL = src.URL
+	dst.Instagram = src.Instagram
+	dst.Twitter = src.Twitter
+	dst.Logo = src.Logo
+	dst.ShipsTo = src.ShipsTo
+	dst.Wholesale = src.Wholesale
+	dst.Founded = src.Founded
+}
+
+// planCatalogueImport validates rows and upserts them by slug against the
+// existing beans or roasters. Beans must belong to an existing roaster.
+func planCatalogueImport(rows []CatalogueRow, entity string, beans map[string]Bean, roasters map[string]Roaster) []catalogueChange {
+	var (
+		changes []catalogueChange
+		seen    = make(map[string]int)
+	)
+
+	for _, row := range rows {
+		c := catalogueChange{Result: CatalogueRowResult{Line: row.Line}}
+		fail := func(err error) {
+			c.Result.Action = rowFailed
+			c.Result.Error = err.Error()
+			changes = append(changes, c)
+		}
+
+		if row.Err != nil {
+			fail(row.Err)
+			continue
+		}
+
+		var (
+			current, proposed interface{}
+			exists            bool
+		)
+		if entity == "roasters" {
+			r := row.Roaster
+			c.Result.Slug = r.Slug
+			if err := validateRoasterRow(&r); err != nil {
+				fail(err)
+				continue
+			}
+			// Roasters are only verified through a claim
+			r.Verified = false
+			c.Roaster = r
+			if existing, ok := roasters[r.Slug]; ok {
+				exists = true
+				c.Roaster = existing
+				setRoasterColumns(&c.Roaster, r)
+				current, proposed = existing, c.Roaster
+			}
+		} else {
+			b := row.Bean
+			c.Result.Slug = b.Slug
+			if err := validateBeanRow(&b, beans[b.Slug]); err != nil {
+				fail(err)
+				continue
+			}
+			roaster, ok := roasters[b.Roaster.Slug]
+			if !ok {
+				fail(fmt.Errorf("unknown roaster %q", b.Roaster.Slug))
+				continue
+			}
+			b.Roaster = RoasterMap{Name: roaster.Name, Slug: roaster.Slug}
+			c.Bean = b
+			clearManagedFields(&c.Bean)
+			if existing, ok := beans[b.Slug]; ok {
+				exists = true
+				c.Bean = existing
+				setBeanColumns(&c.Bean, b)
+				current, proposed = existing, c.Bean
+			}
+		}
+
+		if line, ok := seen[c.Result.Slug]; ok {
+			fail(fmt.Errorf("duplicate of line %d", line))
+			continue
+		}
+		seen[c.Result.Slug] = row.Line
+
+		c.Result.Action = rowCreated
+		if exists {
+			c.Result.Changes = diffFields(current, proposed)
+			c.Result.Action = rowUpdated
+			if len(c.Result.Changes) == 0 {
+				c.Result.Action = rowSkipped
+			}
+		}
+		changes = append(changes, c)
+	}
+
+	return changes
+}
+
+// importCatalogue upserts beans or roasters from a CSV or JSONL file. With
+// dry_run nothing is written, but every row is still validated.
+func (h *Handler) importCatalogue(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		q         = r.URL.Query()
+		entity    = q.Get("entity")
+		format    = q.Get("format")
+		resp      = &CatalogueImportResp{Rows: []CatalogueRowResult{}}
+		userEmail = r.Header.Get("X-User-Email")
+		body      io.Reader
+	)
+
+	if !h.isModerator(userEmail) {
+		h.writeError(w, r, forbiddenError("only moderators can import the catalogue"))
+		return
+	}
+
+	if entity != "beans" && entity != "roasters" {
+		h.writeError(w, r, validationError("entity must be beans or roasters"))
+		return
+	}
+
+	if dryRun := q.Get("dry_run"); dryRun != "" {
+		ok, err := strconv.ParseBool(dryRun)
+		if err != nil {
+			h.writeError(w, r, validationError("dry_run must be true or false"))
+			return
+		}
+		resp.DryRun = ok
+	}
+
+	// The file is either the body or the "file" of a multipart form
+	contentType := r.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "multipart/form-data") {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			h.writeError(w, r, validationError(err.Error()))
+			return
+		}
+		defer file.Close()
+		body = file
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(header.Filename[strings.LastIndex(header.Filename, ".")+1:]), ".")
+		}
+	} else {
+		body = r.Body
+		if format == "" && strings.Contains(contentType, "csv") {
+			format = formatCSV
+		}
+		if format == "" && strings.Contains(contentType, "ndjson") {
+			format = formatJSONL
+		}
+	}
+
+	rows, err := readCatalogue(body, entity, format)
+	if err != nil {
+		h.writeError(w, r, validationError(err.Error()))
+		return
+	}
+
+	// Load what's already in the catalogue
+	var (
+		beans    = make(map[string]Bean)
+		roasters = make(map[string]Roaster)
+		refs     = make(map[string]*firestore.DocumentRef)
+	)
+	roasterDocs, err := h.database.Collection("roasters").Documents(ctx).GetAll()
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	for _, doc := range roasterDocs {
+		roaster := docToRoaster(doc)
+		roasters[roaster.Slug] = roaster
+		if entity == "roasters" {
+			refs[roaster.Slug] = doc.Ref
+		}
+	}
+	if entity == "beans" {
+		beanDocs, err := h.database.Collection("beans").Documents(ctx).GetAll()
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+		for _, doc := range beanDocs {
+			bean := docToBean(doc)
+			beans[bean.Slug] = bean
+			refs[bean.Slug] = doc.Ref
+		}
+	}
+
+	changes := planCatalogueImport(rows, entity, beans, roasters)
+
+	var (
+		batch   = h.database.Batch()
+		pending []catalogueChange
+	)
+	commit := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		if _, err := batch.Commit(ctx); err != nil {
+			return err
+		}
+		for _, c := range pending {
+			if entity == "roasters" {
+				h.recordRoasterChange(RoasterReq{c.Roaster}, userEmail)
+			} else {
+				h.recordBeanChange(BeanReq{c.Bean}, userEmail)
+			}
+		}
+		batch = h.database.Batch()
+		pending = nil
+		return nil
+	}
+
+	for _, c := range changes {
+		resp.Rows = append(resp.Rows, c.Result)
+		switch c.Result.Action {
+		case rowCreated:
+			resp.Created++
+		case rowUpdated:
+			resp.Updated++
+		case rowSkipped:
+			resp.Skipped++
+			continue
+		case rowFailed:
+			resp.Failed++
+			continue
+		}
+		if resp.DryRun {
+			continue
+		}
+
+		switch {
+		case c.Result.Action == rowCreated && entity == "roasters":
+			batch.Create(h.database.Collection("roasters").NewDoc(), RoasterDB{
+				Roaster: c.Roaster,
+				Geohash: roasterGeohash(c.Roaster),
+			})
+		case c.Result.Action == rowCreated:
+			batch.Create(h.database.Collection("beans").NewDoc(), c.Bean)
+		case entity == "roasters":
+			batch.Update(refs[c.Result.Slug], roasterImportUpdates(c.Roaster))
+		default:
+			batch.Update(refs[c.Result.Slug], beanImportUpdates(c.Bean))
+		}
+		pending = append(pending, c)
+		if len(pending) == maxBatchSize {
+			if err := commit(); err != nil {
+				h.writeError(w, r, err)
+				return
+			}
+		}
+	}
+	if err := commit(); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.logger.Infow(
+		"Catalogue imported",
+		"entity", entity,
+		"dry_run", resp.DryRun,
+		"created", resp.Created,
+		"updated", resp.Updated,
+		"skipped", resp.Skipped,
+		"failed", resp.Failed,
+		"updated_by", userEmail,
+	)
+
+	json.NewEncoder(w).Encode(resp)
+}
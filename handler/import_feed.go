@@ -0,0 +1,278 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Product feed sources
+const (
+	feedShopify     = "shopify"
+	feedWooCommerce = "woocommerce"
+)
+
+// maxProductFeedSize is the largest product feed that is imported
+const maxProductFeedSize = 10 * 1024 * 1024
+
+var errUnknownFeed = validationError("unknown feed, expected a Shopify products.json or WooCommerce Store API products feed")
+
+// feedProduct is a product from a shop, independent of the platform
+type feedProduct struct {
+	Name        string
+	Handle      string
+	Description string
+	URL         string
+	Photo       string
+	ProductType string
+	Tags        []string
+	Variants    []feedVariant
+}
+
+// feedVariant is a purchasable variant of a product, e.g. a bag size
+type feedVariant struct {
+	Title     string
+	Grams     int64
+	Price     float64
+	Currency  string
+	Available bool
+}
+
+// shopifyFeed is the response of a Shopify store's /products.json
+type shopifyFeed struct {
+	Products []struct {
+		Title       string          `json:"title"`
+		Handle      string          `json:"handle"`
+		BodyHTML    string          `json:"body_html"`
+		ProductType string          `json:"product_type"`
+		Tags        json.RawMessage `json:"tags"`
+		Images      []struct {
+			Src string `json:"src"`
+		} `json:"images"`
+		Variants []struct {
+			Title     string      `json:"title"`
+			Price     json.Number `json:"price"`
+			Grams     int64       `json:"grams"`
+			Available bool        `json:"available"`
+		} `json:"variants"`
+	} `json:"products"`
+}
+
+// wooProduct is a product from the WooCommerce Store API
+type wooProduct struct {
+	Name             string `json:"name"`
+	Slug             string `json:"slug"`
+	Permalink        string `json:"permalink"`
+	Description      string `json:"description"`
+	ShortDescription string `json:"short_description"`
+	IsInStock        bool   `json:"is_in_stock"`
+	Images           []struct {
+		Src string `json:"src"`
+	} `json:"images"`
+	Categories []struct {
+		Name string `json:"name"`
+	} `json:"categories"`
+	Tags []struct {
+		Name string `json:"name"`
+	} `json:"tags"`
+	Prices struct {
+		Price             string `json:"price"`
+		CurrencyCode      string `json:"currency_code"`
+		CurrencyMinorUnit int    `json:"currency_minor_unit"`
+	} `json:"prices"`
+}
+
+// feedURL returns the products endpoint for a shop URL
+func feedURL(source, shopURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(shopURL))
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", fmt.Errorf("invalid feed url")
+	}
+	if strings.HasSuffix(u.Path, ".json") || strings.Contains(u.Path, "/wp-json/") {
+		return u.String(), nil
+	}
+
+	switch source {
+	case feedShopify:
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/products.json"
+		u.RawQuery = "limit=250"
+	case feedWooCommerce:
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/wp-json/wc/store/v1/products"
+		u.RawQuery = "per_page=100"
+	default:
+		return "", fmt.Errorf("source is required to import from a shop url")
+	}
+
+	return u.String(), nil
+}
+
+// fetchFeed downloads a product feed
+func fetchFeed(ctx context.Context, fetcher Fetcher, feed string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", feed, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := fetcher.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch %s: %v", feed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %d", feed, resp.StatusCode)
+	}
+
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxProductFeedSize))
+}
+
+// detectFeed guesses the platform of a feed from its shape
+func detectFeed(data []byte) string {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(data, []byte("[")):
+		return feedWooCommerce
+	case bytes.HasPrefix(data, []byte("{")) && bytes.Contains(data, []byte(`"products"`)):
+		return feedShopify
+	}
+	return ""
+}
+
+// parseFeed reads the products of a Shopify or WooCommerce feed. The shop
+// URL is used to link Shopify products, which only have a handle.
+func parseFeed(data []byte, source, shopURL, currency string) ([]feedProduct, error) {
+	if source == "" {
+		source = detectFeed(data)
+	}
+
+	switch source {
+	case feedShopify:
+		return parseShopifyFeed(data, shopURL, currency)
+	case feedWooCommerce:
+		return parseWooFeed(data)
+	}
+
+	return nil, errUnknownFeed
+}
+
+func parseShopifyFeed(data []byte, shopURL, currency string) ([]feedProduct, error) {
+	var feed shopifyFeed
+	if err := json.Unmarshal(data, &feed); err != nil {
+		return nil, errUnknownFeed
+	}
+
+	var base string
+	if u, err := url.Parse(shopURL); err == nil && u.Host != "" {
+		base = fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+	}
+
+	var products []feedProduct
+	for _, p := range feed.Products {
+		product := feedProduct{
+			Name:        strings.TrimSpace(p.Title),
+			Handle:      p.Handle,
+			Description: p.BodyHTML,
+			ProductType: p.ProductType,
+			Tags:        shopifyTags(p.Tags),
+		}
+		if base != "" && p.Handle != "" {
+			product.URL = fmt.Sprintf("%s/products/%s", base, p.Handle)
+		}
+		if len(p.Images) > 0 {
+			product.Photo = p.Images[0].Src
+		}
+		for _, v := range p.Variants {
+			price, _ := v.Price.Float64()
+			product.Variants = append(product.Variants, feedVariant{
+				Title:     v.Title,
+				Grams:     v.Grams,
+				Price:     price,
+				Currency:  currency,
+				Available: v.Available,
+			})
+		}
+		products = append(products, product)
+	}
+
+	return products, nil
+}
+
+// shopifyTags reads tags, which are a list in products.json and a comma
+// separated string in the admin API
+func shopifyTags(raw json.RawMessage) []string {
+	var tags []string
+	if err := json.Unmarshal(raw, &tags); err == nil {
+		return tags
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		for _, t := range strings.Split(s, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tags = append(tags, t)
+			}
+		}
+	}
+	return tags
+}
+
+func parseWooFeed(data []byte) ([]feedProduct, error) {
+	var feed []wooProduct
+	if err := json.Unmarshal(data, &feed); err != nil {
+		return nil, errUnknownFeed
+	}
+
+	var products []feedProduct
+	for _, p := range feed {
+		product := feedProduct{
+			Name:        html.UnescapeString(strings.TrimSpace(p.Name)),
+			Handle:      p.Slug,
+			Description: p.Description + "\n" + p.ShortDescription,
+			URL:         p.Permalink,
+		}
+		if len(p.Images) > 0 {
+			product.Photo = p.Images[0].Src
+		}
+		for _, c := range p.Categories {
+			product.ProductType = strings.TrimSpace(product.ProductType + " " + c.Name)
+		}
+		for _, t := range p.Tags {
+			product.Tags = append(product.Tags, t.Name)
+		}
+
+		// Prices are in the currency's minor unit
+		if minor, err := strconv.ParseFloat(p.Prices.Price, 64); err == nil && minor > 0 {
+			product.Variants = append(product.Variants, feedVariant{
+				Title:     product.Name,
+				Price:     minor / math.Pow10(p.Prices.CurrencyMinorUnit),
+				Currency:  p.Prices.CurrencyCode,
+				Available: p.IsInStock,
+			})
+		}
+		products = append(products, product)
+	}
+
+	return products, nil
+}
+
+// notCoffee matches products that shops sell next to their beans
+var notCoffee = regexp.MustCompile(`(?i)\b(mugs?|tumblers?|t-?shirts?|shirts?|tees?|hoodies?|hats?|totes?|stickers?|gift ?cards?|grinders?|drippers?|kettles?|scales?|filter papers?|brewers?|chemex|aeropress|v60|french press|teas?|merch|equipment)\b`)
+
+// isCoffeeProduct guesses if a product is a coffee rather than merch or gear
+func isCoffeeProduct(p feedProduct) bool {
+	kind := strings.ToLower(p.ProductType)
+	if kind != "" && !strings.Contains(kind, "coffee") && !strings.Contains(kind, "bean") {
+		return false
+	}
+	return !notCoffee.MatchString(p.Name + " " + kind + " " + strings.Join(p.Tags, " "))
+}
@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// producingCountries are the coffee producing countries recognised in
+// product descriptions
+var producingCountries = []string{
+	"Bolivia", "Brazil", "Burundi", "Cameroon", "China", "Colombia",
+	"Costa Rica", "Democratic Republic of Congo", "Dominican Republic",
+	"Ecuador", "El Salvador", "Ethiopia", "Guatemala", "Haiti", "Honduras",
+	"India", "Indonesia", "Jamaica", "Kenya", "Laos", "Malawi", "Mexico",
+	"Myanmar", "Nicaragua", "Panama", "Papua New Guinea", "Peru",
+	"Philippines", "Rwanda", "Tanzania", "Thailand", "Timor-Leste", "Uganda",
+	"Vietnam", "Yemen", "Zambia", "Zimbabwe",
+}
+
+// countryAliases map names shops use to a producing country
+var countryAliases = map[string]string{
+	"congo":      "Democratic Republic of Congo",
+	"drc":        "Democratic Republic of Congo",
+	"east timor": "Timor-Leste",
+	"png":        "Papua New Guinea",
+	"sumatra":    "Indonesia",
+	"java":       "Indonesia",
+	"sulawesi":   "Indonesia",
+}
+
+var (
+	htmlBreaks  = regexp.MustCompile(`(?i)<\s*(br|/p|/li|/div|/h[1-6]|/tr)\s*/?>`)
+	htmlTags    = regexp.MustCompile(`<[^>]*>`)
+	listSplit   = regexp.MustCompile(`\s*(?:,|;|/|&|\+|\band\b|\|)\s*`)
+	weightValue = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*(kg|kilo|g|gr|grams?|oz|ounces?|lbs?|pounds?)\b`)
+	numberValue = regexp.MustCompile(`\d[\d,.]*`)
+)
+
+// Labels of the lines in product descriptions that describe a coffee
+var (
+	flavorLabels   = []string{"tasting notes", "taste notes", "flavor notes", "flavour notes", "notes", "tastes like", "we taste", "cup profile", "flavors", "flavours"}
+	originLabels   = []string{"origin", "country", "country of origin"}
+	regionLabels   = []string{"region", "area"}
+	processLabels  = []string{"process", "processing", "processing method"}
+	varietalLabels = []string{"variety", "varietal", "varietals", "varieties", "cultivar", "cultivars"}
+	producerLabels = []string{"producer", "producers", "grower", "growers", "producer/farm"}
+	farmLabels     = []string{"farm", "estate", "washing station", "finca"}
+	altitudeLabels = []string{"altitude", "elevation", "grown at"}
+)
+
+// htmlToText strips a product description down to lines of text
+func htmlToText(s string) string {
+	s = htmlBreaks.ReplaceAllString(s, "\n")
+	s = htmlTags.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// labelValue finds the value of a "Label: value" line
+func labelValue(text string, labels []string) string {
+	for _, line := range strings.Split(text, "\n") {
+		lower := strings.ToLower(line)
+		for _, label := range labels {
+			if !strings.HasPrefix(lower, label) {
+				continue
+			}
+			rest := strings.TrimSpace(line[len(label):])
+			if strings.HasPrefix(rest, ":") || strings.HasPrefix(rest, "-") || strings.HasPrefix(rest, "–") {
+				return strings.TrimSpace(strings.TrimLeft(rest, ":-– "))
+			}
+		}
+	}
+	return ""
+}
+
+// isLabelLine checks if a line is one of the labeled coffee details
+func isLabelLine(line string) bool {
+	for _, labels := range [][]string{flavorLabels, originLabels, regionLabels, processLabels, varietalLabels, producerLabels, farmLabels, altitudeLabels} {
+		if labelValue(line, labels) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range listSplit.Split(s, -1) {
+		if item = strings.Trim(item, " ."); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// countryPatterns match producing countries and their aliases as whole words
+var countryPatterns = func() map[*regexp.Regexp]string {
+	var patterns = make(map[*regexp.Regexp]string)
+	for _, c := range producingCountries {
+		patterns[regexp.MustCompile(`(?i)\b`+regexp.QuoteMeta(c)+`\b`)] = c
+	}
+	for alias, c := range countryAliases {
+		patterns[regexp.MustCompile(`(?i)\b`+regexp.QuoteMeta(alias)+`\b`)] = c
+	}
+	return patterns
+}()
+
+// findCountries finds producing countries mentioned in some text, in the
+// order they are mentioned
+func findCountries(text string) []string {
+	var (
+		countries []string
+		positions = make(map[string]int)
+	)
+
+	for p, c := range countryPatterns {
+		loc := p.FindStringIndex(text)
+		if loc == nil {
+			continue
+		}
+		if pos, ok := positions[c]; !ok || loc[0] < pos {
+			positions[c] = loc[0]
+		}
+	}
+	for c := range positions {
+		countries = append(countries, c)
+	}
+	sort.Slice(countries, func(i, j int) bool {
+		return positions[countries[i]] < positions[countries[j]]
+	})
+
+	return countries
+}
+
+// parseAltitude reads an altitude like "1,800 - 2,100 masl" or "6000 ft"
+func parseAltitude(s string) *Altitude {
+	var values []int64
+	for _, n := range numberValue.FindAllString(s, 2) {
+		n = strings.NewReplacer(",", "", ".", "").Replace(n)
+		if v, err := strconv.ParseInt(n, 10, 64); err == nil {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	a := &Altitude{Min: values[0], Max: values[0]}
+	if len(values) > 1 {
+		a.Max = values[1]
+	}
+	if strings.Contains(strings.ToLower(s), "ft") || strings.Contains(strings.ToLower(s), "feet") {
+		a.Min = a.Min * 3048 / 10000
+		a.Max = a.Max * 3048 / 10000
+	}
+	if a.Min > a.Max {
+		a.Min, a.Max = a.Max, a.Min
+	}
+	return a
+}
+
+// parseWeight reads a bag size like "12oz" or "1 kg" in grams
+func parseWeight(s string) int64 {
+	m := weightValue.FindStringSubmatch(s)
+	if m == nil {
+		return 0
+	}
+	v, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0
+	}
+
+	switch unit := strings.ToLower(m[2]); {
+	case unit == "kg" || unit == "kilo":
+		v *= 1000
+	case strings.HasPrefix(unit, "oz") || strings.HasPrefix(unit, "ounce"):
+		v *= 28.3495
+	case strings.HasPrefix(unit, "lb") || strings.HasPrefix(unit, "pound"):
+		v *= 453.592
+	}
+	return int64(v + 0.5)
+}
+
+// productToBean maps a product to a bean candidate for a roaster
+func productToBean(p feedProduct, roaster Roaster, currency string) Bean {
+	var (
+		text = htmlToText(p.Description)
+		b    = Bean{
+			Name:    p.Name,
+			Slug:    slugify(roaster.Slug + " " + p.Name),
+			Photo:   p.Photo,
+			URL:     p.URL,
+			Roaster: RoasterMap{Name: roaster.Name, Slug: roaster.Slug},
+		}
+	)
+
+	// The description is the first line that isn't a detail of the coffee
+	for _, line := range strings.Split(text, "\n") {
+		if !isLabelLine(line) {
+			b.Description = line
+			break
+		}
+	}
+
+	// Flavors come from the tasting notes, or tags that are on the flavor wheel
+	if notes := labelValue(text, flavorLabels); notes != "" {
+		b.Flavors = splitList(notes)
+	}
+	for _, tag := range p.Tags {
+		if lookupFlavor(cleanFlavor(tag)) != nil {
+			b.Flavors = append(b.Flavors, tag)
+		}
+	}
+	b.Flavors = normalizeFlavors(b.Flavors)
+
+	if origin := labelValue(text, originLabels); origin != "" {
+		b.Countries = findCountries(origin)
+	}
+	if len(b.Countries) == 0 {
+		b.Countries = findCountries(p.Name + "\n" + strings.Join(p.Tags, " ") + "\n" + text)
+	}
+
+	b.Region = labelValue(text, regionLabels)
+	b.Producer = labelValue(text, producerLabels)
+	b.Farm = labelValue(text, farmLabels)
+	b.Altitude = parseAltitude(labelValue(text, altitudeLabels))
+
+	// Only keep terms from the controlled vocabularies
+	for _, v := range append(splitList(labelValue(text, varietalLabels)), p.Tags...) {
+		if t, ok := canonicalTerm(varietals, v); ok && !containsString(b.Varietals, t) {
+			b.Varietals = append(b.Varietals, t)
+		}
+	}
+	for _, v := range append([]string{labelValue(text, processLabels)}, p.Tags...) {
+		if t, ok := canonicalTerm(processes, v); ok {
+			b.Process = t
+			break
+		}
+	}
+
+	for _, v := range p.Variants {
+		o := Offering{
+			Currency:     v.Currency,
+			InStock:      v.Available,
+			Price:        v.Price,
+			Subscription: strings.Contains(strings.ToLower(v.Title), "subscri"),
+			URL:          p.URL,
+			WeightGrams:  parseWeight(v.Title),
+		}
+		if o.WeightGrams == 0 {
+			o.WeightGrams = parseWeight(p.Name)
+		}
+		if o.WeightGrams == 0 {
+			o.WeightGrams = v.Grams
+		}
+		if o.Currency == "" {
+			o.Currency = currency
+		}
+		if strings.Contains(strings.ToLower(v.Title), "ground") {
+			o.Grind = grindGround
+		}
+		if o.WeightGrams <= 0 || o.Price <= 0 {
+			continue
+		}
+		b.Offerings = append(b.Offerings, o)
+	}
+
+	return b
+}
@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ImportReq is the request body for the POST /roasters/{slug}/imports
+// endpoint. The feed can also be uploaded as the "feed" file of a
+// multipart form.
+type ImportReq struct {
+	Source   string `json:"source"`
+	URL      string `json:"url"`
+	Currency string `json:"currency"`
+}
+
+// ImportResp is the response from the import preview endpoint
+type ImportResp struct {
+	Import Import `json:"import"`
+}
+
+// ConfirmImportReq is the request body for the confirm endpoint. All
+// creates and updates are applied when no slugs are given.
+type ConfirmImportReq struct {
+	Slugs []string `json:"slugs"`
+}
+
+// ImportError is an item that failed to import
+type ImportError struct {
+	Slug  string `json:"slug"`
+	Error string `json:"error"`
+}
+
+// ConfirmImportResp is the response from the confirm endpoint
+type ConfirmImportResp struct {
+	Created   []string      `json:"created"`
+	Updated   []string      `json:"updated"`
+	Submitted []string      `json:"submitted"`
+	Failed    []ImportError `json:"failed"`
+}
+
+// previewRoasterImport reads a roaster's product feed and shows what
+// importing it would change, without writing any beans
+func (h *Handler) previewRoasterImport(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		vars      = mux.Vars(r)
+		slug      = vars["slug"]
+		err       error
+		req       ImportReq
+		data      []byte
+		resp      = &ImportResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	roasterDoc, err := h.getRoasterDocBySlug(ctx, slug)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	roaster := docToRoasterDB(roasterDoc)
+	if !h.canEdit(roaster, userEmail) {
+		h.writeError(w, r, forbiddenError("roaster is verified, only its owner can import beans"))
+		return
+	}
+
+	// Read the feed from an upload or the shop
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("feed")
+		if err != nil {
+			h.writeError(w, r, validationError(err.Error()))
+			return
+		}
+		defer file.Close()
+		data, err = ioutil.ReadAll(io.LimitReader(file, maxProductFeedSize))
+		if err != nil {
+			h.writeError(w, r, validationError(err.Error()))
+			return
+		}
+		req.Source = r.FormValue("source")
+		req.Currency = r.FormValue("currency")
+		req.URL = roaster.URL
+	} else {
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			h.writeError(w, r, bodyError(err))
+			return
+		}
+		if req.URL == "" {
+			req.URL = roaster.URL
+		}
+		resp.Import.FeedURL, err = feedURL(req.Source, req.URL)
+		if err != nil {
+			h.writeError(w, r, validationError(err.Error()))
+			return
+		}
+		data, err = fetchFeed(ctx, h.fetcher, resp.Import.FeedURL)
+		if err != nil {
+			h.writeError(w, r, upstreamError(err))
+			return
+		}
+	}
+
+	rates := h.exchangeRates()
+	req.Currency = strings.ToUpper(req.Currency)
+	if req.Currency == "" {
+		req.Currency = strings.ToUpper(h.cfg.BaseCurrency)
+	}
+	if _, ok := rates[req.Currency]; !ok {
+		h.writeError(w, r, errUnknownCurrency)
+		return
+	}
+
+	if req.Source == "" {
+		req.Source = detectFeed(data)
+	}
+	products, err := parseFeed(data, req.Source, req.URL, req.Currency)
+	if err != nil {
+		h.writeError(w, r, validationError(err.Error()))
+		return
+	}
+
+	var candidates []Bean
+	for _, p := range products {
+		if isCoffeeProduct(p) {
+			candidates = append(candidates, productToBean(p, roaster.Roaster, req.Currency))
+		}
+	}
+
+	// Compare with the beans the roaster already has
+	docs, err := h.database.Collection("beans").Where("roaster.slug", "==", slug).Documents(ctx).GetAll()
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	var existing []Bean
+	for _, doc := range docs {
+		existing = append(existing, docToBean(doc))
+	}
+
+	resp.Import.RoasterSlug = slug
+	resp.Import.Source = req.Source
+	resp.Import.Status = importPending
+	resp.Import.Items = buildImportItems(existing, candidates, rates)
+	resp.Import.CreatedBy = userEmail
+	resp.Import.CreatedAt = time.Now()
+
+	doc, _, err := h.database.Collection("imports").Add(ctx, resp.Import)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	resp.Import.ID = doc.ID
+	h.logger.Infow(
+		"Import previewed",
+		"id", doc.ID,
+		"roaster", slug,
+		"items", len(resp.Import.Items),
+		"created_by", userEmail,
+	)
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// confirmRoasterImport writes the beans of a previewed import
+func (h *Handler) confirmRoasterImport(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		vars      = mux.Vars(r)
+		slug      = vars["slug"]
+		id        = vars["id"]
+		req       ConfirmImportReq
+		resp      = &ConfirmImportResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	// The body is optional
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		h.writeError(w, r, bodyError(err))
+		return
+	}
+
+	// Check the user can still edit the roaster
+	roasterDoc, err := h.getRoasterDocBySlug(ctx, slug)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if !h.canEdit(docToRoasterDB(roasterDoc), userEmail) {
+		h.writeError(w, r, forbiddenError("roaster is verified, only its owner can import beans"))
+		return
+	}
+
+	// Claim the import first so confirming twice doesn't apply it twice
+	imp, err := h.claimImport(ctx, id, slug, userEmail)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	for _, item := range imp.Items {
+		if item.Action != importCreate && item.Action != importUpdate {
+			continue
+		}
+		if len(req.Slugs) > 0 && !containsString(req.Slugs, item.Slug) {
+			continue
+		}
+
+		outcome, err := h.applyImportItem(ctx, item, userEmail)
+		if err != nil {
+			resp.Failed = append(resp.Failed, ImportError{Slug: item.Slug, Error: err.Error()})
+			continue
+		}
+		switch outcome {
+		case importCreate:
+			resp.Created = append(resp.Created, item.Slug)
+		case importUpdate:
+			resp.Updated = append(resp.Updated, item.Slug)
+		default:
+			resp.Submitted = append(resp.Submitted, item.Slug)
+		}
+	}
+
+	h.logger.Infow(
+		"Import applied",
+		"id", id,
+		"roaster", slug,
+		"created", len(resp.Created),
+		"updated", len(resp.Updated),
+		"submitted", len(resp.Submitted),
+		"failed", len(resp.Failed),
+		"applied_by", userEmail,
+	)
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// applyImportItem creates or updates a bean through the normal write path,
+// including moderation. It returns the action taken, or "submitted" when
+// the write is waiting for a moderator.
+func (h *Handler) applyImportItem(ctx context.Context, item ImportItem, userEmail string) (string, error) {
+	// Imports previewed before candidates were kept only have the preview
+	candidate := item.Candidate
+	if candidate.Slug == "" {
+		candidate = item.Bean
+	}
+
+	if h.requiresModeration(ctx, userEmail) {
+		action := "add"
+		if item.Action == importUpdate {
+			action = "edit"
+		}
+		preview := item.Bean
+		_, err := h.submit(ctx, Submission{
+			Kind:        "bean",
+			Action:      action,
+			Slug:        item.Slug,
+			Bean:        &preview,
+			Candidate:   &candidate,
+			SubmittedBy: userEmail,
+		})
+		return "submitted", err
+	}
+
+	if err := h.applyImportedBean(ctx, item.Action, item.Slug, candidate, userEmail); err != nil {
+		return "", err
+	}
+	return item.Action, nil
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testShopifyFeed = `{"products": [
+  {
+    "title": "Ethiopia Guji Hambela",
+    "handle": "ethiopia-guji-hambela",
+    "product_type": "Coffee",
+    "tags": ["Single Origin", "Natural", "Heirloom"],
+    "body_html": "<p>A juicy natural from the Hambela washing station.</p><ul><li><strong>Tasting notes:</strong> Blueberry, Jasmine &amp; Milk Chocolate</li><li>Region: Guji</li><li>Altitude: 1,900 - 2,200 masl</li><li>Producer: Hambela Estate</li></ul>",
+    "images": [{"src": "https://cdn.shopify.com/guji.jpg"}],
+    "variants": [
+      {"title": "12oz / Whole Bean", "price": "19.00", "grams": 0, "available": true},
+      {"title": "12oz / Ground", "price": "19.00", "grams": 0, "available": false},
+      {"title": "5 lb", "price": "95.00", "grams": 2268, "available": true}
+    ]
+  },
+  {
+    "title": "Logo Mug",
+    "handle": "logo-mug",
+    "product_type": "Merch",
+    "tags": [],
+    "body_html": "",
+    "images": [],
+    "variants": [{"title": "Default Title", "price": "15.00", "grams": 400, "available": true}]
+  }
+]}`
+
+const testWooFeed = `[{
+  "name": "Colombia Huila &#8211; Decaf",
+  "slug": "colombia-huila-decaf",
+  "permalink": "https://roaster.example/product/colombia-huila-decaf/",
+  "description": "<p>Sugarcane processed decaf.</p><p>Notes: Caramel, Red Apple</p><p>Variety: Caturra, Castillo</p><p>Process: Washed</p>",
+  "short_description": "",
+  "is_in_stock": true,
+  "images": [{"src": "https://roaster.example/huila.jpg"}],
+  "categories": [{"name": "Coffee"}],
+  "tags": [],
+  "prices": {"price": "1650", "currency_code": "EUR", "currency_minor_unit": 2}
+}]`
+
+func Test_parseFeed(t *testing.T) {
+	products, err := parseFeed([]byte(testShopifyFeed), "", "https://roaster.example", "USD")
+	assert.NoError(t, err)
+	assert.Len(t, products, 2)
+	assert.True(t, isCoffeeProduct(products[0]))
+	assert.False(t, isCoffeeProduct(products[1]))
+	assert.Equal(t, "https://roaster.example/products/ethiopia-guji-hambela", products[0].URL)
+
+	products, err = parseFeed([]byte(testWooFeed), "", "", "USD")
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, "Colombia Huila – Decaf", products[0].Name)
+	assert.Equal(t, []feedVariant{{Title: "Colombia Huila – Decaf", Price: 16.5, Currency: "EUR", Available: true}}, products[0].Variants)
+
+	_, err = parseFeed([]byte(`"nope"`), "", "", "USD")
+	assert.Equal(t, errUnknownFeed, err)
+}
+
+func Test_productToBean(t *testing.T) {
+	roaster := Roaster{Name: "Example Roasters", Slug: "example-roasters"}
+
+	products, _ := parseFeed([]byte(testShopifyFeed), feedShopify, "https://roaster.example", "USD")
+	b := productToBean(products[0], roaster, "USD")
+	assert.Equal(t, "example-roasters-ethiopia-guji-hambela", b.Slug)
+	assert.Equal(t, "A juicy natural from the Hambela washing station.", b.Description)
+	assert.Equal(t, []string{"blueberry", "jasmine", "chocolate"}, b.Flavors)
+	assert.Equal(t, []string{"Ethiopia"}, b.Countries)
+	assert.Equal(t, "Guji", b.Region)
+	assert.Equal(t, "Hambela Estate", b.Producer)
+	assert.Equal(t, &Altitude{Min: 1900, Max: 2200}, b.Altitude)
+	assert.Equal(t, []string{"Ethiopian Landrace"}, b.Varietals)
+	assert.Equal(t, "Natural", b.Process)
+	assert.Len(t, b.Offerings, 3)
+	assert.Equal(t, int64(340), b.Offerings[0].WeightGrams)
+	assert.Equal(t, grindGround, b.Offerings[1].Grind)
+	assert.False(t, b.Offerings[1].InStock)
+	assert.Equal(t, int64(2268), b.Offerings[2].WeightGrams)
+
+	products, _ = parseFeed([]byte(testWooFeed), feedWooCommerce, "", "USD")
+	b = productToBean(products[0], roaster, "USD")
+	assert.Equal(t, []string{"Colombia"}, b.Countries)
+	assert.Equal(t, []string{"Caturra", "Castillo"}, b.Varietals)
+	assert.Equal(t, "Washed", b.Process)
+	// The bag size is unknown, so there is no offering to compare prices with
+	assert.Empty(t, b.Offerings)
+}
+
+func Test_buildImportItems(t *testing.T) {
+	existing := []Bean{{
+		Name:      "Ethiopia Guji Hambela",
+		Slug:      "example-roasters-guji",
+		Countries: []string{"Ethiopia"},
+		Flavors:   []string{"blueberry"},
+		Year:      2020,
+		Offerings: []Offering{{ID: "abc", Currency: "USD", Price: 19, WeightGrams: 340, Grind: grindWhole, InStock: true}},
+	}}
+	candidates := []Bean{
+		{
+			Name:      "Ethiopia Guji Hambela",
+			Slug:      "example-roasters-ethiopia-guji-hambela",
+			Flavors:   []string{"blueberry", "jasmine"},
+			Offerings: []Offering{{Currency: "USD", Price: 19, WeightGrams: 340, InStock: true}},
+		},
+		{Name: "Kenya Nyeri", Slug: "example-roasters-kenya-nyeri", Process: "washed"},
+		{Name: "Mystery", Slug: "example-roasters-mystery", Shade: "burnt"},
+	}
+
+	items := buildImportItems(existing, candidates, testRates)
+	assert.Len(t, items, 3)
+
+	assert.Equal(t, importUpdate, items[0].Action)
+	assert.Equal(t, "example-roasters-guji", items[0].Slug)
+	assert.Equal(t, int64(2020), items[0].Bean.Year)
+	assert.Equal(t, "abc", items[0].Bean.Offerings[0].ID)
+	assert.Equal(t, []FieldChange{{Field: "flavors", From: []interface{}{"blueberry"}, To: []interface{}{"blueberry", "jasmine"}}}, items[0].Changes)
+
+	assert.Equal(t, importCreate, items[1].Action)
+	assert.Equal(t, "Washed", items[1].Bean.Process)
+
+	assert.Equal(t, importInvalid, items[2].Action)
+	assert.NotEmpty(t, items[2].Error)
+}
+
+func Test_parseWeight(t *testing.T) {
+	tests := map[string]int64{
+		"12oz / Whole Bean": 340,
+		"250g":              250,
+		"1 kg":              1000,
+		"2lb bag":           907,
+		"Default Title":     0,
+	}
+	for in, exp := range tests {
+		assert.Equal(t, exp, parseWeight(in), in)
+	}
+}
+
+func Test_withOfferingIDs(t *testing.T) {
+	offerings, err := withOfferingIDs([]Offering{{ID: "kept", WeightGrams: 340}, {WeightGrams: 1000}})
+	assert.NoError(t, err)
+	assert.Equal(t, "kept", offerings[0].ID)
+	assert.Len(t, offerings[1].ID, 16)
+}
@@ -2,8 +2,8 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
 )
 
@@ -19,17 +19,31 @@ type IPResp struct {
 func (h *Handler) getIP(w http.ResponseWriter, r *http.Request) {
 	var (
 		resp = &IPResp{}
+		ctx  = r.Context()
+		body []byte
 	)
 
-	ipResp, err := http.Get("https://curlmyip.org")
-	if err != nil {
-		h.logger.Fatalf(err.Error())
-	}
+	err := retry(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, "GET", "https://curlmyip.org", nil)
+		if err != nil {
+			return err
+		}
+		ipResp, err := h.fetcher.Do(req)
+		if err != nil {
+			return err
+		}
+		defer ipResp.Body.Close()
+		if ipResp.StatusCode != http.StatusOK {
+			return fmt.Errorf("curlmyip.org returned %s", ipResp.Status)
+		}
 
-	//We Read the response body on the line below.
-	body, err := ioutil.ReadAll(ipResp.Body)
+		//We Read the response body on the line below.
+		body, err = ioutil.ReadAll(ipResp.Body)
+		return err
+	})
 	if err != nil {
-		log.Fatalln(err)
+		h.writeError(w, r, upstreamError(err))
+		return
 	}
 	//Convert the body to type string
 	resp.IP.IPAddress = string(body)
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// FlavorMergesResp is the response for the GET /admin/flavors/merges endpoint
+type FlavorMergesResp struct {
+	Merges []FlavorMerge `json:"merges"`
+}
+
+// MergeFlavorsReq is the request body for the POST /admin/flavors/merges endpoint
+type MergeFlavorsReq struct {
+	Merges map[string]string `json:"merges"`
+	DryRun bool              `json:"dry_run"`
+}
+
+// getFlavorMerges proposes merges of misspelled and inconsistent flavors
+func (h *Handler) getFlavorMerges(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		resp      = &FlavorMergesResp{Merges: []FlavorMerge{}}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	if !h.isModerator(userEmail) {
+		h.writeError(w, r, forbiddenError("only moderators can merge flavors"))
+		return
+	}
+
+	merges, err := h.ProposeFlavorMerges(ctx)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	resp.Merges = append(resp.Merges, merges...)
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// mergeFlavors applies an approved merge map to every bean
+func (h *Handler) mergeFlavors(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		req       MergeFlavorsReq
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	if !h.isModerator(userEmail) {
+		h.writeError(w, r, forbiddenError("only moderators can merge flavors"))
+		return
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.writeError(w, r, bodyError(err))
+		return
+	}
+	if len(req.Merges) == 0 {
+		h.writeError(w, r, validationError("no merges"))
+		return
+	}
+
+	resp, err := h.ApplyFlavorMerges(ctx, req.Merges, userEmail, req.DryRun)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if !req.DryRun {
+		w.WriteHeader(http.StatusAccepted)
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+)
+
+// metrics are published at /debug/vars
+var metrics = expvar.NewMap("cafebean")
+
+// Metric names
+const (
+	metricPanics             = "panics"
+	metricRetries            = "retries"
+	metricErrors             = "errors"
+	metricDroppedSideEffects = "dropped_side_effects"
+)
+
+// countError counts an error response by status code, like errors.500
+func countError(status int) {
+	metrics.Add(metricErrors+"."+strconv.Itoa(status), 1)
+}
+
+// getMetrics serves the expvar metrics to moderators. They include the
+// command line and memory stats, so they aren't public.
+func (h *Handler) getMetrics(w http.ResponseWriter, r *http.Request) {
+	if !h.isModerator(r.Header.Get("X-User-Email")) {
+		h.writeError(w, r, forbiddenError("only moderators can read metrics"))
+		return
+	}
+	expvar.Handler().ServeHTTP(w, r)
+}
+
+// recoverMiddleware turns a panic in a handler into a 500 instead of taking
+// down the server
+func (h *Handler) recoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			// Aborted responses are handled by the server
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			metrics.Add(metricPanics, 1)
+			h.logger.Errorw(
+				"Recovered from panic",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"request_id", r.Header.Get(requestIDHeader),
+				"panic", fmt.Sprint(p),
+				"stack", string(debug.Stack()),
+			)
+			h.writeError(w, r, fmt.Errorf("panic: %v", p))
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// approveClaim verifies a roaster claim without the domain check
+func (h *Handler) approveClaim(w http.ResponseWriter, r *http.Request) {
+	h.moderateClaim(w, r, claimVerified)
+}
+
+// rejectClaim closes a roaster claim
+func (h *Handler) rejectClaim(w http.ResponseWriter, r *http.Request) {
+	h.moderateClaim(w, r, claimRejected)
+}
+
+func (h *Handler) moderateClaim(w http.ResponseWriter, r *http.Request, decision string) {
+	var (
+		ctx       = r.Context()
+		vars      = mux.Vars(r)
+		id        = vars["id"]
+		req       ModerateReq
+		resp      = &ClaimResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	if !h.isModerator(userEmail) {
+		h.writeError(w, r, forbiddenError("only moderators can review claims"))
+		return
+	}
+
+	// The comment is optional
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.writeError(w, r, bodyError(err))
+			return
+		}
+	}
+
+	ref := h.database.Collection("claims").Doc(id)
+	doc, err := ref.Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		h.writeError(w, r, errClaimNotFound)
+		return
+	}
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	c := docToClaim(doc)
+
+	if decision == claimVerified {
+		resp.Claim, err = h.verifyClaim(ctx, ref, c, "admin", userEmail)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	c, err = h.closeClaim(ctx, ref, userEmail)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	h.logger.Infow(
+		"Roaster claim rejected",
+		"id", c.ID,
+		"slug", c.RoasterSlug,
+		"reviewed_by", userEmail,
+	)
+
+	message := fmt.Sprintf("Your claim on %s was rejected", c.RoasterSlug)
+	if req.Comment != "" {
+		message = fmt.Sprintf("%s: %s", message, req.Comment)
+	}
+	h.notify(ctx, Notification{User: c.Email, Message: message})
+
+	resp.Claim = c
+
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// ModerateReviewResp is the response from the review moderation endpoints
+type ModerateReviewResp struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+}
+
+// approveReview restores a review hidden by reports and clears the reports
+func (h *Handler) approveReview(w http.ResponseWriter, r *http.Request) {
+	h.moderateReview(w, r, submissionApproved)
+}
+
+// rejectReview deletes a review hidden by reports
+func (h *Handler) rejectReview(w http.ResponseWriter, r *http.Request) {
+	h.moderateReview(w, r, submissionRejected)
+}
+
+func (h *Handler) moderateReview(w http.ResponseWriter, r *http.Request, decision string) {
+	var (
+		ctx       = r.Context()
+		vars      = mux.Vars(r)
+		req       ModerateReq
+		resp      = &ModerateReviewResp{Status: decision}
+		userEmail = r.Header.Get("X-User-Email")
+		author    string
+	)
+
+	if !h.cfg.ReviewsEnabled {
+		h.writeError(w, r, notFoundError("reviews are disabled"))
+		return
+	}
+
+	if !h.isModerator(userEmail) {
+		h.writeError(w, r, forbiddenError("only moderators can review reports"))
+		return
+	}
+
+	reviewID, err := parseReviewID(vars["id"])
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	resp.ID = reviewID
+
+	// The comment is optional
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.writeError(w, r, bodyError(err))
+			return
+		}
+	}
+
+	tx, err := h.postgres.BeginTx(ctx, nil)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	if decision == submissionApproved {
+		_, err = tx.ExecContext(ctx, `DELETE FROM review_reports WHERE review_id = $1;`, reviewID)
+		if err == nil {
+			err = tx.QueryRowContext(ctx, `
+				UPDATE reviews r SET hidden = false, report_count = 0
+				FROM users u
+				WHERE r.review_id = $1 AND r.hidden AND r.user_id = u.user_id
+				RETURNING u.username;
+			`, reviewID).Scan(&author)
+		}
+	} else {
+		err = tx.QueryRowContext(ctx, `
+			DELETE FROM reviews r
+			USING users u
+			WHERE r.review_id = $1 AND r.hidden AND r.user_id = u.user_id
+			RETURNING u.username;
+		`, reviewID).Scan(&author)
+	}
+	if err != nil {
+		h.logger.Error(err)
+		h.writeError(w, r, errReviewNotFound)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	h.logger.Infow(
+		"Reported review reviewed",
+		"review_id", reviewID,
+		"status", decision,
+		"reviewed_by", userEmail,
+	)
+
+	// Let the author know their review was removed
+	if decision == submissionRejected {
+		if u, err := h.getUserByUsername(ctx, author); err == nil {
+			message := "Your review was removed by a moderator"
+			if req.Comment != "" {
+				message = fmt.Sprintf("%s: %s", message, req.Comment)
+			}
+			h.notify(ctx, Notification{User: u.Email, Message: message})
+		}
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
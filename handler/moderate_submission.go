@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ModerateReq is the request body for approving or rejecting a submission
+type ModerateReq struct {
+	Comment string `json:"comment"`
+}
+
+// ModerateSubmissionResp is the response from the submission moderation endpoints
+type ModerateSubmissionResp struct {
+	Submission Submission `json:"submission"`
+}
+
+// approveSubmission publishes a pending submission through the normal write path
+func (h *Handler) approveSubmission(w http.ResponseWriter, r *http.Request) {
+	h.moderateSubmission(w, r, submissionApproved)
+}
+
+// rejectSubmission discards a pending submission
+func (h *Handler) rejectSubmission(w http.ResponseWriter, r *http.Request) {
+	h.moderateSubmission(w, r, submissionRejected)
+}
+
+func (h *Handler) moderateSubmission(w http.ResponseWriter, r *http.Request, decision string) {
+	var (
+		ctx       = r.Context()
+		vars      = mux.Vars(r)
+		id        = vars["id"]
+		req       ModerateReq
+		resp      = &ModerateSubmissionResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	if !h.isModerator(userEmail) {
+		h.writeError(w, r, forbiddenError("only moderators can review submissions"))
+		return
+	}
+
+	// The comment is optional
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.writeError(w, r, bodyError(err))
+			return
+		}
+	}
+
+	// Claim the submission first so two moderators can't apply it twice
+	s, err := h.claimSubmission(ctx, id, decision, userEmail, req.Comment)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if decision == submissionApproved {
+		if err = h.applySubmission(ctx, s); err != nil {
+			h.releaseSubmission(ctx, s.ID)
+			h.writeError(w, r, err)
+			return
+		}
+	}
+
+	h.logger.Infow(
+		"Submission reviewed",
+		"id", s.ID,
+		"status", s.Status,
+		"reviewed_by", userEmail,
+	)
+
+	// Let the submitter know
+	message := fmt.Sprintf("Your %s %s for %s was %s", s.Kind, s.Action, s.Slug, s.Status)
+	if s.Comment != "" {
+		message = fmt.Sprintf("%s: %s", message, s.Comment)
+	}
+	h.notify(ctx, Notification{
+		User:         s.SubmittedBy,
+		Message:      message,
+		SubmissionID: s.ID,
+	})
+
+	resp.Submission = s
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// claimSubmission checks that a submission is pending and records the
+// decision in one transaction
+func (h *Handler) claimSubmission(ctx context.Context, id, decision, reviewer, comment string) (Submission, error) {
+	var (
+		ref = h.database.Collection("submissions").Doc(id)
+		s   Submission
+	)
+	err := h.database.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return errSubmissionNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		s = docToSubmission(doc)
+		if s.Status != submissionPending {
+			return conflictError(fmt.Sprintf("submission already %s", s.Status))
+		}
+
+		s.Status = decision
+		s.ReviewedBy = reviewer
+		s.ReviewedAt = time.Now()
+		s.Comment = comment
+		return tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: s.Status},
+			{Path: "reviewed_by", Value: s.ReviewedBy},
+			{Path: "reviewed_at", Value: s.ReviewedAt},
+			{Path: "comment", Value: s.Comment},
+		})
+	})
+	return s, err
+}
+
+// releaseSubmission puts a claimed submission back in the queue when it
+// couldn't be applied
+func (h *Handler) releaseSubmission(ctx context.Context, id string) {
+	_, err := h.database.Collection("submissions").Doc(id).Update(ctx, []firestore.Update{
+		{Path: "status", Value: submissionPending},
+		{Path: "reviewed_by", Value: ""},
+		{Path: "reviewed_at", Value: time.Time{}},
+		{Path: "comment", Value: ""},
+	})
+	if err != nil {
+		h.logger.Errorw("Error releasing submission", "id", id, "error", err)
+	}
+}
+
+// applySubmission writes an approved submission on behalf of the submitter
+func (h *Handler) applySubmission(ctx context.Context, s Submission) error {
+	switch {
+	case s.Kind == "bean" && s.Candidate != nil:
+		// Imported beans are merged with the bean as it is now, offerings included
+		action := importCreate
+		if s.Action == "edit" {
+			action = importUpdate
+		}
+		return h.applyImportedBean(ctx, action, s.Slug, *s.Candidate, s.SubmittedBy)
+
+	case s.Kind == "bean" && s.Bean != nil:
+		req := BeanReq{*s.Bean}
+		if s.Action == "add" {
+			exists, err := h.roasterNameExists(ctx, req.Roaster.Name)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return validationError("invalid roaster")
+			}
+			_, err = h.createBean(ctx, req, s.SubmittedBy)
+			return err
+		}
+		doc, err := h.getBeanDocBySlug(ctx, s.Slug)
+		if err != nil {
+			return err
+		}
+		return h.updateBean(ctx, doc.Ref, req, s.SubmittedBy)
+
+	case s.Kind == "roaster" && s.Roaster != nil:
+		req := RoasterReq{*s.Roaster}
+		if s.Action == "add" {
+			_, err := h.getRoasterDocBySlug(ctx, req.Slug)
+			if err == nil {
+				return conflictError("roaster already exists")
+			}
+			if err != errRoasterNotFound {
+				return err
+			}
+			_, err = h.createRoaster(ctx, req, s.SubmittedBy)
+			return err
+		}
+		doc, err := h.getRoasterDocBySlug(ctx, s.Slug)
+		if err != nil {
+			return err
+		}
+		return h.updateRoaster(ctx, doc.Ref, req, s.SubmittedBy)
+
+	case s.Kind == "review" && s.Review != nil:
+		_, err := h.createReview(ctx, *s.Review, s.SubmittedBy)
+		return err
+
+	case s.Kind == "offering" && s.Offering != nil:
+		return h.updateOffering(ctx, s.Slug, s.Action, *s.Offering, s.SubmittedBy)
+	}
+
+	return validationError("invalid submission")
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// Submission statuses
+const (
+	submissionPending  = "pending"
+	submissionApproved = "approved"
+	submissionRejected = "rejected"
+)
+
+// errSubmissionNotFound is returned when a submission doesn't exist
+var errSubmissionNotFound = notFoundError("submission not found")
+
+// Submission is a bean, roaster, review or offering write waiting for a moderator
+type Submission struct {
+	ID          string        `firestore:"-" json:"id"`
+	Kind        string        `firestore:"kind" json:"kind"`
+	Action      string        `firestore:"action" json:"action"`
+	Slug        string        `firestore:"slug" json:"slug"`
+	Bean        *Bean         `firestore:"bean,omitempty" json:"bean,omitempty"`
+	Candidate   *Bean         `firestore:"candidate,omitempty" json:"-"`
+	Roaster     *Roaster      `firestore:"roaster,omitempty" json:"roaster,omitempty"`
+	Review      *AddReviewReq `firestore:"review,omitempty" json:"review,omitempty"`
+	Offering    *Offering     `firestore:"offering,omitempty" json:"offering,omitempty"`
+	Status      string        `firestore:"status" json:"status"`
+	SubmittedBy string        `firestore:"submitted_by" json:"submitted_by"`
+	SubmittedAt time.Time     `firestore:"submitted_at" json:"submitted_at"`
+	ReviewedBy  string        `firestore:"reviewed_by" json:"reviewed_by,omitempty"`
+	ReviewedAt  time.Time     `firestore:"reviewed_at" json:"reviewed_at,omitempty"`
+	Comment     string        `firestore:"comment" json:"comment,omitempty"`
+	Changes     []FieldChange `firestore:"-" json:"changes,omitempty"`
+}
+
+// FieldChange is a single field that a submission changes
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// Notification is a message for a user, e.g. when their submission is reviewed
+type Notification struct {
+	User         string    `firestore:"user" json:"-"`
+	Message      string    `firestore:"message" json:"message"`
+	SubmissionID string    `firestore:"submission_id" json:"submission_id,omitempty"`
+	CreatedAt    time.Time `firestore:"created_at" json:"created_at"`
+}
+
+func docToSubmission(doc *firestore.DocumentSnapshot) Submission {
+	var s Submission
+	doc.DataTo(&s)
+	s.ID = doc.Ref.ID
+	return s
+}
+
+// isModerator checks if a user can review submissions
+func (h *Handler) isModerator(userEmail string) bool {
+	for _, m := range h.cfg.Moderators {
+		if userEmail != "" && strings.EqualFold(strings.TrimSpace(m), userEmail) {
+			return true
+		}
+	}
+	return false
+}
+
+// requiresModeration checks if writes from a user need to be approved first.
+// Moderators, contributors with enough approved submissions and contributors
+// with enough reputation skip the queue.
+func (h *Handler) requiresModeration(ctx context.Context, userEmail string) bool {
+	if !h.cfg.ModerationEnabled || h.isModerator(userEmail) {
+		return false
+	}
+
+	if h.hasReputationPermission(ctx, userEmail, permissionSkipModeration) {
+		return false
+	}
+
+	approved, err := h.database.Collection("submissions").
+		Where("submitted_by", "==", userEmail).
+		Where("status", "==", submissionApproved).
+		Limit(h.cfg.ModerationTrustThreshold).
+		Documents(ctx).
+		GetAll()
+	if err != nil {
+		h.logger.Error(err)
+		return true
+	}
+
+	return len(approved) < h.cfg.ModerationTrustThreshold
+}
+
+// submit adds a pending submission to the moderation queue
+func (h *Handler) submit(ctx context.Context, s Submission) (string, error) {
+	s.Status = submissionPending
+	s.SubmittedAt = time.Now()
+
+	doc, _, err := h.database.Collection("submissions").Add(ctx, s)
+	if err != nil {
+		return "", err
+	}
+	h.logger.Infow(
+		"Submission added",
+		"id", doc.ID,
+		"kind", s.Kind,
+		"action", s.Action,
+		"slug", s.Slug,
+		"submitted_by", s.SubmittedBy,
+	)
+
+	return doc.ID, nil
+}
+
+// notify leaves a notification for a user
+func (h *Handler) notify(ctx context.Context, n Notification) {
+	n.CreatedAt = time.Now()
+	if _, _, err := h.database.Collection("notifications").Add(ctx, n); err != nil {
+		h.logger.Error(err)
+	}
+}
+
+// submissionChanges diffs a submission against the current data
+func (h *Handler) submissionChanges(ctx context.Context, s Submission) []FieldChange {
+	var (
+		current  interface{}
+		proposed interface{}
+	)
+
+	switch s.Kind {
+	case "bean":
+		proposed = s.Bean
+		if s.Action == "edit" {
+			if doc, err := h.getBeanDocBySlug(ctx, s.Slug); err == nil {
+				current = docToBean(doc)
+			}
+		}
+	case "roaster":
+		proposed = s.Roaster
+		if s.Action == "edit" {
+			if doc, err := h.getRoasterDocBySlug(ctx, s.Slug); err == nil {
+				current = docToRoaster(doc)
+			}
+		}
+	case "review":
+		proposed = s.Review
+	case "offering":
+		if s.Action != offeringDelete {
+			proposed = s.Offering
+		}
+		if s.Action != offeringAdd && s.Offering != nil {
+			if doc, err := h.getBeanDocBySlug(ctx, s.Slug); err == nil {
+				offerings := docToBean(doc).Offerings
+				if i := findOffering(offerings, s.Offering.ID); i >= 0 {
+					current = offerings[i]
+				}
+			}
+		}
+	}
+
+	return diffFields(current, proposed)
+}
+
+// diffFields compares the JSON representations of two values and returns the
+// top-level fields that differ. Empty values are treated as equal.
+func diffFields(current, proposed interface{}) []FieldChange {
+	var (
+		changes []FieldChange
+		from    = toJSONMap(current)
+		to      = toJSONMap(proposed)
+		fields  []string
+	)
+
+	for k := range from {
+		fields = append(fields, k)
+	}
+	for k := range to {
+		if _, ok := from[k]; !ok {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+
+	for _, f := range fields {
+		a, b := from[f], to[f]
+		if isEmptyJSON(a) && isEmptyJSON(b) {
+			continue
+		}
+		if reflect.DeepEqual(a, b) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: f, From: a, To: b})
+	}
+
+	return changes
+}
+
+func toJSONMap(v interface{}) map[string]interface{} {
+	m := map[string]interface{}{}
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+		return m
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return m
+	}
+	json.Unmarshal(b, &m)
+	return m
+}
+
+// zeroTimeJSON is how an unset time.Time marshals to JSON
+var zeroTimeJSON = time.Time{}.Format(time.RFC3339Nano)
+
+func isEmptyJSON(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case string:
+		// Unset times marshal as the zero time
+		return t == "" || t == zeroTimeJSON
+	case bool:
+		return !t
+	case float64:
+		return t == 0
+	case []interface{}:
+		return len(t) == 0
+	case map[string]interface{}:
+		for _, e := range t {
+			if !isEmptyJSON(e) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_diffFields(t *testing.T) {
+	type test struct {
+		name     string
+		current  interface{}
+		proposed interface{}
+		exp      []FieldChange
+	}
+
+	tests := []test{
+		{
+			name:     "new bean lists non-empty fields",
+			current:  nil,
+			proposed: &Bean{Name: "Jumpstart", Flavors: []string{"caramel"}},
+			exp: []FieldChange{
+				{Field: "flavors", From: nil, To: []interface{}{"caramel"}},
+				{Field: "name", From: nil, To: "Jumpstart"},
+			},
+		},
+		{
+			name:     "edit only lists changed fields",
+			current:  Bean{Name: "Jumpstart", Shade: "light", Countries: []string{}},
+			proposed: &Bean{Name: "Jumpstart", Shade: "medium"},
+			exp: []FieldChange{
+				{Field: "shade", From: "light", To: "medium"},
+			},
+		},
+		{
+			name:     "review lists the rating and text",
+			current:  nil,
+			proposed: &AddReviewReq{Bean: "jumpstart", Rating: 4, Review: "Sweet"},
+			exp: []FieldChange{
+				{Field: "bean", From: nil, To: "jumpstart"},
+				{Field: "rating", From: nil, To: float64(4)},
+				{Field: "review", From: nil, To: "Sweet"},
+			},
+		},
+		{
+			name:     "no changes",
+			current:  Roaster{Name: "Ipsento", Slug: "ipsento"},
+			proposed: &Roaster{Name: "Ipsento", Slug: "ipsento"},
+			exp:      nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.exp, diffFields(tc.current, tc.proposed))
+		})
+	}
+}
+
+func Test_applyPatch(t *testing.T) {
+	var proposed Bean
+	assert.NoError(t, applyPatch(Bean{Name: "Gesha"}, map[string]interface{}{"year": 2021}, &proposed))
+	assert.Equal(t, "Gesha", proposed.Name)
+	assert.Equal(t, int64(2021), proposed.Year)
+
+	err := applyPatch(Bean{Name: "Gesha"}, map[string]interface{}{"year": "soon"}, &proposed)
+	if assert.IsType(t, &domainError{}, err) {
+		assert.Equal(t, codeValidation, err.(*domainError).code)
+	}
+}
@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// Grinds an offering can be sold as
+const (
+	grindWhole  = "whole"
+	grindGround = "ground"
+)
+
+// Offering is a bag of a bean a roaster sells
+type Offering struct {
+	ID           string    `firestore:"id" json:"id"`
+	CheckedAt    time.Time `firestore:"checked_at" json:"checked_at"`
+	Currency     string    `firestore:"currency" json:"currency"`
+	Grind        string    `firestore:"grind" json:"grind"`
+	InStock      bool      `firestore:"in_stock" json:"in_stock"`
+	Price        float64   `firestore:"price" json:"price"`
+	Subscription bool      `firestore:"subscription" json:"subscription"`
+	URL          string    `firestore:"url" json:"url"`
+	WeightGrams  int64     `firestore:"weight_grams" json:"weight_grams"`
+}
+
+// OfferingBQ represents an offering in the bean changelog
+type OfferingBQ struct {
+	ID           string
+	CheckedAt    string
+	Currency     string
+	Grind        string
+	InStock      bool
+	Price        float64
+	Subscription bool
+	URL          string
+	WeightGrams  int64
+}
+
+// Price is an amount in a currency
+type Price struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+}
+
+// OfferingResp is the response from the offering endpoints
+type OfferingResp struct {
+	Offering     *Offering `json:"offering,omitempty"`
+	SubmissionID string    `json:"submission_id,omitempty"`
+}
+
+// errOfferingNotFound is returned when a bean has no offering with an ID
+var errOfferingNotFound = notFoundError("offering not found")
+
+// errUnknownCurrency is returned when there's no exchange rate for a currency
+var errUnknownCurrency = validationError("unknown currency")
+
+// ExchangeRates is the value of one unit of each currency in a base currency
+type ExchangeRates map[string]float64
+
+// convert converts an amount between currencies
+func (rates ExchangeRates) convert(amount float64, from, to string) (float64, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return amount, nil
+	}
+	f, ok := rates[from]
+	if !ok || f <= 0 {
+		return 0, errUnknownCurrency
+	}
+	t, ok := rates[to]
+	if !ok || t <= 0 {
+		return 0, errUnknownCurrency
+	}
+	return amount * f / t, nil
+}
+
+// exchangeRates returns the configured exchange rates, always including the
+// base currency
+func (h *Handler) exchangeRates() ExchangeRates {
+	var rates = make(ExchangeRates)
+	for c, r := range h.cfg.ExchangeRates {
+		rates[strings.ToUpper(c)] = r
+	}
+	rates[strings.ToUpper(h.cfg.BaseCurrency)] = 1
+	return rates
+}
+
+func newOfferingID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// normalizeOffering validates an offering and fills in its defaults
+func normalizeOffering(o *Offering, rates ExchangeRates) error {
+	o.Currency = strings.ToUpper(strings.TrimSpace(o.Currency))
+	if _, ok := rates[o.Currency]; !ok {
+		return fmt.Errorf("unknown currency %q", o.Currency)
+	}
+	if o.Price <= 0 {
+		return fmt.Errorf("price must be positive")
+	}
+	if o.WeightGrams <= 0 {
+		return fmt.Errorf("weight_grams must be positive")
+	}
+
+	o.Grind = strings.ToLower(strings.TrimSpace(o.Grind))
+	switch o.Grind {
+	case "", "whole bean", "beans":
+		o.Grind = grindWhole
+	case grindWhole, grindGround:
+	default:
+		return fmt.Errorf("grind must be %s or %s", grindWhole, grindGround)
+	}
+
+	o.URL = strings.TrimSpace(o.URL)
+	if o.CheckedAt.IsZero() {
+		o.CheckedAt = time.Now()
+	}
+
+	return nil
+}
+
+// pricePer100g normalizes the price of an offering to 100g
+func (o Offering) pricePer100g(rates ExchangeRates, currency string) (float64, error) {
+	price, err := rates.convert(o.Price, o.Currency, currency)
+	if err != nil {
+		return 0, err
+	}
+	return math.Round(price/float64(o.WeightGrams)*100*100) / 100, nil
+}
+
+// lowestPricePer100g returns the cheapest in-stock price of a bean per 100g
+func lowestPricePer100g(b Bean, rates ExchangeRates, currency string) *Price {
+	var lowest *Price
+	for _, o := range b.Offerings {
+		if !o.InStock || o.WeightGrams <= 0 {
+			continue
+		}
+		p, err := o.pricePer100g(rates, currency)
+		if err != nil {
+			continue
+		}
+		if lowest == nil || p < lowest.Amount {
+			lowest = &Price{Amount: p, Currency: strings.ToUpper(currency)}
+		}
+	}
+	return lowest
+}
+
+// findOffering returns the index of an offering by ID
+func findOffering(offerings []Offering, id string) int {
+	for i, o := range offerings {
+		if o.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Offering changes
+const (
+	offeringAdd    = "add"
+	offeringEdit   = "edit"
+	offeringDelete = "delete"
+)
+
+// changeOfferings applies an add, edit or delete of an offering to the
+// offerings of a bean
+func changeOfferings(offerings []Offering, action string, o Offering) ([]Offering, error) {
+	var out = append([]Offering{}, offerings...)
+	if action == offeringAdd {
+		return append(out, o), nil
+	}
+
+	i := findOffering(out, o.ID)
+	if i < 0 {
+		return nil, errOfferingNotFound
+	}
+	switch action {
+	case offeringEdit:
+		out[i] = o
+	case offeringDelete:
+		out = append(out[:i], out[i+1:]...)
+	default:
+		return nil, fmt.Errorf("invalid offering change %q", action)
+	}
+	return out, nil
+}
+
+// updateOffering adds, edits or deletes an offering of a bean
+func (h *Handler) updateOffering(ctx context.Context, slug string, action string, o Offering, userEmail string) error {
+	doc, err := h.getBeanDocBySlug(ctx, slug)
+	if err != nil {
+		return err
+	}
+
+	return h.updateOfferings(ctx, doc.Ref, func(offerings []Offering) ([]Offering, error) {
+		return changeOfferings(offerings, action, o)
+	}, userEmail)
+}
+
+// updateOfferings changes the offerings of a bean and records the change. The
+// offerings are read and written in one transaction so concurrent changes
+// aren't lost.
+func (h *Handler) updateOfferings(ctx context.Context, ref *firestore.DocumentRef, change func([]Offering) ([]Offering, error), userEmail string) error {
+	var bean Bean
+	err := h.database.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		bean = docToBean(doc)
+
+		bean.Offerings, err = change(bean.Offerings)
+		if err != nil {
+			return err
+		}
+		return tx.Update(ref, []firestore.Update{
+			{Path: "offerings", Value: bean.Offerings},
+		})
+	})
+	if err != nil {
+		return err
+	}
+	h.logger.Infow(
+		"Offerings updated",
+		"id", ref.ID,
+		"updated_by", userEmail,
+	)
+
+	h.recordBeanChange(BeanReq{bean}, userEmail)
+
+	return nil
+}
+
+// submitOffering queues an offering change for a moderator
+func (h *Handler) submitOffering(ctx context.Context, slug string, action string, o Offering, userEmail string) (string, error) {
+	return h.submit(ctx, Submission{
+		Kind:        "offering",
+		Action:      action,
+		Slug:        slug,
+		Offering:    &o,
+		SubmittedBy: userEmail,
+	})
+}
+
+func offeringsToBQ(offerings []Offering) []OfferingBQ {
+	var items = []OfferingBQ{}
+	for _, o := range offerings {
+		items = append(items, OfferingBQ{
+			ID:           o.ID,
+			CheckedAt:    o.CheckedAt.Format(time.RFC3339),
+			Currency:     o.Currency,
+			Grind:        o.Grind,
+			InStock:      o.InStock,
+			Price:        o.Price,
+			Subscription: o.Subscription,
+			URL:          o.URL,
+			WeightGrams:  o.WeightGrams,
+		})
+	}
+	return items
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testRates = ExchangeRates{"USD": 1, "EUR": 1.2, "GBP": 1.3}
+
+func Test_normalizeOffering(t *testing.T) {
+	o := Offering{Currency: "eur", Price: 12, WeightGrams: 250}
+	assert.NoError(t, normalizeOffering(&o, testRates))
+	assert.Equal(t, "EUR", o.Currency)
+	assert.Equal(t, grindWhole, o.Grind)
+	assert.False(t, o.CheckedAt.IsZero())
+
+	assert.Error(t, normalizeOffering(&Offering{Currency: "XYZ", Price: 12, WeightGrams: 250}, testRates))
+	assert.Error(t, normalizeOffering(&Offering{Currency: "USD", Price: 0, WeightGrams: 250}, testRates))
+	assert.Error(t, normalizeOffering(&Offering{Currency: "USD", Price: 12, WeightGrams: 250, Grind: "coarse"}, testRates))
+}
+
+func Test_lowestPricePer100g(t *testing.T) {
+	b := Bean{Offerings: []Offering{
+		{Currency: "USD", Price: 18, WeightGrams: 340, InStock: true},
+		{Currency: "EUR", Price: 12, WeightGrams: 250, InStock: true},
+		{Currency: "USD", Price: 1, WeightGrams: 1000, InStock: false},
+	}}
+
+	assert.Equal(t, &Price{Amount: 5.29, Currency: "USD"}, lowestPricePer100g(b, testRates, "USD"))
+	assert.Equal(t, &Price{Amount: 4.41, Currency: "EUR"}, lowestPricePer100g(b, testRates, "EUR"))
+	assert.Nil(t, lowestPricePer100g(Bean{}, testRates, "USD"))
+
+	f := BeanFilter{MaxPricePer100g: 5}
+	b.PricePer100g = lowestPricePer100g(b, testRates, "USD")
+	assert.False(t, f.Match(b))
+	f.MaxPricePer100g = 6
+	assert.True(t, f.Match(b))
+}
+
+func Test_changeOfferings(t *testing.T) {
+	var (
+		bag  = Offering{ID: "a", Currency: "USD", Price: 18, WeightGrams: 340}
+		kilo = Offering{ID: "b", Currency: "USD", Price: 45, WeightGrams: 1000}
+	)
+
+	type test struct {
+		name     string
+		action   string
+		offering Offering
+		exp      []Offering
+		wantErr  bool
+	}
+
+	tests := []test{
+		{name: "add", action: offeringAdd, offering: Offering{ID: "c"}, exp: []Offering{bag, kilo, {ID: "c"}}},
+		{name: "edit", action: offeringEdit, offering: Offering{ID: "b", Price: 40}, exp: []Offering{bag, {ID: "b", Price: 40}}},
+		{name: "delete", action: offeringDelete, offering: Offering{ID: "a"}, exp: []Offering{kilo}},
+		{name: "edit a missing offering", action: offeringEdit, offering: Offering{ID: "z"}, wantErr: true},
+		{name: "delete a missing offering", action: offeringDelete, offering: Offering{ID: "z"}, wantErr: true},
+		{name: "unknown change", action: "swap", offering: Offering{ID: "a"}, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			offerings := []Offering{bag, kilo}
+			out, err := changeOfferings(offerings, tc.action, tc.offering)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.exp, out)
+			// The stored offerings are left alone
+			assert.Equal(t, []Offering{bag, kilo}, offerings)
+		})
+	}
+}
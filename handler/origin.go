@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Altitude is the elevation range a coffee was grown at, in meters
+type Altitude struct {
+	Min int64 `firestore:"min" json:"min"`
+	Max int64 `firestore:"max" json:"max"`
+}
+
+// varietals is the controlled vocabulary of coffee varietals, keyed by
+// lowercase aliases
+var varietals = vocabulary(map[string][]string{
+	"74110":              nil,
+	"74112":              nil,
+	"Batian":             nil,
+	"Bourbon":            {"red bourbon"},
+	"Castillo":           nil,
+	"Catimor":            nil,
+	"Catuai":             {"catuaí", "red catuai", "yellow catuai"},
+	"Caturra":            {"red caturra", "yellow caturra"},
+	"Colombia":           nil,
+	"Ethiopian Landrace": {"heirloom", "ethiopian heirloom", "landrace", "ethiopian landraces", "indigenous landrace"},
+	"Excelsa":            nil,
+	"Gesha":              {"geisha", "panama gesha", "ethiopian gesha"},
+	"Java":               nil,
+	"Kent":               nil,
+	"Laurina":            {"bourbon pointu"},
+	"Liberica":           nil,
+	"Maragogype":         {"maragogipe"},
+	"Marsellesa":         nil,
+	"Mokka":              {"mocha", "moka"},
+	"Mundo Novo":         nil,
+	"Obata":              nil,
+	"Pacamara":           nil,
+	"Pacas":              nil,
+	"Parainema":          nil,
+	"Pink Bourbon":       nil,
+	"Robusta":            {"canephora"},
+	"Ruiru 11":           {"ruiru"},
+	"S795":               {"s 795"},
+	"Sarchimor":          nil,
+	"Sidra":              nil,
+	"SL28":               {"sl 28", "sl-28"},
+	"SL34":               {"sl 34", "sl-34"},
+	"Tabi":               nil,
+	"Typica":             nil,
+	"Villa Sarchi":       {"villa sarchí"},
+	"Wush Wush":          nil,
+	"Yellow Bourbon":     nil,
+})
+
+// processes is the controlled vocabulary of processing methods, keyed by
+// lowercase aliases
+var processes = vocabulary(map[string][]string{
+	"Anaerobic":           {"anaerobic natural", "anaerobic washed", "anaerobic fermentation"},
+	"Carbonic Maceration": {"carbonic"},
+	"Honey":               {"white honey", "yellow honey", "red honey", "black honey"},
+	"Natural":             {"dry", "dry processed", "sun dried", "sun-dried", "unwashed"},
+	"Pulped Natural":      nil,
+	"Semi-Washed":         {"semi washed"},
+	"Washed":              {"fully washed", "wet", "wet processed"},
+	"Wet-Hulled":          {"wet hulled", "giling basah"},
+})
+
+// vocabulary indexes canonical terms and their aliases by lowercase name
+func vocabulary(terms map[string][]string) map[string]string {
+	var v = make(map[string]string)
+	for term, aliases := range terms {
+		v[strings.ToLower(term)] = term
+		for _, a := range aliases {
+			v[a] = term
+		}
+	}
+	return v
+}
+
+// canonicalTerm looks up a term in a vocabulary, ignoring case and whitespace
+func canonicalTerm(v map[string]string, term string) (string, bool) {
+	t, ok := v[strings.ToLower(strings.Join(strings.Fields(term), " "))]
+	return t, ok
+}
+
+// vocabularyTerms lists the canonical terms of a vocabulary
+func vocabularyTerms(v map[string]string) []string {
+	var (
+		terms []string
+		seen  = make(map[string]bool)
+	)
+	for _, t := range v {
+		if !seen[t] {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// normalizeOrigin canonicalizes the varietals and process of a bean and
+// validates them against the controlled vocabularies. Values kept from the
+// stored bean that predate the vocabularies are left as they are.
+func normalizeOrigin(b *Bean, stored Bean) error {
+	var normalized []string
+	for _, v := range b.Varietals {
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+		t, ok := canonicalTerm(varietals, v)
+		if !ok && containsString(stored.Varietals, v) {
+			t, ok = v, true
+		}
+		if !ok {
+			return fmt.Errorf("unknown varietal %q, expected one of: %s", v, strings.Join(vocabularyTerms(varietals), ", "))
+		}
+		if !containsString(normalized, t) {
+			normalized = append(normalized, t)
+		}
+	}
+	b.Varietals = normalized
+
+	if strings.TrimSpace(b.Process) != "" {
+		t, ok := canonicalTerm(processes, b.Process)
+		if !ok && b.Process == stored.Process {
+			t, ok = b.Process, true
+		}
+		if !ok {
+			return fmt.Errorf("unknown process %q, expected one of: %s", b.Process, strings.Join(vocabularyTerms(processes), ", "))
+		}
+		b.Process = t
+	} else {
+		b.Process = ""
+	}
+
+	if a := b.Altitude; a != nil {
+		if a.Max == 0 {
+			a.Max = a.Min
+		}
+		if a.Min < 0 || a.Min > a.Max {
+			return fmt.Errorf("invalid altitude")
+		}
+		if a.Min == 0 && a.Max == 0 {
+			b.Altitude = nil
+		}
+	}
+
+	b.Region = strings.TrimSpace(b.Region)
+	b.Farm = strings.TrimSpace(b.Farm)
+	b.Producer = strings.TrimSpace(b.Producer)
+
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
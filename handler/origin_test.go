@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_normalizeOrigin(t *testing.T) {
+	b := Bean{
+		Varietals: []string{"geisha", " SL 28 ", "Gesha", ""},
+		Process:   "Fully Washed",
+		Altitude:  &Altitude{Min: 1800},
+	}
+	assert.NoError(t, normalizeOrigin(&b, Bean{}))
+	assert.Equal(t, []string{"Gesha", "SL28"}, b.Varietals)
+	assert.Equal(t, "Washed", b.Process)
+	assert.Equal(t, &Altitude{Min: 1800, Max: 1800}, b.Altitude)
+
+	assert.Error(t, normalizeOrigin(&Bean{Varietals: []string{"arabica-ish"}}, Bean{}))
+	assert.Error(t, normalizeOrigin(&Bean{Process: "boiled"}, Bean{}))
+	assert.Error(t, normalizeOrigin(&Bean{Altitude: &Altitude{Min: 2000, Max: 1500}}, Bean{}))
+
+	// Stored values from before the vocabularies can be kept, but not added
+	legacy := Bean{Varietals: []string{"arabica-ish"}, Process: "boiled"}
+	edit := Bean{Varietals: []string{"arabica-ish", "geisha"}, Process: "boiled"}
+	assert.NoError(t, normalizeOrigin(&edit, legacy))
+	assert.Equal(t, []string{"arabica-ish", "Gesha"}, edit.Varietals)
+	assert.Equal(t, "boiled", edit.Process)
+	assert.Error(t, normalizeOrigin(&Bean{Varietals: []string{"mystery"}}, legacy))
+	assert.Error(t, normalizeOrigin(&Bean{Process: "steamed"}, legacy))
+}
+
+func Test_countOrigins(t *testing.T) {
+	beans := []Bean{
+		{Countries: []string{"Ethiopia"}, Region: "Yirgacheffe"},
+		{Countries: []string{"ethiopia"}, Region: "yirgacheffe"},
+		{Countries: []string{"Ethiopia"}, Region: "Guji"},
+		{Countries: []string{"Ethiopia", "Kenya"}, Region: "Nyeri"},
+	}
+
+	assert.Equal(t, []OriginCount{
+		{Country: "Ethiopia", Count: 4, Regions: []RegionCount{
+			{Region: "Yirgacheffe", Count: 2},
+			{Region: "Guji", Count: 1},
+		}},
+		{Country: "Kenya", Count: 1, Regions: []RegionCount{}},
+	}, countOrigins(beans))
+}
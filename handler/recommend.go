@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// Weights of each signal when comparing two beans
+const (
+	flavorWeight    = 3.0
+	countryWeight   = 2.0
+	shadeWeight     = 1.0
+	proximityWeight = 1.0
+
+	// proximityRangeKm is the distance at which roasters stop counting as close
+	proximityRangeKm = 1000.0
+
+	// highRating is the rating from which a review counts as liking a bean
+	highRating = 4.0
+)
+
+// Recommendation is a bean with the score and reason it was recommended
+type Recommendation struct {
+	Bean   Bean    `json:"bean"`
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"`
+}
+
+// RecommendationsResp is the response for the recommendation endpoints
+type RecommendationsResp struct {
+	Beans []Recommendation `json:"beans"`
+}
+
+// rating is a single review score from the reviews table
+type rating struct {
+	UserID  int
+	BeanRef string
+	Rating  float64
+}
+
+// jaccard returns the overlap of two sets of strings, ignoring case
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	set := make(map[string]bool)
+	for _, s := range a {
+		set[strings.ToLower(strings.TrimSpace(s))] = true
+	}
+
+	var (
+		intersection int
+		union        = len(set)
+		seen         = make(map[string]bool)
+	)
+	for _, s := range b {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		if set[s] {
+			intersection++
+		} else {
+			union++
+		}
+	}
+
+	return float64(intersection) / float64(union)
+}
+
+// similarityScore compares two beans by flavors, countries, shade and how
+// close their roasters are. distanceKm is negative when it is unknown.
+func similarityScore(a, b Bean, distanceKm float64) float64 {
+	score := flavorWeight*jaccard(a.Flavors, b.Flavors) +
+		countryWeight*jaccard(a.Countries, b.Countries)
+
+	switch {
+	case a.RoastLevel > 0 && b.RoastLevel > 0:
+		diff := math.Abs(float64(a.RoastLevel - b.RoastLevel))
+		score += shadeWeight * (1 - diff/(roastDark-roastLight))
+	case a.Shade != "" && strings.EqualFold(a.Shade, b.Shade):
+		score += shadeWeight
+	}
+
+	switch {
+	case a.Roaster.Slug != "" && a.Roaster.Slug == b.Roaster.Slug:
+		score += proximityWeight
+	case distanceKm >= 0 && distanceKm < proximityRangeKm:
+		score += proximityWeight * (1 - distanceKm/proximityRangeKm)
+	}
+
+	return score
+}
+
+// collaborativeScores predicts ratings of unrated beans for a user from the
+// ratings of users with similar taste (user-based, mean-centered cosine)
+func collaborativeScores(ratings []rating, userID int) map[string]float64 {
+	var (
+		byUser = make(map[int]map[string]float64)
+		scores = make(map[string]float64)
+	)
+	for _, r := range ratings {
+		if byUser[r.UserID] == nil {
+			byUser[r.UserID] = make(map[string]float64)
+		}
+		byUser[r.UserID][r.BeanRef] = r.Rating
+	}
+
+	mine, ok := byUser[userID]
+	if !ok {
+		return scores
+	}
+
+	mean := func(m map[string]float64) float64 {
+		var sum float64
+		for _, v := range m {
+			sum += v
+		}
+		return sum / float64(len(m))
+	}
+	myMean := mean(mine)
+
+	var (
+		weighted = make(map[string]float64)
+		weights  = make(map[string]float64)
+	)
+	for other, theirs := range byUser {
+		if other == userID {
+			continue
+		}
+		theirMean := mean(theirs)
+
+		// Similarity over the beans both users rated
+		var dot, normA, normB float64
+		for bean, mr := range mine {
+			tr, ok := theirs[bean]
+			if !ok {
+				continue
+			}
+			a, b := mr-myMean, tr-theirMean
+			dot += a * b
+			normA += a * a
+			normB += b * b
+		}
+		if dot <= 0 || normA == 0 || normB == 0 {
+			continue
+		}
+		sim := dot / (math.Sqrt(normA) * math.Sqrt(normB))
+
+		for bean, tr := range theirs {
+			if _, rated := mine[bean]; rated {
+				continue
+			}
+			weighted[bean] += sim * (tr - theirMean)
+			weights[bean] += sim
+		}
+	}
+
+	for bean, w := range weights {
+		scores[bean] = myMean + weighted[bean]/w
+	}
+
+	return scores
+}
+
+// popularityScores ranks beans by their Bayesian average rating, so beans
+// with a handful of perfect ratings don't outrank well reviewed ones
+func popularityScores(ratings []rating) map[string]float64 {
+	var (
+		sums   = make(map[string]float64)
+		counts = make(map[string]float64)
+		scores = make(map[string]float64)
+		total  float64
+	)
+	for _, r := range ratings {
+		sums[r.BeanRef] += r.Rating
+		counts[r.BeanRef]++
+		total += r.Rating
+	}
+	if len(ratings) == 0 {
+		return scores
+	}
+
+	var (
+		globalMean = total / float64(len(ratings))
+		prior      = float64(len(ratings)) / float64(len(counts))
+	)
+	for bean, sum := range sums {
+		scores[bean] = (prior*globalMean + sum) / (prior + counts[bean])
+	}
+
+	return scores
+}
+
+// topRecommendations sorts recommendations by score and keeps the first n
+func topRecommendations(recs []Recommendation, n int) []Recommendation {
+	sort.SliceStable(recs, func(i, j int) bool {
+		return recs[i].Score > recs[j].Score
+	})
+	if len(recs) > n {
+		recs = recs[:n]
+	}
+	return recs
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_similarityScore(t *testing.T) {
+	base := Bean{
+		Flavors:   []string{"Chocolate", "cherry"},
+		Countries: []string{"Ethiopia"},
+		Shade:     "light",
+		Roaster:   RoasterMap{Slug: "ipsento"},
+	}
+
+	same := similarityScore(base, base, -1)
+	assert.Equal(t, maxSimilarityScore, same)
+
+	other := Bean{
+		Flavors:   []string{"chocolate", "caramel"},
+		Countries: []string{"Brazil"},
+		Shade:     "dark",
+		Roaster:   RoasterMap{Slug: "partners-coffee"},
+	}
+	assert.InDelta(t, flavorWeight/3, similarityScore(base, other, -1), 0.0001)
+	assert.InDelta(t, flavorWeight/3+proximityWeight/2, similarityScore(base, other, proximityRangeKm/2), 0.0001)
+	assert.InDelta(t, flavorWeight/3, similarityScore(base, other, proximityRangeKm*2), 0.0001)
+}
+
+func Test_collaborativeScores(t *testing.T) {
+	ratings := []rating{
+		// User 1 and 2 agree, user 3 has the opposite taste
+		{UserID: 1, BeanRef: "a", Rating: 5},
+		{UserID: 1, BeanRef: "b", Rating: 1},
+		{UserID: 2, BeanRef: "a", Rating: 5},
+		{UserID: 2, BeanRef: "b", Rating: 2},
+		{UserID: 2, BeanRef: "c", Rating: 5},
+		{UserID: 3, BeanRef: "a", Rating: 1},
+		{UserID: 3, BeanRef: "b", Rating: 5},
+		{UserID: 3, BeanRef: "d", Rating: 5},
+	}
+
+	scores := collaborativeScores(ratings, 1)
+
+	// Only similar users contribute and rated beans are skipped
+	assert.Contains(t, scores, "c")
+	assert.NotContains(t, scores, "d")
+	assert.NotContains(t, scores, "a")
+	assert.Greater(t, scores["c"], 3.0)
+
+	assert.Empty(t, collaborativeScores(ratings, 42))
+}
+
+func Test_popularityScores(t *testing.T) {
+	ratings := []rating{
+		{UserID: 1, BeanRef: "a", Rating: 5},
+		{UserID: 1, BeanRef: "b", Rating: 4},
+		{UserID: 2, BeanRef: "b", Rating: 5},
+		{UserID: 3, BeanRef: "b", Rating: 5},
+		{UserID: 4, BeanRef: "b", Rating: 5},
+		{UserID: 5, BeanRef: "c", Rating: 1},
+	}
+
+	scores := popularityScores(ratings)
+	assert.Greater(t, scores["b"], scores["a"])
+	assert.Greater(t, scores["a"], scores["c"])
+}
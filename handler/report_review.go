@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// maxReportReasonLength caps the size of a report reason
+const maxReportReasonLength = 500
+
+// ReportReviewReq is the request body for the POST /reviews/{id}/report endpoint
+type ReportReviewReq struct {
+	Reason string `json:"reason"`
+}
+
+// ReportReviewResp is the response from the POST /reviews/{id}/report endpoint
+type ReportReviewResp struct {
+	ReportCount int  `json:"report_count"`
+	Hidden      bool `json:"hidden"`
+}
+
+// reportReview flags a review for moderation. Once a review collects
+// ReviewReportThreshold reports it is hidden until a moderator looks at it.
+func (h *Handler) reportReview(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		vars      = mux.Vars(r)
+		err       error
+		req       ReportReviewReq
+		resp      = &ReportReviewResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	if !h.cfg.ReviewsEnabled {
+		h.writeError(w, r, notFoundError("reviews are disabled"))
+		return
+	}
+
+	reviewID, err := parseReviewID(vars["id"])
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.writeError(w, r, bodyError(err))
+		return
+	}
+
+	req.Reason, err = normalizeReportReason(req.Reason)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	// Resolve the reporter
+	u, err := h.getUserByEmail(ctx, userEmail)
+	if err != nil {
+		h.writeError(w, r, validationError("invalid user"))
+		return
+	}
+	userID, err := h.getPostgresUserID(ctx, u.Username)
+	if err != nil {
+		h.writeError(w, r, validationError("invalid user"))
+		return
+	}
+
+	// Make sure the review exists
+	_, err = h.getReviewAuthorID(ctx, reviewID)
+	if err == errReviewNotFound {
+		h.writeError(w, r, err)
+		return
+	}
+	if err != nil {
+		h.logger.Error(err)
+		h.writeError(w, r, err)
+		return
+	}
+
+	// Record the report and hide the review once it crosses the threshold
+	tx, err := h.postgres.BeginTx(ctx, nil)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO review_reports (review_id, user_id, reason)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (review_id, user_id) DO NOTHING;
+	`, reviewID, userID, req.Reason)
+	if err != nil {
+		h.logger.Error(err)
+		h.writeError(w, r, err)
+		return
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE reviews SET
+			report_count = c.count,
+			hidden = hidden OR c.count >= $2
+		FROM (SELECT count(*) AS count FROM review_reports WHERE review_id = $1) c
+		WHERE review_id = $1
+		RETURNING report_count, hidden;
+	`, reviewID, h.cfg.ReviewReportThreshold).Scan(&resp.ReportCount, &resp.Hidden)
+	if err != nil {
+		h.logger.Error(err)
+		h.writeError(w, r, err)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.logger.Infow(
+		"Review reported",
+		"review_id", reviewID,
+		"report_count", resp.ReportCount,
+		"hidden", resp.Hidden,
+		"reported_by", userEmail,
+	)
+
+	w.WriteHeader(http.StatusAccepted)
+
+	json.NewEncoder(w).Encode(resp)
+}
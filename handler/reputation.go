@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Reputation points for each contribution
+const (
+	beanAddedPoints    = 10
+	roasterAddedPoints = 15
+	editPoints         = 2
+	flavorEditPoints   = 1
+	reviewPoints       = 5
+	helpfulVotePoints  = 1
+)
+
+// Limits that keep reviews from being farmed for reputation: only reviews
+// with some text count, and only up to maxReviewPoints
+const (
+	minReviewLength = 20
+	maxReviewPoints = 25
+)
+
+// minTrustedAccountAge is how old an account must be before its reputation
+// can skip moderation
+const minTrustedAccountAge = 30 * 24 * time.Hour
+
+// reputationCacheTTL is how long a computed reputation is reused, so writes
+// don't query the changelog every time
+const reputationCacheTTL = 10 * time.Minute
+
+// Permissions unlocked by reputation
+const permissionSkipModeration = "skip_moderation"
+
+// Badge is an award for a kind of contribution
+type Badge struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Reputation is the score, badges and permissions a user earned
+type Reputation struct {
+	Score         int64         `json:"score"`
+	Badges        []Badge       `json:"badges"`
+	Permissions   []string      `json:"permissions"`
+	Contributions Contributions `json:"contributions"`
+}
+
+// badgeRule awards a badge when a user's contributions qualify
+type badgeRule struct {
+	Badge
+	earned func(c Contributions) bool
+}
+
+// badgeRules are the badges that can be earned, in display order
+var badgeRules = []badgeRule{
+	{
+		Badge: Badge{ID: "first_bean", Name: "First bean", Description: "Added a bean"},
+		earned: func(c Contributions) bool {
+			return c.BeansAdded >= 1
+		},
+	},
+	{
+		Badge: Badge{ID: "roaster_scout", Name: "Roaster scout", Description: "Added 10 roasters"},
+		earned: func(c Contributions) bool {
+			return c.RoastersAdded >= 10
+		},
+	},
+	{
+		Badge: Badge{ID: "critic", Name: "Critic", Description: "Wrote 50 reviews"},
+		earned: func(c Contributions) bool {
+			return c.Reviews >= 50
+		},
+	},
+	{
+		Badge: Badge{ID: "flavor_curator", Name: "Flavor curator", Description: "Fixed the flavors of 25 beans"},
+		earned: func(c Contributions) bool {
+			return c.FlavorEdits >= 25
+		},
+	},
+}
+
+// scoreReputation computes the score, badges and permissions from a user's
+// contributions. trustThreshold is the score that skips moderation, for
+// accounts at least minTrustedAccountAge old.
+func scoreReputation(c Contributions, accountAge time.Duration, trustThreshold int64) Reputation {
+	reviews := c.Reviews*reviewPoints + c.HelpfulVotes*helpfulVotePoints
+	if reviews > maxReviewPoints {
+		reviews = maxReviewPoints
+	}
+
+	r := Reputation{
+		Score: c.BeansAdded*beanAddedPoints +
+			c.RoastersAdded*roasterAddedPoints +
+			(c.BeanEdits+c.RoasterEdits)*editPoints +
+			c.FlavorEdits*flavorEditPoints +
+			reviews,
+		Badges:        []Badge{},
+		Permissions:   []string{},
+		Contributions: c,
+	}
+
+	for _, rule := range badgeRules {
+		if rule.earned(c) {
+			r.Badges = append(r.Badges, rule.Badge)
+		}
+	}
+
+	if trustThreshold > 0 && r.Score >= trustThreshold && accountAge >= minTrustedAccountAge {
+		r.Permissions = append(r.Permissions, permissionSkipModeration)
+	}
+
+	return r
+}
+
+// hasPermission checks if a reputation unlocked a permission
+func (r Reputation) hasPermission(permission string) bool {
+	return containsString(r.Permissions, permission)
+}
+
+// reputationCache holds recently computed reputations by email
+type reputationCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedReputation
+}
+
+type cachedReputation struct {
+	Reputation
+	computedAt time.Time
+}
+
+// reputation computes the reputation of a user from the changelog and their
+// reviews
+func (h *Handler) reputation(ctx context.Context, user UserDB) (Reputation, error) {
+	c := h.reputations
+	c.mu.Lock()
+	cached, ok := c.entries[user.Email]
+	c.mu.Unlock()
+	if ok && time.Since(cached.computedAt) < reputationCacheTTL {
+		return cached.Reputation, nil
+	}
+
+	contributions, err := h.analytics.Contributions(ctx, user.Email)
+	if err != nil {
+		return Reputation{}, err
+	}
+
+	// Hidden reviews and reviews without much text don't count
+	if h.cfg.ReviewsEnabled {
+		err = h.postgres.QueryRowContext(ctx, `
+			SELECT count(*), COALESCE(sum(r.helpful_count), 0)
+			FROM reviews r
+			JOIN users u on r.user_id = u.user_id
+			WHERE u.username = $1 AND NOT r.hidden AND length(trim(r.review)) >= $2;
+		`, user.Username, minReviewLength).Scan(&contributions.Reviews, &contributions.HelpfulVotes)
+		if err != nil {
+			return Reputation{}, err
+		}
+	}
+
+	r := scoreReputation(contributions, time.Since(user.CreatedAt), h.cfg.ReputationTrustThreshold)
+
+	c.mu.Lock()
+	c.entries[user.Email] = cachedReputation{Reputation: r, computedAt: time.Now()}
+	c.mu.Unlock()
+
+	return r, nil
+}
+
+// hasReputationPermission checks if a user's reputation unlocked a permission.
+// Users without a profile or whose reputation can't be computed have none.
+func (h *Handler) hasReputationPermission(ctx context.Context, userEmail string, permission string) bool {
+	if h.cfg.ReputationTrustThreshold <= 0 {
+		return false
+	}
+
+	user, err := h.getUserByEmail(ctx, userEmail)
+	if err != nil {
+		if err != errUserNotFound {
+			h.logger.Errorw("Error fetching user", "user", userEmail, "error", err)
+		}
+		return false
+	}
+
+	reputation, err := h.reputation(ctx, user)
+	if err != nil {
+		h.logger.Errorw("Error computing reputation", "user", userEmail, "error", err)
+		return false
+	}
+
+	return reputation.hasPermission(permission)
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/mager/cafebean-api/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_scoreReputation(t *testing.T) {
+	old := 365 * 24 * time.Hour
+
+	r := scoreReputation(Contributions{}, old, 100)
+	assert.Equal(t, int64(0), r.Score)
+	assert.Empty(t, r.Badges)
+	assert.False(t, r.hasPermission(permissionSkipModeration))
+
+	r = scoreReputation(Contributions{
+		BeansAdded:    2,
+		RoastersAdded: 10,
+		BeanEdits:     30,
+		FlavorEdits:   25,
+		Reviews:       3,
+		HelpfulVotes:  4,
+	}, old, 100)
+	assert.Equal(t, int64(2*10+10*15+30*2+25+3*5+4), r.Score)
+	var badges []string
+	for _, b := range r.Badges {
+		badges = append(badges, b.ID)
+	}
+	assert.Equal(t, []string{"first_bean", "roaster_scout", "flavor_curator"}, badges)
+	assert.True(t, r.hasPermission(permissionSkipModeration))
+
+	r = scoreReputation(Contributions{BeansAdded: 50}, old, 0)
+	assert.False(t, r.hasPermission(permissionSkipModeration))
+
+	// Reviews alone can't reach the trust threshold
+	r = scoreReputation(Contributions{Reviews: 40, HelpfulVotes: 100}, old, 100)
+	assert.Equal(t, int64(maxReviewPoints), r.Score)
+	assert.False(t, r.hasPermission(permissionSkipModeration))
+
+	// New accounts go through moderation whatever their score
+	r = scoreReputation(Contributions{BeansAdded: 50}, 24*time.Hour, 100)
+	assert.False(t, r.hasPermission(permissionSkipModeration))
+}
+
+func Test_reputation_reviewsDisabled(t *testing.T) {
+	h := &Handler{
+		cfg:         config.Config{ReviewsEnabled: false},
+		postgres:    &sql.DB{},
+		analytics:   &MemoryAnalytics{},
+		reputations: &reputationCache{entries: make(map[string]cachedReputation)},
+	}
+
+	r, err := h.reputation(context.Background(), UserDB{Email: "a@b.co", Username: "a"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), r.Score)
+}
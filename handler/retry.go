@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Retries of transient backend errors
+const (
+	retryAttempts = 3
+	retryDelay    = 100 * time.Millisecond
+)
+
+// isTransient checks if an error is worth retrying, like an unavailable
+// backend or a network timeout
+func isTransient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return true
+	}
+	return false
+}
+
+// retry calls fn until it succeeds, fails with an error that isn't transient
+// or runs out of attempts, doubling the delay between attempts
+func retry(ctx context.Context, fn func() error) error {
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt == retryAttempts || !isTransient(err) {
+			return err
+		}
+
+		metrics.Add(metricRetries, 1)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// getAllDocs reads every document of a query, retrying transient errors
+func getAllDocs(ctx context.Context, q firestore.Query) ([]*firestore.DocumentSnapshot, error) {
+	var docs []*firestore.DocumentSnapshot
+	err := retry(ctx, func() error {
+		var err error
+		docs, err = q.Documents(ctx).GetAll()
+		return err
+	})
+	return docs, err
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_retry(t *testing.T) {
+	ctx := context.Background()
+
+	calls := 0
+	err := retry(ctx, func() error {
+		calls++
+		if calls < 3 {
+			return status.Error(codes.Unavailable, "try again")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = retry(ctx, func() error {
+		calls++
+		return status.Error(codes.Unavailable, "still down")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, retryAttempts, calls)
+
+	calls = 0
+	err = retry(ctx, func() error {
+		calls++
+		return errors.New("bad query")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
@@ -1,6 +1,10 @@
 package handler
 
 import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/firestore"
@@ -8,19 +12,25 @@ import (
 
 // Review is a review of a bean by a user
 type Review struct {
-	Rating    float64   `firestore:"rating" json:"rating"`
-	Review    string    `firestore:"review" json:"review"`
-	User      string    `firestore:"user" json:"user"`
-	UpdatedAt time.Time `firestore:"updated_at" json:"updated_at"`
-	Bean      string    `firestore:"bean" json:"bean"`
+	ID              int       `firestore:"id" json:"id"`
+	Rating          float64   `firestore:"rating" json:"rating"`
+	Review          string    `firestore:"review" json:"review"`
+	User            string    `firestore:"user" json:"user"`
+	UpdatedAt       time.Time `firestore:"updated_at" json:"updated_at"`
+	Bean            string    `firestore:"bean" json:"bean"`
+	HelpfulCount    int       `firestore:"helpful_count" json:"helpful_count"`
+	NotHelpfulCount int       `firestore:"not_helpful_count" json:"not_helpful_count"`
 }
 
 type ReviewWithBean struct {
-	Rating    float64   `firestore:"rating" json:"rating"`
-	Review    string    `firestore:"review" json:"review"`
-	User      string    `firestore:"user" json:"user"`
-	UpdatedAt time.Time `firestore:"updated_at" json:"updated_at"`
-	Bean      Bean      `firestore:"bean" json:"bean"`
+	ID              int       `firestore:"id" json:"id"`
+	Rating          float64   `firestore:"rating" json:"rating"`
+	Review          string    `firestore:"review" json:"review"`
+	User            string    `firestore:"user" json:"user"`
+	UpdatedAt       time.Time `firestore:"updated_at" json:"updated_at"`
+	Bean            Bean      `firestore:"bean" json:"bean"`
+	HelpfulCount    int       `firestore:"helpful_count" json:"helpful_count"`
+	NotHelpfulCount int       `firestore:"not_helpful_count" json:"not_helpful_count"`
 }
 
 func docToReview(doc *firestore.DocumentSnapshot) Review {
@@ -34,3 +44,74 @@ func docToReviewWithBean(doc *firestore.DocumentSnapshot) ReviewWithBean {
 	doc.DataTo(&r)
 	return r
 }
+
+// parseReviewID reads the id of a review from the path
+func parseReviewID(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil || id < 1 {
+		return 0, validationError("invalid review id")
+	}
+	return id, nil
+}
+
+// normalizeReportReason trims a report reason and checks its length
+func normalizeReportReason(reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return "", validationError("a reason is required")
+	}
+	if len(reason) > maxReportReasonLength {
+		return "", validationError("reason is too long")
+	}
+	return reason, nil
+}
+
+// errReviewNotFound is returned when a review doesn't exist or is hidden
+var errReviewNotFound = notFoundError("review not found")
+
+// getReviewAuthorID returns the Postgres user_id of the author of a visible review
+func (h *Handler) getReviewAuthorID(ctx context.Context, reviewID int) (int, error) {
+	var authorID int
+	err := h.postgres.QueryRowContext(
+		ctx,
+		`SELECT user_id FROM reviews WHERE review_id = $1 AND NOT hidden;`,
+		reviewID,
+	).Scan(&authorID)
+	if err == sql.ErrNoRows {
+		return 0, errReviewNotFound
+	}
+	return authorID, err
+}
+
+// getRatings loads the ratings of all visible reviews
+func (h *Handler) getRatings(ctx context.Context) ([]rating, error) {
+	var ratings []rating
+
+	rows, err := h.postgres.QueryContext(ctx, `SELECT user_id, bean_ref, rating FROM reviews WHERE NOT hidden;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r rating
+		if err := rows.Scan(&r.UserID, &r.BeanRef, &r.Rating); err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, r)
+	}
+
+	return ratings, rows.Err()
+}
+
+// normalizeReview trims the review text and checks the rating
+func normalizeReview(req *AddReviewReq) error {
+	req.Bean = strings.TrimSpace(req.Bean)
+	req.Review = strings.TrimSpace(req.Review)
+	if req.Bean == "" {
+		return validationError("invalid bean")
+	}
+	if req.Rating < 0 || req.Rating > 5 {
+		return validationError("rating must be between 0 and 5")
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// acceptSuggestion applies a suggestion through the normal edit path
+func (h *Handler) acceptSuggestion(w http.ResponseWriter, r *http.Request) {
+	h.reviewSuggestion(w, r, suggestionAccepted)
+}
+
+// declineSuggestion closes a suggestion without applying it
+func (h *Handler) declineSuggestion(w http.ResponseWriter, r *http.Request) {
+	h.reviewSuggestion(w, r, suggestionDeclined)
+}
+
+func (h *Handler) reviewSuggestion(w http.ResponseWriter, r *http.Request, decision string) {
+	var (
+		ctx       = r.Context()
+		vars      = mux.Vars(r)
+		id        = vars["id"]
+		req       ModerateReq
+		resp      = &SuggestionResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	// The comment is optional
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.writeError(w, r, bodyError(err))
+			return
+		}
+	}
+
+	ref := h.database.Collection("suggestions").Doc(id)
+	doc, err := ref.Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		h.writeError(w, r, errSuggestionNotFound)
+		return
+	}
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	s := docToSuggestion(doc)
+	if !h.canReviewSuggestion(ctx, s, userEmail) {
+		h.writeError(w, r, forbiddenError("only the roaster owner or a moderator can review suggestions"))
+		return
+	}
+	if s.Status != suggestionOpen {
+		h.writeError(w, r, conflictError(fmt.Sprintf("suggestion already %s", s.Status)))
+		return
+	}
+
+	// Claim the suggestion first so it can't be applied twice
+	s, err = h.claimSuggestion(ctx, id, decision, userEmail, req.Comment)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if decision == suggestionAccepted {
+		target, current, proposed, err := h.suggestionTarget(ctx, s)
+		if err != nil {
+			h.releaseSuggestion(ctx, s.ID)
+			h.writeError(w, r, err)
+			return
+		}
+		s.Changes = diffFields(current, proposed)
+
+		switch p := proposed.(type) {
+		case Bean:
+			err = h.updateBean(ctx, target.Ref, BeanReq{p}, s.SuggestedBy)
+		case Roaster:
+			err = h.updateRoaster(ctx, target.Ref, RoasterReq{p}, s.SuggestedBy)
+		}
+		if err != nil {
+			h.releaseSuggestion(ctx, s.ID)
+			h.writeError(w, r, err)
+			return
+		}
+	}
+
+	h.logger.Infow(
+		"Suggestion reviewed",
+		"id", s.ID,
+		"status", s.Status,
+		"reviewed_by", userEmail,
+	)
+
+	// Let the suggester know
+	message := fmt.Sprintf("Your suggested edit for %s was %s", s.Slug, s.Status)
+	if s.Comment != "" {
+		message = fmt.Sprintf("%s: %s", message, s.Comment)
+	}
+	h.notify(ctx, Notification{
+		User:    s.SuggestedBy,
+		Message: message,
+	})
+
+	resp.Suggestion = s
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// claimSuggestion checks that a suggestion is open and records the decision
+// in one transaction
+func (h *Handler) claimSuggestion(ctx context.Context, id, decision, reviewer, comment string) (Suggestion, error) {
+	var (
+		ref = h.database.Collection("suggestions").Doc(id)
+		s   Suggestion
+	)
+	err := h.database.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return errSuggestionNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		s = docToSuggestion(doc)
+		if s.Status != suggestionOpen {
+			return conflictError(fmt.Sprintf("suggestion already %s", s.Status))
+		}
+
+		s.Status = decision
+		s.ReviewedBy = reviewer
+		s.ReviewedAt = time.Now()
+		s.Comment = comment
+		return tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: s.Status},
+			{Path: "reviewed_by", Value: s.ReviewedBy},
+			{Path: "reviewed_at", Value: s.ReviewedAt},
+			{Path: "comment", Value: s.Comment},
+		})
+	})
+	return s, err
+}
+
+// releaseSuggestion reopens a claimed suggestion when it couldn't be applied
+func (h *Handler) releaseSuggestion(ctx context.Context, id string) {
+	_, err := h.database.Collection("suggestions").Doc(id).Update(ctx, []firestore.Update{
+		{Path: "status", Value: suggestionOpen},
+		{Path: "reviewed_by", Value: ""},
+		{Path: "reviewed_at", Value: time.Time{}},
+		{Path: "comment", Value: ""},
+	})
+	if err != nil {
+		h.logger.Errorw("Error reopening suggestion", "id", id, "error", err)
+	}
+}
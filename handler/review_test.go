@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseReviewID(t *testing.T) {
+	type test struct {
+		name    string
+		id      string
+		exp     int
+		wantErr bool
+	}
+
+	tests := []test{
+		{name: "valid id", id: "42", exp: 42},
+		{name: "not a number", id: "abc", wantErr: true},
+		{name: "zero", id: "0", wantErr: true},
+		{name: "negative", id: "-3", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			id, err := parseReviewID(tc.id)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.exp, id)
+		})
+	}
+}
+
+func Test_normalizeReportReason(t *testing.T) {
+	type test struct {
+		name    string
+		reason  string
+		exp     string
+		wantErr bool
+	}
+
+	tests := []test{
+		{name: "trims spaces", reason: "  spam \n", exp: "spam"},
+		{name: "empty", reason: "   ", wantErr: true},
+		{name: "too long", reason: strings.Repeat("a", maxReportReasonLength+1), wantErr: true},
+		{name: "at the limit", reason: strings.Repeat("a", maxReportReasonLength), exp: strings.Repeat("a", maxReportReasonLength)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reason, err := normalizeReportReason(tc.reason)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.exp, reason)
+		})
+	}
+}
+
+func Test_normalizeReview(t *testing.T) {
+	type test struct {
+		name    string
+		req     AddReviewReq
+		exp     AddReviewReq
+		wantErr bool
+	}
+
+	tests := []test{
+		{
+			name: "trims the bean and text",
+			req:  AddReviewReq{Bean: " jumpstart ", Rating: 4.5, Review: "  Sweet and juicy \n"},
+			exp:  AddReviewReq{Bean: "jumpstart", Rating: 4.5, Review: "Sweet and juicy"},
+		},
+		{name: "missing bean", req: AddReviewReq{Rating: 3}, wantErr: true},
+		{name: "rating too low", req: AddReviewReq{Bean: "jumpstart", Rating: -1}, wantErr: true},
+		{name: "rating too high", req: AddReviewReq{Bean: "jumpstart", Rating: 5.5}, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := normalizeReview(&tc.req)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.exp, tc.req)
+		})
+	}
+}
+
+func Test_isUniqueViolation(t *testing.T) {
+	assert.True(t, isUniqueViolation(&pq.Error{Code: "23505"}))
+	assert.False(t, isUniqueViolation(&pq.Error{Code: "23503"}))
+	assert.False(t, isUniqueViolation(sql.ErrNoRows))
+	assert.False(t, isUniqueViolation(nil))
+}
@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Roast levels from lightest to darkest
+const (
+	roastLight       = 1
+	roastMediumLight = 2
+	roastMedium      = 3
+	roastMediumDark  = 4
+	roastDark        = 5
+)
+
+// roastLevelNames are the canonical names of the roast levels
+var roastLevelNames = map[int64]string{
+	roastLight:       "light",
+	roastMediumLight: "medium-light",
+	roastMedium:      "medium",
+	roastMediumDark:  "medium-dark",
+	roastDark:        "dark",
+}
+
+// roastLevelAliases maps the shades roasters use to a roast level
+var roastLevelAliases = map[string]int64{
+	"light":        roastLight,
+	"very light":   roastLight,
+	"cinnamon":     roastLight,
+	"nordic":       roastLight,
+	"filter":       roastLight,
+	"medium-light": roastMediumLight,
+	"medium light": roastMediumLight,
+	"light-medium": roastMediumLight,
+	"light medium": roastMediumLight,
+	"city":         roastMediumLight,
+	"omni":         roastMediumLight,
+	"medium":       roastMedium,
+	"city+":        roastMedium,
+	"city plus":    roastMedium,
+	"american":     roastMedium,
+	"medium-dark":  roastMediumDark,
+	"medium dark":  roastMediumDark,
+	"full city":    roastMediumDark,
+	"full city+":   roastMediumDark,
+	"espresso":     roastMediumDark,
+	"vienna":       roastMediumDark,
+	"dark":         roastDark,
+	"very dark":    roastDark,
+	"french":       roastDark,
+	"italian":      roastDark,
+	"spanish":      roastDark,
+	"extra dark":   roastDark,
+}
+
+// freshnessWindow is how long a batch is considered fresh when the roaster
+// doesn't give a best by date
+const freshnessWindow = 30 * 24 * time.Hour
+
+// Batch is a roaster-supplied roast of a bean
+type Batch struct {
+	Agtron    int64     `firestore:"agtron" json:"agtron"`
+	BestBy    time.Time `firestore:"best_by" json:"best_by"`
+	RoastedAt time.Time `firestore:"roasted_at" json:"roasted_at"`
+}
+
+// parseRoastLevel reads a roast level from a name, alias or number
+func parseRoastLevel(s string) (int64, bool) {
+	s = strings.ToLower(strings.Join(strings.Fields(s), " "))
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		_, ok := roastLevelNames[n]
+		return n, ok
+	}
+	s = strings.TrimSuffix(s, " roast")
+	l, ok := roastLevelAliases[s]
+	return l, ok
+}
+
+// agtronRoastLevel maps an Agtron reading to a roast level. Higher readings
+// are lighter roasts.
+func agtronRoastLevel(agtron int64) int64 {
+	switch {
+	case agtron >= 70:
+		return roastLight
+	case agtron >= 60:
+		return roastMediumLight
+	case agtron >= 50:
+		return roastMedium
+	case agtron >= 40:
+		return roastMediumDark
+	default:
+		return roastDark
+	}
+}
+
+// validAgtron checks an Agtron reading is on the gourmet scale
+func validAgtron(agtron int64) bool {
+	return agtron >= 0 && agtron <= 100
+}
+
+// normalizeRoast sets the roast level of a bean from its shade, or from its
+// Agtron reading when there's no shade. A free-text shade kept from the stored
+// bean that isn't a roast level is dropped rather than rejected.
+func normalizeRoast(b *Bean, stored Bean) error {
+	if !validAgtron(b.Agtron) {
+		return fmt.Errorf("agtron must be between 0 and 100")
+	}
+
+	switch {
+	case strings.TrimSpace(b.Shade) != "":
+		l, ok := parseRoastLevel(b.Shade)
+		if !ok && b.Shade != stored.Shade {
+			return fmt.Errorf("unknown roast level %q, expected one of: light, medium-light, medium, medium-dark, dark", b.Shade)
+		}
+		if !ok && b.Agtron > 0 {
+			l = agtronRoastLevel(b.Agtron)
+		}
+		b.RoastLevel = l
+	case b.Agtron > 0:
+		b.RoastLevel = agtronRoastLevel(b.Agtron)
+	default:
+		b.RoastLevel = 0
+	}
+	if b.RoastLevel > 0 {
+		b.Shade = roastLevelNames[b.RoastLevel]
+	} else {
+		b.Shade = ""
+	}
+
+	return nil
+}
+
+// latestBatch returns the most recent batch of a bean
+func latestBatch(b Bean) *Batch {
+	var latest *Batch
+	for i := range b.Batches {
+		if latest == nil || b.Batches[i].RoastedAt.After(latest.RoastedAt) {
+			latest = &b.Batches[i]
+		}
+	}
+	return latest
+}
+
+// bestBy returns when a batch should be used by
+func (b Batch) bestBy() time.Time {
+	if b.BestBy.IsZero() {
+		return b.RoastedAt.Add(freshnessWindow)
+	}
+	return b.BestBy
+}
+
+// isFresh checks if a bean has a batch that is still before its best by date
+func isFresh(b Bean, now time.Time) bool {
+	for _, batch := range b.Batches {
+		if !batch.RoastedAt.After(now) && now.Before(batch.bestBy()) {
+			return true
+		}
+	}
+	return false
+}
+
+// RoastSummary summarises the roasts of a roaster's beans
+type RoastSummary struct {
+	Levels          map[string]int `json:"levels"`
+	AverageLevel    float64        `json:"average_level"`
+	AverageAgtron   float64        `json:"average_agtron"`
+	FreshBeans      int            `json:"fresh_beans"`
+	LatestRoastedAt *time.Time     `json:"latest_roasted_at"`
+}
+
+// summarizeRoasts summarises the roast levels and batches of beans
+func summarizeRoasts(beans []Bean, now time.Time) RoastSummary {
+	var (
+		summary       = RoastSummary{Levels: make(map[string]int)}
+		levels, agtrn int
+	)
+
+	for _, b := range beans {
+		if b.RoastLevel > 0 {
+			summary.Levels[roastLevelNames[b.RoastLevel]]++
+			summary.AverageLevel += float64(b.RoastLevel)
+			levels++
+		}
+		if b.Agtron > 0 {
+			summary.AverageAgtron += float64(b.Agtron)
+			agtrn++
+		}
+		if isFresh(b, now) {
+			summary.FreshBeans++
+		}
+		if latest := latestBatch(b); latest != nil {
+			if summary.LatestRoastedAt == nil || latest.RoastedAt.After(*summary.LatestRoastedAt) {
+				t := latest.RoastedAt
+				summary.LatestRoastedAt = &t
+			}
+		}
+	}
+
+	if levels > 0 {
+		summary.AverageLevel /= float64(levels)
+	}
+	if agtrn > 0 {
+		summary.AverageAgtron /= float64(agtrn)
+	}
+
+	return summary
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_normalizeRoast(t *testing.T) {
+	tests := []struct {
+		bean  Bean
+		level int64
+		shade string
+	}{
+		{Bean{Shade: "Light Roast"}, roastLight, "light"},
+		{Bean{Shade: "full city"}, roastMediumDark, "medium-dark"},
+		{Bean{Shade: "3"}, roastMedium, "medium"},
+		{Bean{Agtron: 65}, roastMediumLight, "medium-light"},
+		{Bean{Shade: "dark", Agtron: 80}, roastDark, "dark"},
+		{Bean{}, 0, ""},
+	}
+
+	for _, tt := range tests {
+		b := tt.bean
+		assert.NoError(t, normalizeRoast(&b, Bean{}))
+		assert.Equal(t, tt.level, b.RoastLevel)
+		assert.Equal(t, tt.shade, b.Shade)
+	}
+
+	assert.Error(t, normalizeRoast(&Bean{Shade: "burnt"}, Bean{}))
+	assert.Error(t, normalizeRoast(&Bean{Agtron: 140}, Bean{}))
+
+	// Legacy free-text shades fall back to the Agtron reading or are cleared
+	legacy := Bean{Shade: "chocolatey"}
+	b := Bean{Shade: "chocolatey", Agtron: 55}
+	assert.NoError(t, normalizeRoast(&b, legacy))
+	assert.Equal(t, int64(roastMedium), b.RoastLevel)
+	assert.Equal(t, "medium", b.Shade)
+	b = Bean{Shade: "chocolatey"}
+	assert.NoError(t, normalizeRoast(&b, legacy))
+	assert.Equal(t, int64(0), b.RoastLevel)
+	assert.Equal(t, "", b.Shade)
+	assert.Error(t, normalizeRoast(&Bean{Shade: "burnt"}, legacy))
+}
+
+func Test_sortBeans(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2020, 11, d, 0, 0, 0, 0, time.UTC) }
+	beans := []Bean{
+		{Slug: "a", RoastLevel: roastDark, Batches: []Batch{{RoastedAt: day(1)}}},
+		{Slug: "b"},
+		{Slug: "c", RoastLevel: roastLight, Batches: []Batch{{RoastedAt: day(2)}, {RoastedAt: day(9)}}},
+	}
+	slugs := func() []string {
+		var s []string
+		for _, b := range beans {
+			s = append(s, b.Slug)
+		}
+		return s
+	}
+
+	assert.NoError(t, sortBeans(beans, "roast_level"))
+	assert.Equal(t, []string{"c", "a", "b"}, slugs())
+
+	assert.NoError(t, sortBeans(beans, "-roast_level"))
+	assert.Equal(t, []string{"a", "c", "b"}, slugs())
+
+	assert.NoError(t, sortBeans(beans, "-roasted_at"))
+	assert.Equal(t, []string{"c", "a", "b"}, slugs())
+
+	assert.Error(t, sortBeans(beans, "price"))
+}
+
+func Test_summarizeRoasts(t *testing.T) {
+	now := time.Date(2020, 11, 20, 0, 0, 0, 0, time.UTC)
+	beans := []Bean{
+		{RoastLevel: roastLight, Agtron: 80, Batches: []Batch{{RoastedAt: now.AddDate(0, 0, -5)}}},
+		{RoastLevel: roastMedium, Batches: []Batch{{RoastedAt: now.AddDate(0, 0, -60)}}},
+		{RoastLevel: roastMedium},
+		{},
+	}
+
+	summary := summarizeRoasts(beans, now)
+	assert.Equal(t, map[string]int{"light": 1, "medium": 2}, summary.Levels)
+	assert.InDelta(t, 2.33, summary.AverageLevel, 0.01)
+	assert.Equal(t, 80.0, summary.AverageAgtron)
+	assert.Equal(t, 1, summary.FreshBeans)
+	assert.Equal(t, now.AddDate(0, 0, -5), *summary.LatestRoastedAt)
+}
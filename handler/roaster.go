@@ -3,42 +3,61 @@ package handler
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/bwmarrin/discordgo"
+	"google.golang.org/api/iterator"
 
 	"google.golang.org/genproto/googleapis/type/latlng"
 )
 
 // Roaster represents an organization that roasts beans
 type Roaster struct {
+	Address   *Address       `firestore:"address" json:"address"`
+	Cafes     []Cafe         `firestore:"cafes" json:"cafes"`
 	City      string         `firestore:"city" json:"city"`
+	Country   string         `firestore:"country" json:"country"`
+	Founded   int64          `firestore:"founded" json:"founded"`
 	Instagram string         `firestore:"instagram" json:"instagram"`
 	Location  *latlng.LatLng `firestore:"location" json:"location"`
 	Logo      string         `firestore:"logo" json:"logo"`
 	Name      string         `firestore:"name" json:"name"`
+	Region    string         `firestore:"region" json:"region"`
+	ShipsTo   []string       `firestore:"ships_to" json:"ships_to"`
 	Slug      string         `firestore:"slug" json:"slug"`
 	Twitter   string         `firestore:"twitter" json:"twitter"`
 	URL       string         `firestore:"url" json:"url"`
+	Verified  bool           `firestore:"verified" json:"verified"`
+	Wholesale bool           `firestore:"wholesale" json:"wholesale"`
 }
 
 // RoasterDB represents a Roaster in firestore
 type RoasterDB struct {
 	Roaster
-	Verified bool `firestore:"verified"`
+	Owner        string    `firestore:"owner"`
+	Geohash      string    `firestore:"geohash"`
+	URLChangedAt time.Time `firestore:"url_changed_at"`
 }
 
 // RoasterBQ represents a coffee roaster
 type RoasterBQ struct {
+	Address   string
+	Cafes     int64
 	City      string
+	Country   string
+	Founded   int64
 	Instagram string
 	Location  string
 	Logo      string
 	Name      string
+	Region    string
+	ShipsTo   string
 	Slug      string
 	Twitter   string
 	URL       string
+	Wholesale bool
 }
 
 type RoasterBQItem struct {
@@ -54,8 +73,9 @@ type RoasterReq struct {
 
 // RoasterResp is the response for the GET /roaster/{slug} endpoint
 type RoasterResp struct {
-	Roaster Roaster `json:"roaster"`
-	Beans   []Bean  `json:"beans"`
+	Roaster      Roaster      `json:"roaster"`
+	Beans        []Bean       `json:"beans"`
+	RoastSummary RoastSummary `json:"roast_summary"`
 }
 
 // RoastersResp is the response for the GET /roasters endpoint
@@ -71,34 +91,262 @@ type RoastersListResp struct {
 func docToRoaster(doc *firestore.DocumentSnapshot) Roaster {
 	var r Roaster
 	doc.DataTo(&r)
+	if r.Cafes == nil {
+		r.Cafes = []Cafe{}
+	}
+	if r.ShipsTo == nil {
+		r.ShipsTo = []string{}
+	}
 	return r
 }
 
-// recordRoasterChange posts a changelog event to BigQuery
-func (h *Handler) recordRoasterChange(ctx context.Context, req RoasterReq, userEmail string) {
+func docToRoasterDB(doc *firestore.DocumentSnapshot) RoasterDB {
+	var r RoasterDB
+	doc.DataTo(&r)
+	return r
+}
+
+// canEdit checks if a user can edit a roaster and its beans directly.
+// Verified roasters can only be edited by their owner or a moderator,
+// everyone else has to suggest an edit.
+func (h *Handler) canEdit(roaster RoasterDB, userEmail string) bool {
+	if !roaster.Verified || h.isModerator(userEmail) {
+		return true
+	}
+	return userEmail != "" && roaster.Owner == userEmail
+}
+
+// canEditBean checks if a user can edit a bean directly, based on its roaster
+func (h *Handler) canEditBean(ctx context.Context, b Bean, userEmail string) (bool, error) {
+	var roaster RoasterDB
+	doc, err := h.getRoasterDocBySlug(ctx, b.Roaster.Slug)
+	if err == nil {
+		roaster = docToRoasterDB(doc)
+	}
+	return h.canEditLookedUp(roaster, err, userEmail)
+}
+
+// canEditLookedUp applies canEdit to the result of a roaster lookup. A roaster
+// that doesn't exist has no owner, any other lookup error denies the edit.
+func (h *Handler) canEditLookedUp(roaster RoasterDB, lookupErr error, userEmail string) (bool, error) {
+	if lookupErr == errRoasterNotFound {
+		return h.canEdit(RoasterDB{}, userEmail), nil
+	}
+	if lookupErr != nil {
+		return false, lookupErr
+	}
+	return h.canEdit(roaster, userEmail), nil
+}
+
+// isRoasterOwner checks if a user speaks for a roaster
+func (h *Handler) isRoasterOwner(roaster RoasterDB, userEmail string) bool {
+	if h.isModerator(userEmail) {
+		return true
+	}
+	return userEmail != "" && roaster.Owner == userEmail
+}
+
+// errRoasterNotFound is returned when no roaster matches a slug
+var errRoasterNotFound = notFoundError("roaster not found")
+
+// getRoasterDocBySlug fetches the firestore document for a roaster
+func (h *Handler) getRoasterDocBySlug(ctx context.Context, slug string) (*firestore.DocumentSnapshot, error) {
+	iter := h.database.Collection("roasters").Where("slug", "==", slug).Limit(1).Documents(ctx)
+	defer iter.Stop()
+	doc, err := iter.Next()
+	if err == iterator.Done {
+		return nil, errRoasterNotFound
+	}
+	return doc, err
+}
+
+// roasterNameExists checks that a roaster with the given name exists
+func (h *Handler) roasterNameExists(ctx context.Context, name string) (bool, error) {
+	iter := h.database.Collection("roasters").Where("name", "==", name).Limit(1).Documents(ctx)
+	defer iter.Stop()
+	_, err := iter.Next()
+	if err == iterator.Done {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// verifiedRoasters returns the slugs of all verified roasters
+func (h *Handler) verifiedRoasters(ctx context.Context) (map[string]bool, error) {
+	var verified = make(map[string]bool)
+
+	docs, err := h.database.Collection("roasters").Where("verified", "==", true).Select("slug").Documents(ctx).GetAll()
+	if err != nil {
+		return verified, err
+	}
+	for _, doc := range docs {
+		slug, err := doc.DataAt("slug")
+		if err != nil {
+			continue
+		}
+		if s, ok := slug.(string); ok {
+			verified[s] = true
+		}
+	}
+
+	return verified, nil
+}
+
+// setVerifiedBadges marks the roasters of beans that are verified
+func (h *Handler) setVerifiedBadges(ctx context.Context, beans []Bean) {
+	verified, err := h.verifiedRoasters(ctx)
+	if err != nil {
+		h.logger.Error(err)
+		return
+	}
+	for i := range beans {
+		beans[i].Roaster.Verified = verified[beans[i].Roaster.Slug]
+	}
+}
+
+// createRoaster adds a roaster and publishes the change
+func (h *Handler) createRoaster(ctx context.Context, req RoasterReq, userEmail string) (*firestore.DocumentRef, error) {
+	if err := prepareRoaster(&req.Roaster, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// Roasters are only verified through a claim
+	req.Verified = false
+
+	doc, _, err := h.database.Collection("roasters").Add(ctx, RoasterDB{
+		Roaster: req.Roaster,
+		Geohash: roasterGeohash(req.Roaster),
+	})
+	if err != nil {
+		return nil, err
+	}
+	h.logger.Infow(
+		"Roaster added",
+		"id", doc.ID,
+		"updated_by", userEmail,
+	)
+
+	// Publish an entry in BigQuery
+	h.recordRoasterChange(req, userEmail)
+
+	// Send a webhook event to Discord
+	h.detach("discord webhook", func(context.Context) {
+		if _, err := h.postRoasterToDiscord(req, userEmail, "add"); err != nil {
+			h.logger.Error(err)
+		}
+	})
+
+	h.publishEvent(ctx, roasterEvent(eventRoasterAdded, req.Roaster), userEmail)
+
+	return doc, nil
+}
+
+// updateRoaster updates an existing roaster and publishes the change
+func (h *Handler) updateRoaster(ctx context.Context, roaster *firestore.DocumentRef, req RoasterReq, userEmail string) error {
+	if err := prepareRoaster(&req.Roaster, time.Now()); err != nil {
+		return err
+	}
+
+	current, err := roaster.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	updates := []firestore.Update{
+		{Path: "city", Value: req.City},
+		{Path: "region", Value: req.Region},
+		{Path: "country", Value: req.Country},
+		{Path: "instagram", Value: req.Instagram},
+		{Path: "location", Value: req.Location},
+		{Path: "geohash", Value: roasterGeohash(req.Roaster)},
+		{Path: "logo", Value: req.Logo},
+		{Path: "name", Value: req.Name},
+		{Path: "slug", Value: req.Slug},
+		{Path: "twitter", Value: req.Twitter},
+		{Path: "url", Value: req.URL},
+		{Path: "address", Value: req.Address},
+		{Path: "cafes", Value: req.Cafes},
+		{Path: "ships_to", Value: req.ShipsTo},
+		{Path: "wholesale", Value: req.Wholesale},
+		{Path: "founded", Value: req.Founded},
+	}
+
+	// Domain claims are held back for a while after the website changes
+	if docToRoaster(current).URL != req.URL {
+		updates = append(updates, firestore.Update{Path: "url_changed_at", Value: time.Now()})
+	}
+
+	result, err := roaster.Update(ctx, updates)
+	if err != nil {
+		return err
+	}
+	h.logger.Infow(
+		"Roaster updated",
+		"id", roaster.ID,
+		"updated_at", result.UpdateTime,
+		"updated_by", userEmail,
+	)
+
+	// Publish an entry in BigQuery
+	h.recordRoasterChange(req, userEmail)
+
+	// Send a webhook event to Discord
+	h.detach("discord webhook", func(context.Context) {
+		if _, err := h.postRoasterToDiscord(req, userEmail, "edit"); err != nil {
+			h.logger.Error(err)
+		}
+	})
+
+	h.publishEvent(ctx, roasterEvent(eventRoasterUpdated, req.Roaster), userEmail)
+
+	return nil
+}
+
+// recordRoasterChange posts a changelog event to BigQuery in the background
+func (h *Handler) recordRoasterChange(req RoasterReq, userEmail string) {
 	dataset := h.bq.DatasetInProject("cafebean", "roaster")
 	table := dataset.Table("changelog")
 
+	var location, address string
+	if req.Location != nil {
+		location = fmt.Sprintf("POINT(%f %f)", req.Location.Longitude, req.Location.Latitude)
+	}
+	if req.Address != nil {
+		address = req.Address.String()
+	}
+
 	u := table.Inserter()
 	items := []*RoasterBQItem{
 		{
 			Roaster: RoasterBQ{
+				Address:   address,
+				Cafes:     int64(len(req.Cafes)),
 				City:      req.City,
+				Country:   req.Country,
+				Founded:   req.Founded,
 				Instagram: req.Instagram,
-				Location:  fmt.Sprintf("POINT(%f %f)", req.Location.Longitude, req.Location.Latitude),
+				Location:  location,
 				Logo:      req.Logo,
 				Name:      req.Name,
+				Region:    req.Region,
+				ShipsTo:   strings.Join(req.ShipsTo, ", "),
 				Slug:      req.Slug,
 				URL:       req.URL,
 				Twitter:   req.Twitter,
+				Wholesale: req.Wholesale,
 			},
 			UpdatedBy: userEmail,
 			UpdatedAt: time.Now().Format(time.RFC3339),
 		},
 	}
-	if err := u.Put(ctx, items); err != nil {
-		h.logger.Error(err)
-	}
+	h.detach("roaster changelog", func(ctx context.Context) {
+		if err := u.Put(ctx, items); err != nil {
+			h.logger.Error(err)
+		}
+	})
 }
 
 // postRoasterToDiscord posts a webhook to Discord when a roaster is added or updated
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// RoasterFilter narrows down the roasters returned by GET /roasters
+type RoasterFilter struct {
+	// ShipsTo is the country code a roaster must ship to
+	ShipsTo string
+	// Country matches the country of the roaster or its address
+	Country string
+	// Wholesale and HasCafe only match when they're asked for
+	Wholesale bool
+	HasCafe   bool
+}
+
+// parseRoasterFilter reads a filter from the query string
+func parseRoasterFilter(q url.Values) (RoasterFilter, error) {
+	var f RoasterFilter
+
+	if c := strings.TrimSpace(q.Get("ships_to")); c != "" {
+		code, ok := countryCode(c)
+		if !ok {
+			return f, fmt.Errorf("unknown country %q, use a code like CA", c)
+		}
+		f.ShipsTo = code
+	}
+
+	if c := strings.TrimSpace(q.Get("country")); c != "" {
+		code, ok := countryCode(c)
+		if !ok {
+			return f, fmt.Errorf("unknown country %q, use a code like CA", c)
+		}
+		f.Country = code
+	}
+
+	for param, field := range map[string]*bool{
+		"wholesale": &f.Wholesale,
+		"cafes":     &f.HasCafe,
+	} {
+		if v := q.Get(param); v != "" {
+			ok, err := strconv.ParseBool(v)
+			if err != nil {
+				return f, fmt.Errorf("%s must be true or false", param)
+			}
+			*field = ok
+		}
+	}
+
+	return f, nil
+}
+
+// Match checks if a roaster passes the filter
+func (f RoasterFilter) Match(r Roaster) bool {
+	if f.ShipsTo != "" && !containsString(r.ShipsTo, f.ShipsTo) {
+		return false
+	}
+
+	if f.Country != "" {
+		country := r.Country
+		if country == "" && r.Address != nil {
+			country = r.Address.Country
+		}
+		if code, ok := countryCode(country); !ok || code != f.Country {
+			return false
+		}
+	}
+
+	if f.Wholesale && !r.Wholesale {
+		return false
+	}
+
+	if f.HasCafe && len(r.Cafes) == 0 {
+		return false
+	}
+
+	return true
+}
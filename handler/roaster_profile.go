@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// firstRoasterYear is the earliest founding year we accept
+const firstRoasterYear = 1600
+
+// Address is the structured address of a roaster or café
+type Address struct {
+	Street     string `firestore:"street" json:"street"`
+	City       string `firestore:"city" json:"city"`
+	Region     string `firestore:"region" json:"region"`
+	PostalCode string `firestore:"postal_code" json:"postal_code"`
+	Country    string `firestore:"country" json:"country"`
+}
+
+// String formats an address on one line
+func (a Address) String() string {
+	var parts []string
+	for _, p := range []string{a.Street, a.City, strings.TrimSpace(a.Region + " " + a.PostalCode), a.Country} {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// IsZero checks if no part of the address is set
+func (a Address) IsZero() bool {
+	return a.String() == ""
+}
+
+// OpeningHours are the hours a café is open on a day of the week, as 24 hour
+// HH:MM times
+type OpeningHours struct {
+	Day    string `firestore:"day" json:"day"`
+	Opens  string `firestore:"opens" json:"opens"`
+	Closes string `firestore:"closes" json:"closes"`
+}
+
+// Cafe is a physical location of a roaster
+type Cafe struct {
+	Name     string         `firestore:"name" json:"name"`
+	Address  *Address       `firestore:"address" json:"address"`
+	Location *latlng.LatLng `firestore:"location" json:"location"`
+	Hours    []OpeningHours `firestore:"hours" json:"hours"`
+}
+
+// weekdays are the days opening hours can be given for, in order
+var weekdays = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+// weekdayNames are the full names of weekdays
+var weekdayNames = []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+
+var clockTime = regexp.MustCompile(`^([01]\d|2[0-3]):[0-5]\d$`)
+
+// parseWeekday reads a day like "Monday", "mon" or "Mo"
+func parseWeekday(s string) (string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) < 2 {
+		return "", false
+	}
+	for i, d := range weekdays {
+		if strings.HasPrefix(weekdayNames[i], s) {
+			return d, true
+		}
+	}
+	return "", false
+}
+
+func indexOf(l []string, s string) int {
+	for i, v := range l {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// normalizeHours validates opening hours and sorts them by day. Closing
+// before opening means the café closes after midnight.
+func normalizeHours(hours []OpeningHours) ([]OpeningHours, error) {
+	var normalized = []OpeningHours{}
+	for _, h := range hours {
+		day, ok := parseWeekday(h.Day)
+		if !ok {
+			return nil, fmt.Errorf("unknown day %q", h.Day)
+		}
+		h.Day = day
+		h.Opens, h.Closes = strings.TrimSpace(h.Opens), strings.TrimSpace(h.Closes)
+		if !clockTime.MatchString(h.Opens) || !clockTime.MatchString(h.Closes) {
+			return nil, fmt.Errorf("hours on %s must be times like 07:30", day)
+		}
+		if h.Opens == h.Closes {
+			return nil, fmt.Errorf("hours on %s open and close at the same time", day)
+		}
+		normalized = append(normalized, h)
+	}
+
+	sort.SliceStable(normalized, func(i, j int) bool {
+		di, dj := indexOf(weekdays, normalized[i].Day), indexOf(weekdays, normalized[j].Day)
+		if di != dj {
+			return di < dj
+		}
+		return normalized[i].Opens < normalized[j].Opens
+	})
+
+	return normalized, nil
+}
+
+// countryCode reads a country as an ISO 3166 code like "CA" or a name
+// like "Canada"
+func countryCode(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	for code, names := range countryNames {
+		for _, name := range names {
+			if strings.EqualFold(s, name) {
+				return code, true
+			}
+		}
+	}
+	if len(s) == 2 && isLetters(s) {
+		return strings.ToUpper(s), true
+	}
+	return "", false
+}
+
+func isLetters(s string) bool {
+	for _, r := range strings.ToLower(s) {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// normalizeShipsTo turns a list of countries into sorted, unique country codes
+func normalizeShipsTo(countries []string) ([]string, error) {
+	var (
+		codes = []string{}
+		seen  = make(map[string]bool)
+	)
+	for _, c := range countries {
+		if strings.TrimSpace(c) == "" {
+			continue
+		}
+		code, ok := countryCode(c)
+		if !ok {
+			return nil, fmt.Errorf("unknown country %q, use a code like CA", c)
+		}
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	return codes, nil
+}
+
+func validLocation(l *latlng.LatLng) bool {
+	return l == nil || l.Latitude >= -90 && l.Latitude <= 90 && l.Longitude >= -180 && l.Longitude <= 180
+}
+
+// prepareRoaster validates and normalizes the profile of a roaster before it's
+// written
+func prepareRoaster(r *Roaster, now time.Time) error {
+	if !validLocation(r.Location) {
+		return fmt.Errorf("location is out of range")
+	}
+
+	if r.Address != nil && r.Address.IsZero() {
+		r.Address = nil
+	}
+
+	if r.Founded != 0 && (r.Founded < firstRoasterYear || int(r.Founded) > now.Year()) {
+		return fmt.Errorf("founded must be a year between %d and %d", firstRoasterYear, now.Year())
+	}
+
+	shipsTo, err := normalizeShipsTo(r.ShipsTo)
+	if err != nil {
+		return err
+	}
+	r.ShipsTo = shipsTo
+
+	cafes := []Cafe{}
+	for i, c := range r.Cafes {
+		c.Name = strings.TrimSpace(c.Name)
+		if c.Address != nil && c.Address.IsZero() {
+			c.Address = nil
+		}
+		if c.Address == nil && c.Location == nil {
+			return fmt.Errorf("café %d needs an address or a location", i+1)
+		}
+		if !validLocation(c.Location) {
+			return fmt.Errorf("café %d location is out of range", i+1)
+		}
+		if c.Hours, err = normalizeHours(c.Hours); err != nil {
+			return fmt.Errorf("café %d: %v", i+1, err)
+		}
+		cafes = append(cafes, c)
+	}
+	r.Cafes = cafes
+
+	return nil
+}
+
+// geocodeCafes places the cafés sent with an address but no location at the
+// center of their city
+func (h *Handler) geocodeCafes(r *Roaster) {
+	for i := range r.Cafes {
+		c := &r.Cafes[i]
+		if c.Location != nil || c.Address == nil {
+			continue
+		}
+		place, err := h.geocoder.Geocode(c.Address.String())
+		if err != nil {
+			h.logger.Infow(
+				"Couldn't geocode café address",
+				"slug", r.Slug,
+				"address", c.Address.String(),
+			)
+			continue
+		}
+		c.Location = &latlng.LatLng{Latitude: place.Lat, Longitude: place.Lng}
+	}
+}
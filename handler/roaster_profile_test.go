@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+func Test_normalizeHours(t *testing.T) {
+	hours, err := normalizeHours([]OpeningHours{
+		{Day: "Saturday", Opens: "08:00", Closes: "14:00"},
+		{Day: "mon", Opens: "07:00", Closes: "15:00"},
+		{Day: "Fri", Opens: "18:00", Closes: "01:00"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []OpeningHours{
+		{Day: "mon", Opens: "07:00", Closes: "15:00"},
+		{Day: "fri", Opens: "18:00", Closes: "01:00"},
+		{Day: "sat", Opens: "08:00", Closes: "14:00"},
+	}, hours)
+
+	_, err = normalizeHours([]OpeningHours{{Day: "someday", Opens: "07:00", Closes: "15:00"}})
+	assert.Error(t, err)
+	_, err = normalizeHours([]OpeningHours{{Day: "tue", Opens: "7am", Closes: "15:00"}})
+	assert.Error(t, err)
+	_, err = normalizeHours([]OpeningHours{{Day: "tue", Opens: "07:00", Closes: "07:00"}})
+	assert.Error(t, err)
+}
+
+func Test_prepareRoaster(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	r := Roaster{
+		Address: &Address{},
+		ShipsTo: []string{"us", "Canada", "US", " "},
+		Founded: 2012,
+		Cafes: []Cafe{{
+			Name:     " Lincoln Square ",
+			Location: &latlng.LatLng{Latitude: 41.97, Longitude: -87.69},
+			Hours:    []OpeningHours{{Day: "Sunday", Opens: "08:00", Closes: "16:00"}},
+		}},
+	}
+	assert.NoError(t, prepareRoaster(&r, now))
+	assert.Nil(t, r.Address)
+	assert.Equal(t, []string{"CA", "US"}, r.ShipsTo)
+	assert.Equal(t, "Lincoln Square", r.Cafes[0].Name)
+	assert.Equal(t, "sun", r.Cafes[0].Hours[0].Day)
+
+	assert.Error(t, prepareRoaster(&Roaster{Founded: 2030}, now))
+	assert.Error(t, prepareRoaster(&Roaster{ShipsTo: []string{"Atlantis"}}, now))
+	assert.Error(t, prepareRoaster(&Roaster{Cafes: []Cafe{{Name: "Nowhere"}}}, now))
+
+	assert.Equal(t, "1 Main St, Portland, OR 97214, US", Address{
+		Street:     "1 Main St",
+		City:       "Portland",
+		Region:     "OR",
+		PostalCode: "97214",
+		Country:    "US",
+	}.String())
+}
+
+func Test_RoasterFilter(t *testing.T) {
+	roasters := []Roaster{
+		{Slug: "ipsento", Country: "United States", ShipsTo: []string{"CA", "US"}, Cafes: []Cafe{{Name: "Ipsento 606"}}},
+		{Slug: "phil-sebastian", Address: &Address{Country: "CA"}, ShipsTo: []string{"CA"}, Wholesale: true},
+		{Slug: "monmouth", Country: "United Kingdom"},
+	}
+	match := func(query string) []string {
+		q, _ := url.ParseQuery(query)
+		f, err := parseRoasterFilter(q)
+		assert.NoError(t, err)
+
+		var slugs []string
+		for _, r := range roasters {
+			if f.Match(r) {
+				slugs = append(slugs, r.Slug)
+			}
+		}
+		return slugs
+	}
+
+	assert.Equal(t, []string{"ipsento", "phil-sebastian"}, match("ships_to=CA"))
+	assert.Equal(t, []string{"ipsento"}, match("ships_to=united+states"))
+	assert.Equal(t, []string{"phil-sebastian"}, match("country=CA"))
+	assert.Equal(t, []string{"monmouth"}, match("country=UK"))
+	assert.Equal(t, []string{"phil-sebastian"}, match("wholesale=true"))
+	assert.Equal(t, []string{"ipsento"}, match("cafes=true"))
+
+	_, err := parseRoasterFilter(url.Values{"ships_to": {"Narnia"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mager/cafebean-api/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_canEditLookedUp(t *testing.T) {
+	h := &Handler{cfg: config.Config{Moderators: []string{"mod@cafebean.org"}}}
+	verified := RoasterDB{Roaster: Roaster{Verified: true}, Owner: "owner@ipsento.com"}
+
+	type test struct {
+		name      string
+		roaster   RoasterDB
+		lookupErr error
+		user      string
+		exp       bool
+		wantErr   bool
+	}
+
+	tests := []test{
+		{name: "owner of a verified roaster", roaster: verified, user: "owner@ipsento.com", exp: true},
+		{name: "non-owner of a verified roaster", roaster: verified, user: "ana@cafebean.org", exp: false},
+		{name: "anonymous user of a verified roaster", roaster: verified, user: "", exp: false},
+		{name: "moderator of a verified roaster", roaster: verified, user: "mod@cafebean.org", exp: true},
+		{name: "unverified roaster", roaster: RoasterDB{}, user: "ana@cafebean.org", exp: true},
+		{name: "roaster not found is unowned", lookupErr: errRoasterNotFound, user: "ana@cafebean.org", exp: true},
+		{name: "lookup error denies the edit", roaster: verified, lookupErr: errors.New("unavailable"), user: "owner@ipsento.com", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			allowed, err := h.canEditLookedUp(tc.roaster, tc.lookupErr, tc.user)
+			if tc.wantErr {
+				assert.Error(t, err)
+				assert.False(t, allowed)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.exp, allowed)
+		})
+	}
+}
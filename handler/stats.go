@@ -3,14 +3,36 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/iterator"
 )
 
+// statsReseedInterval is how often the stats are rebuilt from scratch, which
+// drops renamed slugs the changelog can't tell apart from new ones
+const statsReseedInterval = 24 * time.Hour
+
+// statsLateWindow is how far back each refresh reads the changelog again.
+// Rows streamed into BigQuery can show up after rows with a later UpdatedAt.
+const statsLateWindow = 10 * time.Minute
+
 // Stats represents stats
 type Stats struct {
-	BeanCount        int               `firestore:"bean_count" json:"bean_count"`
-	RoasterCount     int               `firestore:"roaster_count" json:"roaster_count"`
-	RoasterLocations []RoasterLocation `json:"roaster_locations"`
+	BeanCount        int                 `firestore:"bean_count" json:"bean_count"`
+	RoasterCount     int                 `firestore:"roaster_count" json:"roaster_count"`
+	RoasterLocations []RoasterLocation   `json:"roaster_locations"`
+	BeansByCountry   []StatCount         `json:"beans_by_country"`
+	BeansByRoaster   []StatCount         `json:"beans_by_roaster"`
+	BeansByShade     []StatCount         `json:"beans_by_shade"`
+	Certifications   CertificationCounts `json:"certifications"`
+	TopFlavors       []StatCount         `json:"top_flavors"`
+	Growth           []WeekCount         `json:"growth"`
+	UpdatedAt        time.Time           `json:"updated_at"`
 }
 
 type RoasterLocation struct {
@@ -24,38 +46,311 @@ type StatsResp struct {
 	Stats Stats `json:"stats"`
 }
 
+// statsCache holds the stats between requests
+type statsCache struct {
+	mu        sync.Mutex
+	state     *statsState
+	checkedAt time.Time
+}
+
+// beanChange is a bean changelog entry with the fields the stats use
+type beanChange struct {
+	Slug        string
+	RoasterName string
+	RoasterSlug string
+	Countries   string
+	Flavors     string
+	Shade       string
+	Organic     bool
+	FairTrade   bool
+	DirectSun   bool
+	UpdatedAt   time.Time
+}
+
+// roasterChange is a roaster changelog entry with the fields the stats use
+type roasterChange struct {
+	Slug      string
+	Name      string
+	Location  string
+	UpdatedAt time.Time
+}
+
+// firstSeen is when a slug first appears in the changelog
+type firstSeen struct {
+	Slug      string
+	UpdatedAt time.Time
+}
+
 func (h *Handler) getStats(w http.ResponseWriter, r *http.Request) {
 	var (
-		resp = &StatsResp{}
-		ctx  = context.TODO()
+		resp  = &StatsResp{}
+		ctx   = r.Context()
+		weeks = defaultStatsWeeks
+		err   error
 	)
 
-	// Get bean count
-	beans, err := h.database.Collection("beans").Documents(ctx).GetAll()
+	if v := r.URL.Query().Get("weeks"); v != "" {
+		weeks, err = strconv.Atoi(v)
+		if err != nil || weeks < 1 || weeks > maxStatsWeeks {
+			h.writeError(w, r, validationError(fmt.Sprintf("weeks must be between 1 and %d", maxStatsWeeks)))
+			return
+		}
+	}
+
+	resp.Stats, err = h.summarizeStats(ctx, weeks)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.writeError(w, r, err)
 		return
 	}
-	resp.Stats.BeanCount = len(beans)
 
-	// Get roaster count
-	roasters, err := h.database.Collection("roasters").Documents(ctx).GetAll()
+	json.NewEncoder(w).Encode(resp)
+}
+
+// summarizeStats brings the stats up to date and summarizes them. Only the
+// changelog entries and reviews written since the last refresh are read, the
+// beans and roasters are loaded once a day.
+func (h *Handler) summarizeStats(ctx context.Context, weeks int) (Stats, error) {
+	c := h.stats
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	switch {
+	case c.state == nil || now.Sub(c.state.seededAt) > statsReseedInterval:
+		state, err := h.seedStats(ctx, now)
+		if err != nil {
+			if c.state == nil {
+				return Stats{}, err
+			}
+			h.logger.Errorw("Error rebuilding stats, serving stale stats", "error", err)
+			break
+		}
+		c.state, c.checkedAt = state, now
+	case now.Sub(c.checkedAt) > h.cfg.StatsRefreshInterval:
+		if err := h.refreshStats(ctx, c.state); err != nil {
+			h.logger.Errorw("Error refreshing stats, serving stale stats", "error", err)
+			break
+		}
+		c.checkedAt = now
+	}
+
+	return c.state.summarize(now, weeks), nil
+}
+
+// seedStats builds the stats from the beans and roasters in Firestore and
+// when each first appeared in the changelog
+func (h *Handler) seedStats(ctx context.Context, now time.Time) (*statsState, error) {
+	s := newStatsState()
+	s.seededAt = now
+
+	// Everything written after now is folded in by the next refresh
+	s.since = now
+
+	beans, err := h.queryFirstSeen(ctx, "bean", "Bean")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		return nil, err
+	}
+	for _, b := range beans {
+		s.addedBeans[b.Slug] = true
+		s.beansAdded[weekOf(b.UpdatedAt)]++
+	}
+	roasters, err := h.queryFirstSeen(ctx, "roaster", "Roaster")
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range roasters {
+		s.addedRoasters[r.Slug] = true
+		s.roastersAdded[weekOf(r.UpdatedAt)]++
 	}
-	resp.Stats.RoasterCount = len(roasters)
 
-	// Get roaster locations
-	for _, roaster := range roasters {
-		r := docToRoaster(roaster)
-		resp.Stats.RoasterLocations = append(resp.Stats.RoasterLocations, RoasterLocation{
-			Lat:  r.Location.Latitude,
-			Lng:  r.Location.Longitude,
-			Name: r.Name,
-			Slug: r.Slug,
-		})
+	// Beans and roasters from before the changelog don't count in the growth
+	docs, err := h.database.Collection("beans").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range docs {
+		b := docToBean(doc)
+		s.beans[b.Slug] = beanToStat(b)
+		s.beanAt[b.Slug] = now
+		s.addedBeans[b.Slug] = true
+	}
+	docs, err = h.database.Collection("roasters").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range docs {
+		r := docToRoaster(doc)
+		s.roasters[r.Slug] = roasterStat{Name: r.Name, Location: r.Location}
+		s.roasterAt[r.Slug] = now
+		s.addedRoasters[r.Slug] = true
 	}
 
-	json.NewEncoder(w).Encode(resp)
+	if err := h.countReviews(ctx, s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// refreshStats folds in the changelog entries and reviews written since the
+// stats were last brought up to date. The entries of the last few minutes are
+// read again to catch late rows, folding them twice is harmless.
+func (h *Handler) refreshStats(ctx context.Context, s *statsState) error {
+	since := s.since.Add(-statsLateWindow)
+	beans, err := h.queryBeanChanges(ctx, since)
+	if err != nil {
+		return err
+	}
+	roasters, err := h.queryRoasterChanges(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range beans {
+		s.foldBean(b.Slug, beanStat{
+			Countries: splitJoined(b.Countries),
+			Flavors:   splitJoined(b.Flavors),
+			Roaster:   RoasterMap{Name: b.RoasterName, Slug: b.RoasterSlug},
+			Shade:     b.Shade,
+			Organic:   b.Organic,
+			FairTrade: b.FairTrade,
+			DirectSun: b.DirectSun,
+		}, b.UpdatedAt)
+	}
+	for _, r := range roasters {
+		s.foldRoaster(r.Slug, roasterStat{Name: r.Name, Location: parsePoint(r.Location)}, r.UpdatedAt)
+	}
+
+	return h.countReviews(ctx, s)
+}
+
+// countReviews adds the reviews written since the last count to the growth,
+// there are none when reviews are disabled
+func (h *Handler) countReviews(ctx context.Context, s *statsState) error {
+	if !h.cfg.ReviewsEnabled {
+		return nil
+	}
+
+	rows, err := h.postgres.QueryContext(ctx, `
+		SELECT date_trunc('week', created_at AT TIME ZONE 'UTC'), count(*), max(created_at)
+		FROM reviews
+		WHERE created_at > $1
+		GROUP BY 1;
+	`, s.reviewsSince)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			week, latest time.Time
+			n            int
+		)
+		if err := rows.Scan(&week, &n, &latest); err != nil {
+			return err
+		}
+		s.reviewsAdded[weekOf(week)] += n
+		if latest.After(s.reviewsSince) {
+			s.reviewsSince = latest
+		}
+	}
+
+	return rows.Err()
+}
+
+// queryFirstSeen returns when each slug first appears in a changelog
+func (h *Handler) queryFirstSeen(ctx context.Context, dataset, record string) ([]firstSeen, error) {
+	q := h.bq.Query(fmt.Sprintf(`
+		SELECT %[2]s.Slug AS Slug, MIN(CAST(UpdatedAt AS TIMESTAMP)) AS UpdatedAt
+		FROM `+"`cafebean.%[1]s.changelog`"+`
+		WHERE %[2]s.Slug IS NOT NULL
+		GROUP BY Slug
+	`, dataset, record))
+
+	var seen []firstSeen
+	err := readRows(ctx, q, func(it *bigquery.RowIterator) error {
+		var f firstSeen
+		if err := it.Next(&f); err != nil {
+			return err
+		}
+		seen = append(seen, f)
+		return nil
+	})
+	return seen, err
+}
+
+// queryBeanChanges returns the bean changelog entries written after since
+func (h *Handler) queryBeanChanges(ctx context.Context, since time.Time) ([]beanChange, error) {
+	q := h.bq.Query(`
+		SELECT
+			Bean.Slug AS Slug,
+			Bean.Roaster.Name AS RoasterName,
+			Bean.Roaster.Slug AS RoasterSlug,
+			IFNULL(Bean.Countries, '') AS Countries,
+			IFNULL(Bean.Flavors, '') AS Flavors,
+			IFNULL(Bean.Shade, '') AS Shade,
+			IFNULL(Bean.Organic, false) AS Organic,
+			IFNULL(Bean.FairTrade, false) AS FairTrade,
+			IFNULL(Bean.DirectSun, false) AS DirectSun,
+			CAST(UpdatedAt AS TIMESTAMP) AS UpdatedAt
+		FROM ` + "`cafebean.bean.changelog`" + `
+		WHERE CAST(UpdatedAt AS TIMESTAMP) > @since
+		ORDER BY UpdatedAt
+	`)
+	q.Parameters = []bigquery.QueryParameter{{Name: "since", Value: since}}
+
+	var changes []beanChange
+	err := readRows(ctx, q, func(it *bigquery.RowIterator) error {
+		var c beanChange
+		if err := it.Next(&c); err != nil {
+			return err
+		}
+		changes = append(changes, c)
+		return nil
+	})
+	return changes, err
+}
+
+// queryRoasterChanges returns the roaster changelog entries written after since
+func (h *Handler) queryRoasterChanges(ctx context.Context, since time.Time) ([]roasterChange, error) {
+	q := h.bq.Query(`
+		SELECT
+			Roaster.Slug AS Slug,
+			IFNULL(Roaster.Name, '') AS Name,
+			IFNULL(CAST(Roaster.Location AS STRING), '') AS Location,
+			CAST(UpdatedAt AS TIMESTAMP) AS UpdatedAt
+		FROM ` + "`cafebean.roaster.changelog`" + `
+		WHERE CAST(UpdatedAt AS TIMESTAMP) > @since
+		ORDER BY UpdatedAt
+	`)
+	q.Parameters = []bigquery.QueryParameter{{Name: "since", Value: since}}
+
+	var changes []roasterChange
+	err := readRows(ctx, q, func(it *bigquery.RowIterator) error {
+		var c roasterChange
+		if err := it.Next(&c); err != nil {
+			return err
+		}
+		changes = append(changes, c)
+		return nil
+	})
+	return changes, err
+}
+
+// readRows runs a query and calls next until the rows run out
+func readRows(ctx context.Context, q *bigquery.Query, next func(it *bigquery.RowIterator) error) error {
+	it, err := q.Read(ctx)
+	if err != nil {
+		return err
+	}
+	for {
+		err := next(it)
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
 }
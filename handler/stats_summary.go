@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/type/latlng"
+)
+
+// Stats breakdown limits
+const (
+	maxTopFlavors     = 20
+	defaultStatsWeeks = 52
+	maxStatsWeeks     = 520
+)
+
+// StatCount is the number of beans with a value, like a country or flavor
+type StatCount struct {
+	Name  string `json:"name"`
+	Slug  string `json:"slug,omitempty"`
+	Count int    `json:"count"`
+}
+
+// CertificationCounts are the number of beans with each certification
+type CertificationCounts struct {
+	Organic   int `json:"organic"`
+	FairTrade int `json:"fair_trade"`
+	DirectSun int `json:"direct_sun"`
+}
+
+// WeekCount is the number of beans, roasters and reviews added in the week
+// starting on Week
+type WeekCount struct {
+	Week     string `json:"week"`
+	Beans    int    `json:"beans"`
+	Roasters int    `json:"roasters"`
+	Reviews  int    `json:"reviews"`
+}
+
+// beanStat is the part of a bean the stats are computed from
+type beanStat struct {
+	Countries []string
+	Flavors   []string
+	Roaster   RoasterMap
+	Shade     string
+	Organic   bool
+	FairTrade bool
+	DirectSun bool
+}
+
+// roasterStat is the part of a roaster the stats are computed from
+type roasterStat struct {
+	Name     string
+	Location *latlng.LatLng
+}
+
+// statsState is the latest version of every bean and roaster and the number
+// added each week. It's seeded once and then folds in the changelog entries
+// written since.
+type statsState struct {
+	beans    map[string]beanStat
+	roasters map[string]roasterStat
+
+	// beanAt and roasterAt are the times of the versions kept, so entries
+	// read again or streamed late don't replace newer ones
+	beanAt    map[string]time.Time
+	roasterAt map[string]time.Time
+
+	// added are the slugs already counted in the growth, including the ones
+	// from before the changelog
+	addedBeans    map[string]bool
+	addedRoasters map[string]bool
+
+	beansAdded    map[string]int
+	roastersAdded map[string]int
+	reviewsAdded  map[string]int
+
+	// since is the time of the last changelog entry folded in, reviewsSince
+	// the time of the last review counted
+	since        time.Time
+	reviewsSince time.Time
+	seededAt     time.Time
+}
+
+func newStatsState() *statsState {
+	return &statsState{
+		beans:         make(map[string]beanStat),
+		roasters:      make(map[string]roasterStat),
+		beanAt:        make(map[string]time.Time),
+		roasterAt:     make(map[string]time.Time),
+		addedBeans:    make(map[string]bool),
+		addedRoasters: make(map[string]bool),
+		beansAdded:    make(map[string]int),
+		roastersAdded: make(map[string]int),
+		reviewsAdded:  make(map[string]int),
+	}
+}
+
+// weekOf returns the Monday of the week of t, in UTC
+func weekOf(t time.Time) string {
+	t = t.UTC()
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+}
+
+func beanToStat(b Bean) beanStat {
+	return beanStat{
+		Countries: b.Countries,
+		Flavors:   b.Flavors,
+		Roaster:   b.Roaster,
+		Shade:     b.Shade,
+		Organic:   b.Organic,
+		FairTrade: b.FairTrade,
+		DirectSun: b.DirectSun,
+	}
+}
+
+// splitJoined splits a list joined with ", " in the changelog
+func splitJoined(s string) []string {
+	var items = []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parsePoint reads a changelog location like POINT(-87.6298 41.8781)
+func parsePoint(s string) *latlng.LatLng {
+	var lat, lng float64
+	if _, err := fmt.Sscanf(s, "POINT(%f %f)", &lng, &lat); err != nil {
+		return nil
+	}
+	return &latlng.LatLng{Latitude: lat, Longitude: lng}
+}
+
+// foldBean records the latest version of a bean, counting it as added in the
+// week of at the first time it's seen. Folding an entry again is a no-op.
+func (s *statsState) foldBean(slug string, b beanStat, at time.Time) {
+	if !s.addedBeans[slug] {
+		s.addedBeans[slug] = true
+		s.beansAdded[weekOf(at)]++
+	}
+	if !at.Before(s.beanAt[slug]) {
+		s.beans[slug] = b
+		s.beanAt[slug] = at
+	}
+	if at.After(s.since) {
+		s.since = at
+	}
+}
+
+// foldRoaster records the latest version of a roaster, counting it as added
+// in the week of at the first time it's seen. Folding an entry again is a
+// no-op.
+func (s *statsState) foldRoaster(slug string, r roasterStat, at time.Time) {
+	if !s.addedRoasters[slug] {
+		s.addedRoasters[slug] = true
+		s.roastersAdded[weekOf(at)]++
+	}
+	if !at.Before(s.roasterAt[slug]) {
+		s.roasters[slug] = r
+		s.roasterAt[slug] = at
+	}
+	if at.After(s.since) {
+		s.since = at
+	}
+}
+
+// countsOf sorts counts by count and then name
+func countsOf(counts map[string]*StatCount, limit int) []StatCount {
+	var list = []StatCount{}
+	for _, c := range counts {
+		list = append(list, *c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Name < list[j].Name
+	})
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list
+}
+
+// addCount adds one to the count of a value, grouping values that only differ
+// in case under the first spelling seen
+func addCount(counts map[string]*StatCount, name, slug string) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return
+	}
+	key := strings.ToLower(name)
+	if slug != "" {
+		key = slug
+	}
+	if c, ok := counts[key]; ok {
+		c.Count++
+		return
+	}
+	counts[key] = &StatCount{Name: name, Slug: slug, Count: 1}
+}
+
+// summarize computes the stats with the growth of the last weeks up to now
+func (s *statsState) summarize(now time.Time, weeks int) Stats {
+	var (
+		stats = Stats{
+			BeanCount:        len(s.beans),
+			RoasterCount:     len(s.roasters),
+			RoasterLocations: []RoasterLocation{},
+			UpdatedAt:        s.since,
+		}
+		countries = make(map[string]*StatCount)
+		roasters  = make(map[string]*StatCount)
+		shades    = make(map[string]*StatCount)
+		flavors   = make(map[string]*StatCount)
+	)
+
+	for _, b := range s.beans {
+		for _, c := range b.Countries {
+			addCount(countries, c, "")
+		}
+		for _, f := range b.Flavors {
+			addCount(flavors, f, "")
+		}
+		addCount(roasters, b.Roaster.Name, b.Roaster.Slug)
+
+		shade := strings.ToLower(b.Shade)
+		if level, ok := parseRoastLevel(b.Shade); ok {
+			shade = roastLevelNames[level]
+		}
+		addCount(shades, shade, "")
+
+		if b.Organic {
+			stats.Certifications.Organic++
+		}
+		if b.FairTrade {
+			stats.Certifications.FairTrade++
+		}
+		if b.DirectSun {
+			stats.Certifications.DirectSun++
+		}
+	}
+	stats.BeansByCountry = countsOf(countries, 0)
+	stats.BeansByRoaster = countsOf(roasters, 0)
+	stats.BeansByShade = countsOf(shades, 0)
+	stats.TopFlavors = countsOf(flavors, maxTopFlavors)
+
+	for slug, r := range s.roasters {
+		if r.Location == nil {
+			continue
+		}
+		stats.RoasterLocations = append(stats.RoasterLocations, RoasterLocation{
+			Lat:  r.Location.Latitude,
+			Lng:  r.Location.Longitude,
+			Name: r.Name,
+			Slug: slug,
+		})
+	}
+	sort.Slice(stats.RoasterLocations, func(i, j int) bool {
+		return stats.RoasterLocations[i].Slug < stats.RoasterLocations[j].Slug
+	})
+
+	// Every week of the window, including the ones where nothing was added
+	stats.Growth = []WeekCount{}
+	start, _ := time.Parse("2006-01-02", weekOf(now))
+	start = start.AddDate(0, 0, -7*(weeks-1))
+	for i := 0; i < weeks; i++ {
+		week := start.AddDate(0, 0, 7*i).Format("2006-01-02")
+		stats.Growth = append(stats.Growth, WeekCount{
+			Week:     week,
+			Beans:    s.beansAdded[week],
+			Roasters: s.roastersAdded[week],
+			Reviews:  s.reviewsAdded[week],
+		})
+	}
+
+	return stats
+}
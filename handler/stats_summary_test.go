@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/mager/cafebean-api/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_weekOf(t *testing.T) {
+	assert.Equal(t, "2021-05-31", weekOf(time.Date(2021, 6, 6, 23, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "2021-06-07", weekOf(time.Date(2021, 6, 7, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "2021-06-07", weekOf(time.Date(2021, 6, 9, 12, 0, 0, 0, time.UTC)))
+}
+
+func Test_parsePoint(t *testing.T) {
+	l := parsePoint("POINT(-87.629800 41.878100)")
+	if assert.NotNil(t, l) {
+		assert.Equal(t, 41.8781, l.Latitude)
+		assert.Equal(t, -87.6298, l.Longitude)
+	}
+	assert.Nil(t, parsePoint(""))
+}
+
+func Test_statsState(t *testing.T) {
+	var (
+		s       = newStatsState()
+		ipsento = RoasterMap{Name: "Ipsento", Slug: "ipsento"}
+		week1   = time.Date(2021, 5, 26, 10, 0, 0, 0, time.UTC)
+		week2   = time.Date(2021, 6, 2, 10, 0, 0, 0, time.UTC)
+	)
+
+	// Seeded from before the changelog, it doesn't count in the growth
+	s.beans["old"] = beanStat{Countries: []string{"Ethiopia"}, Roaster: ipsento, Shade: "Light", Organic: true}
+	s.addedBeans["old"] = true
+
+	s.foldBean("new", beanStat{Countries: []string{"ethiopia", "Kenya"}, Flavors: []string{"berry"}, Roaster: ipsento, Shade: "dark"}, week1)
+	s.foldBean("new", beanStat{Countries: []string{"Ethiopia", "Kenya"}, Flavors: []string{"berry", "citrus"}, Roaster: ipsento, Shade: "dark", FairTrade: true}, week2)
+	s.foldRoaster("ipsento", roasterStat{Name: "Ipsento", Location: parsePoint("POINT(-87.6 41.9)")}, week2)
+	s.reviewsAdded[weekOf(week2)] = 3
+
+	stats := s.summarize(week2, 3)
+	assert.Equal(t, 2, stats.BeanCount)
+	assert.Equal(t, 1, stats.RoasterCount)
+	assert.Equal(t, []StatCount{{Name: "Ethiopia", Count: 2}, {Name: "Kenya", Count: 1}}, stats.BeansByCountry)
+	assert.Equal(t, []StatCount{{Name: "Ipsento", Slug: "ipsento", Count: 2}}, stats.BeansByRoaster)
+	assert.Equal(t, []StatCount{{Name: "dark", Count: 1}, {Name: "light", Count: 1}}, stats.BeansByShade)
+	assert.Equal(t, CertificationCounts{Organic: 1, FairTrade: 1}, stats.Certifications)
+	assert.Equal(t, []StatCount{{Name: "berry", Count: 1}, {Name: "citrus", Count: 1}}, stats.TopFlavors)
+	assert.Len(t, stats.RoasterLocations, 1)
+	assert.Equal(t, week2, stats.UpdatedAt)
+	assert.Equal(t, []WeekCount{
+		{Week: "2021-05-17"},
+		{Week: "2021-05-24", Beans: 1},
+		{Week: "2021-05-31", Roasters: 1, Reviews: 3},
+	}, stats.Growth)
+}
+
+func Test_statsState_refold(t *testing.T) {
+	var (
+		s     = newStatsState()
+		early = time.Date(2021, 6, 2, 10, 0, 0, 0, time.UTC)
+		late  = early.Add(5 * time.Minute)
+	)
+
+	s.foldBean("new", beanStat{Shade: "dark"}, late)
+	// An entry streamed late or read again by the next refresh
+	s.foldBean("new", beanStat{Shade: "light"}, early)
+	s.foldBean("new", beanStat{Shade: "dark"}, late)
+	s.foldRoaster("ipsento", roasterStat{Name: "Ipsento"}, late)
+	s.foldRoaster("ipsento", roasterStat{Name: "Ipsento Old"}, early)
+
+	assert.Equal(t, "dark", s.beans["new"].Shade)
+	assert.Equal(t, "Ipsento", s.roasters["ipsento"].Name)
+	assert.Equal(t, 1, s.beansAdded[weekOf(late)])
+	assert.Equal(t, 1, s.roastersAdded[weekOf(late)])
+	assert.Equal(t, late, s.since)
+}
+
+func Test_countReviews_disabled(t *testing.T) {
+	h := &Handler{cfg: config.Config{ReviewsEnabled: false}, postgres: &sql.DB{}}
+	s := newStatsState()
+
+	assert.NoError(t, h.countReviews(context.Background(), s))
+	assert.Empty(t, s.reviewsAdded)
+}
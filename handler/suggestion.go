@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// Suggestion statuses
+const (
+	suggestionOpen     = "open"
+	suggestionAccepted = "accepted"
+	suggestionDeclined = "declined"
+)
+
+// errSuggestionNotFound is returned when a suggestion doesn't exist
+var errSuggestionNotFound = notFoundError("suggestion not found")
+
+// beanSuggestionFields are the bean fields a suggestion can patch
+var beanSuggestionFields = map[string]bool{
+	"agtron":      true,
+	"altitude":    true,
+	"countries":   true,
+	"description": true,
+	"farm":        true,
+	"flavors":     true,
+	"name":        true,
+	"photo":       true,
+	"process":     true,
+	"producer":    true,
+	"region":      true,
+	"shade":       true,
+	"url":         true,
+	"varietals":   true,
+}
+
+// roasterSuggestionFields are the roaster fields a suggestion can patch
+var roasterSuggestionFields = map[string]bool{
+	"address":   true,
+	"cafes":     true,
+	"city":      true,
+	"country":   true,
+	"founded":   true,
+	"instagram": true,
+	"location":  true,
+	"logo":      true,
+	"name":      true,
+	"region":    true,
+	"ships_to":  true,
+	"twitter":   true,
+	"url":       true,
+	"wholesale": true,
+}
+
+// Suggestion is a proposed patch to a bean or roaster from a non-owner
+type Suggestion struct {
+	ID          string                 `firestore:"-" json:"id"`
+	Kind        string                 `firestore:"kind" json:"kind"`
+	Slug        string                 `firestore:"slug" json:"slug"`
+	RoasterSlug string                 `firestore:"roaster_slug" json:"roaster_slug"`
+	Patch       map[string]interface{} `firestore:"patch" json:"patch"`
+	Status      string                 `firestore:"status" json:"status"`
+	SuggestedBy string                 `firestore:"suggested_by" json:"suggested_by"`
+	SuggestedAt time.Time              `firestore:"suggested_at" json:"suggested_at"`
+	ReviewedBy  string                 `firestore:"reviewed_by" json:"reviewed_by,omitempty"`
+	ReviewedAt  time.Time              `firestore:"reviewed_at" json:"reviewed_at,omitempty"`
+	Comment     string                 `firestore:"comment" json:"comment,omitempty"`
+	Changes     []FieldChange          `firestore:"-" json:"changes,omitempty"`
+}
+
+func docToSuggestion(doc *firestore.DocumentSnapshot) Suggestion {
+	var s Suggestion
+	doc.DataTo(&s)
+	s.ID = doc.Ref.ID
+	return s
+}
+
+// validatePatch makes sure a patch only touches fields that can be suggested
+func validatePatch(patch map[string]interface{}, allowed map[string]bool) error {
+	if len(patch) == 0 {
+		return fmt.Errorf("patch is empty")
+	}
+
+	var invalid []string
+	for field := range patch {
+		if !allowed[field] {
+			invalid = append(invalid, field)
+		}
+	}
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return fmt.Errorf("fields can't be suggested: %s", strings.Join(invalid, ", "))
+	}
+
+	return nil
+}
+
+// applyPatch overlays a patch on the JSON representation of current and
+// decodes the result into out
+func applyPatch(current interface{}, patch map[string]interface{}, out interface{}) error {
+	merged := toJSONMap(current)
+	for field, value := range patch {
+		merged[field] = value
+	}
+
+	b, err := json.Marshal(merged)
+	if err == nil {
+		err = json.Unmarshal(b, out)
+	}
+	if err != nil {
+		return validationError(fmt.Sprintf("invalid patch: %v", err))
+	}
+	return nil
+}
+
+// suggestionTarget loads the entity a suggestion patches and applies the patch
+func (h *Handler) suggestionTarget(ctx context.Context, s Suggestion) (*firestore.DocumentSnapshot, interface{}, interface{}, error) {
+	switch s.Kind {
+	case "bean":
+		doc, err := h.getBeanDocBySlug(ctx, s.Slug)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		current := docToBean(doc)
+		var proposed Bean
+		err = applyPatch(current, s.Patch, &proposed)
+		return doc, current, proposed, err
+	case "roaster":
+		doc, err := h.getRoasterDocBySlug(ctx, s.Slug)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		current := docToRoaster(doc)
+		var proposed Roaster
+		err = applyPatch(current, s.Patch, &proposed)
+		return doc, current, proposed, err
+	}
+
+	return nil, nil, nil, validationError("invalid suggestion")
+}
+
+// canReviewSuggestion checks if a user is the owner of the roaster or a moderator
+func (h *Handler) canReviewSuggestion(ctx context.Context, s Suggestion, userEmail string) bool {
+	if h.isModerator(userEmail) {
+		return true
+	}
+
+	doc, err := h.getRoasterDocBySlug(ctx, s.RoasterSlug)
+	if err != nil {
+		return false
+	}
+	roaster := docToRoasterDB(doc)
+	return userEmail != "" && roaster.Owner == userEmail
+}
@@ -1,6 +1,12 @@
 package handler
 
-import "time"
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"google.golang.org/api/iterator"
+)
 
 type User struct {
 	Photo     string    `firestore:"photo" json:"photo"`
@@ -22,5 +28,60 @@ type PrivateUser struct {
 }
 
 type UserResp struct {
-	User User `json:"user"`
+	User        User         `json:"user"`
+	Collections []Collection `json:"collections"`
+	Reputation  *Reputation  `json:"reputation,omitempty"`
+}
+
+// errUserNotFound is returned when there is no profile for a user
+var errUserNotFound = notFoundError("user not found")
+
+// getUserByEmail fetches the private profile of a user
+func (h *Handler) getUserByEmail(ctx context.Context, email string) (UserDB, error) {
+	var u UserDB
+
+	iter := h.database.Collection("users").Where("email", "==", email).Limit(1).Documents(ctx)
+	defer iter.Stop()
+	doc, err := iter.Next()
+	if err == iterator.Done {
+		return u, errUserNotFound
+	}
+	if err != nil {
+		return u, err
+	}
+
+	doc.DataTo(&u)
+	return u, nil
+}
+
+// getUserByUsername fetches the private profile of a user by username
+func (h *Handler) getUserByUsername(ctx context.Context, username string) (UserDB, error) {
+	var u UserDB
+
+	iter := h.database.Collection("users").Where("username", "==", username).Limit(1).Documents(ctx)
+	defer iter.Stop()
+	doc, err := iter.Next()
+	if err == iterator.Done {
+		return u, errUserNotFound
+	}
+	if err != nil {
+		return u, err
+	}
+
+	doc.DataTo(&u)
+	return u, nil
+}
+
+// getPostgresUserID maps a username to the user_id used by the reviews tables
+func (h *Handler) getPostgresUserID(ctx context.Context, username string) (int, error) {
+	var userID int
+	err := h.postgres.QueryRowContext(
+		ctx,
+		`SELECT user_id FROM users WHERE username = $1;`,
+		username,
+	).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, errUserNotFound
+	}
+	return userID, err
 }
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// VoteReviewReq is the request body for the POST /reviews/{id}/vote endpoint
+type VoteReviewReq struct {
+	Helpful bool `json:"helpful"`
+}
+
+// VoteReviewResp is the response from the POST /reviews/{id}/vote endpoint
+type VoteReviewResp struct {
+	HelpfulCount    int `json:"helpful_count"`
+	NotHelpfulCount int `json:"not_helpful_count"`
+}
+
+// voteReview marks a review as helpful or not helpful. Each user gets one vote
+// per review, voting again replaces the previous vote.
+func (h *Handler) voteReview(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		vars      = mux.Vars(r)
+		err       error
+		req       VoteReviewReq
+		resp      = &VoteReviewResp{}
+		userEmail = r.Header.Get("X-User-Email")
+	)
+
+	if !h.cfg.ReviewsEnabled {
+		h.writeError(w, r, notFoundError("reviews are disabled"))
+		return
+	}
+
+	reviewID, err := parseReviewID(vars["id"])
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.writeError(w, r, bodyError(err))
+		return
+	}
+
+	// Resolve the voter
+	u, err := h.getUserByEmail(ctx, userEmail)
+	if err != nil {
+		h.writeError(w, r, validationError("invalid user"))
+		return
+	}
+	userID, err := h.getPostgresUserID(ctx, u.Username)
+	if err != nil {
+		h.writeError(w, r, validationError("invalid user"))
+		return
+	}
+
+	// Make sure the review exists and isn't the voter's own
+	authorID, err := h.getReviewAuthorID(ctx, reviewID)
+	if err == errReviewNotFound {
+		h.writeError(w, r, err)
+		return
+	}
+	if err != nil {
+		h.logger.Error(err)
+		h.writeError(w, r, err)
+		return
+	}
+	if authorID == userID {
+		h.writeError(w, r, validationError("cannot vote on your own review"))
+		return
+	}
+
+	// Record the vote and refresh the counts on the review
+	tx, err := h.postgres.BeginTx(ctx, nil)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO review_votes (review_id, user_id, helpful)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (review_id, user_id) DO UPDATE SET helpful = EXCLUDED.helpful, updated_at = now();
+	`, reviewID, userID, req.Helpful)
+	if err != nil {
+		h.logger.Error(err)
+		h.writeError(w, r, err)
+		return
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE reviews SET
+			helpful_count = (SELECT count(*) FROM review_votes WHERE review_id = $1 AND helpful),
+			not_helpful_count = (SELECT count(*) FROM review_votes WHERE review_id = $1 AND NOT helpful)
+		WHERE review_id = $1
+		RETURNING helpful_count, not_helpful_count;
+	`, reviewID).Scan(&resp.HelpfulCount, &resp.NotHelpfulCount)
+	if err != nil {
+		h.logger.Error(err)
+		h.writeError(w, r, err)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	h.logger.Infow(
+		"Review voted",
+		"review_id", reviewID,
+		"helpful", req.Helpful,
+		"updated_by", userEmail,
+	)
+
+	json.NewEncoder(w).Encode(resp)
+}
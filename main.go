@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 
 	"cloud.google.com/go/bigquery"
@@ -53,7 +54,7 @@ func Register(
 		router,
 	)
 
-	handler.New(
+	h := handler.New(
 		bq,
 		cfg,
 		database,
@@ -63,4 +64,28 @@ func Register(
 		postgres,
 		router,
 	)
+
+	// Let the changelog, webhook and event writes of the last requests finish
+	lifecycle.Append(
+		fx.Hook{
+			OnStop: h.Drain,
+		},
+	)
+
+	// Crawl roasters' product pages in the background
+	if cfg.AvailabilityCheckEnabled {
+		ctx, cancel := context.WithCancel(context.Background())
+		lifecycle.Append(
+			fx.Hook{
+				OnStart: func(context.Context) error {
+					go h.RunAvailabilityChecker(ctx)
+					return nil
+				},
+				OnStop: func(context.Context) error {
+					cancel()
+					return nil
+				},
+			},
+		)
+	}
 }
@@ -0,0 +1,26 @@
+-- Helpfulness votes and reports on reviews
+
+ALTER TABLE reviews
+	ADD COLUMN IF NOT EXISTS helpful_count integer NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS not_helpful_count integer NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS report_count integer NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS hidden boolean NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS review_votes (
+	review_id integer NOT NULL REFERENCES reviews (review_id) ON DELETE CASCADE,
+	user_id integer NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+	helpful boolean NOT NULL,
+	created_at timestamptz NOT NULL DEFAULT now(),
+	updated_at timestamptz NOT NULL DEFAULT now(),
+	PRIMARY KEY (review_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS review_reports (
+	review_id integer NOT NULL REFERENCES reviews (review_id) ON DELETE CASCADE,
+	user_id integer NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+	reason text NOT NULL,
+	created_at timestamptz NOT NULL DEFAULT now(),
+	PRIMARY KEY (review_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS reviews_hidden_idx ON reviews (hidden) WHERE hidden;
@@ -0,0 +1,11 @@
+-- When reviews were written, for the weekly growth in GET /stats
+
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS created_at timestamptz;
+
+UPDATE reviews SET created_at = updated_at WHERE created_at IS NULL;
+
+ALTER TABLE reviews
+	ALTER COLUMN created_at SET DEFAULT now(),
+	ALTER COLUMN created_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS reviews_created_at_idx ON reviews (created_at);
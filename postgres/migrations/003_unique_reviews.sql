@@ -0,0 +1,9 @@
+-- One review per user and bean, enforced so concurrent posts can't both insert
+
+DELETE FROM reviews r
+USING reviews earlier
+WHERE r.user_id = earlier.user_id
+	AND r.bean_ref = earlier.bean_ref
+	AND r.review_id > earlier.review_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS reviews_user_id_bean_ref_idx ON reviews (user_id, bean_ref);
@@ -1,14 +1,20 @@
 package router
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
 
 	"github.com/gorilla/mux"
 )
 
+// requestIDHeader identifies a request in the logs and error responses
+const requestIDHeader = "X-Request-ID"
+
 // ProvideRouter provides a gorilla mux router
 func ProvideRouter() *mux.Router {
 	var router = mux.NewRouter()
+	router.Use(requestIDMiddleware)
 	router.Use(jsonMiddleware)
 	return router
 }
@@ -20,4 +26,26 @@ func jsonMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// requestIDMiddleware keeps the request ID sent by the load balancer or makes
+// a new one, and echoes it in the response
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if id == "" {
+			id = newRequestID()
+			r.Header.Set(requestIDHeader, id)
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
 var Options = ProvideRouter